@@ -31,6 +31,105 @@ const (
 	// ReconciledReasonError indicates an error was encountered while
 	// reconciling the CR.
 	ReconciledReasonError string = "ReconcileError"
+	// ConditionDatastoreMigrationBlocked is a status condition type that indicates
+	// whether a change of the datastore backend is being held back.
+	ConditionDatastoreMigrationBlocked string = "DatastoreMigrationBlocked"
+	// DatastoreMigrationReasonBackendChanged indicates the desired datastore backend
+	// differs from the one the repos were initialized with.
+	DatastoreMigrationReasonBackendChanged string = "BackendChanged"
+	// DatastoreMigrationReasonNone indicates the datastore backend is unchanged
+	// or the change has been explicitly allowed.
+	DatastoreMigrationReasonNone string = "NoMigrationRequired"
+	// ConditionGatewayTLSValid is a status condition type that indicates whether
+	// the TLS Secret used by the gateway contains a valid certificate and key pair.
+	ConditionGatewayTLSValid string = "GatewayTLSValid"
+	// GatewayTLSReasonValid indicates the gateway TLS Secret is valid.
+	GatewayTLSReasonValid string = "SecretValid"
+	// GatewayTLSReasonInvalid indicates the gateway TLS Secret is missing or malformed.
+	GatewayTLSReasonInvalid string = "SecretInvalid"
+	// ConditionBackupDestinationReady is a status condition type that indicates whether
+	// the backup destination can be written to by the backup CronJob.
+	ConditionBackupDestinationReady string = "BackupDestinationReady"
+	// BackupDestinationReasonReady indicates the backup destination is usable.
+	BackupDestinationReasonReady string = "DestinationReady"
+	// BackupDestinationReasonInvalid indicates the backup destination is missing or misconfigured.
+	BackupDestinationReasonInvalid string = "DestinationInvalid"
+	// ConditionRaftSplitBrain is a status condition type that indicates whether the
+	// peers of a raft cluster disagree on their leader.
+	ConditionRaftSplitBrain string = "RaftSplitBrain"
+	// RaftSplitBrainReasonLeadersDiverged indicates that peers follow more than one leader.
+	RaftSplitBrainReasonLeadersDiverged string = "LeadersDiverged"
+	// RaftSplitBrainReasonAgreed indicates that the reporting peers follow the same leader.
+	RaftSplitBrainReasonAgreed string = "LeaderAgreed"
+	// RaftSplitBrainReasonNoQuorum indicates that too few peers reported a leader to tell.
+	RaftSplitBrainReasonNoQuorum string = "NoQuorum"
+	// ConditionVolumeClaimTemplatesDrifted is a status condition type that indicates whether
+	// the volume claim templates of the StatefulSet differ from the desired storage.
+	ConditionVolumeClaimTemplatesDrifted string = "VolumeClaimTemplatesDrifted"
+	// VolumeClaimTemplatesReasonDrifted indicates the StatefulSet must be recreated, or its
+	// PVCs expanded, to apply the desired storage.
+	VolumeClaimTemplatesReasonDrifted string = "RecreationRequired"
+	// VolumeClaimTemplatesReasonInSync indicates the volume claim templates match the desired storage.
+	VolumeClaimTemplatesReasonInSync string = "InSync"
+	// ConditionStorageShrinkRequested is a status condition type that indicates whether the
+	// desired storage is smaller than the storage the PVCs of the peers were created with.
+	ConditionStorageShrinkRequested string = "StorageShrinkRequested"
+	// StorageShrinkReasonRequested indicates the desired storage can't be applied, since PVCs can't shrink.
+	StorageShrinkReasonRequested string = "ShrinkRequested"
+	// StorageShrinkReasonNotRequested indicates the desired storage is at least the created storage.
+	StorageShrinkReasonNotRequested string = "NotShrunk"
+	// ConditionStateRestoreAllowed is a status condition type that indicates whether the
+	// backed up pinset may be restored into the cluster.
+	ConditionStateRestoreAllowed string = "StateRestoreAllowed"
+	// StateRestoreReasonTargetEmpty indicates the state of the restore target holds no pins.
+	StateRestoreReasonTargetEmpty string = "TargetEmpty"
+	// StateRestoreReasonTargetNotEmpty indicates the state of the restore target already holds
+	// a pinset, which the restore would conflict with.
+	StateRestoreReasonTargetNotEmpty string = "TargetNotEmpty"
+	// ConditionResourceRequestsDrifted is a status condition type that indicates whether the
+	// resource requests of the IPFS containers differ from their observed usage.
+	ConditionResourceRequestsDrifted string = "ResourceRequestsDrifted"
+	// ResourceRequestsReasonOverProvisioned indicates requests well above the observed usage.
+	ResourceRequestsReasonOverProvisioned string = "OverProvisioned"
+	// ResourceRequestsReasonUnderProvisioned indicates observed usage close to or above the requests.
+	ResourceRequestsReasonUnderProvisioned string = "UnderProvisioned"
+	// ResourceRequestsReasonRightsized indicates requests matching the observed usage.
+	ResourceRequestsReasonRightsized string = "Rightsized"
+	// ResourceRequestsReasonNoSamples indicates no usage has been observed yet.
+	ResourceRequestsReasonNoSamples string = "NoSamples"
+	// ConditionScaleBlockedByPDB is a status condition type that indicates whether scaling to
+	// the desired replicas would violate the PodDisruptionBudget of the peers.
+	ConditionScaleBlockedByPDB string = "ScaleBlockedByPDB"
+	// ScaleBlockedByPDBReasonMinAvailable indicates fewer replicas than the budget's minAvailable.
+	ScaleBlockedByPDBReasonMinAvailable string = "BelowMinAvailable"
+	// ScaleBlockedByPDBReasonAllowed indicates the budget allows the desired replicas.
+	ScaleBlockedByPDBReasonAllowed string = "ScaleAllowed"
+	// ConditionResourceManagerDisabled is a status condition type that warns the libp2p resource
+	// manager of the IPFS nodes is turned off, which is unsafe outside of debugging.
+	ConditionResourceManagerDisabled string = "ResourceManagerDisabled"
+	// ResourceManagerReasonDebugging indicates the resource manager was disabled for debugging.
+	ResourceManagerReasonDebugging string = "DisabledForDebugging"
+	// ResourceManagerReasonEnabled indicates the resource manager is explicitly enabled on the IPFS nodes.
+	ResourceManagerReasonEnabled string = "Enabled"
+	// ConditionPeerIdentityMismatch is a status condition type that indicates whether some IPFS
+	// peers announce a peer ID other than the one stored for them in the identity Secret.
+	ConditionPeerIdentityMismatch string = "PeerIdentityMismatch"
+	// PeerIdentityReasonMatched indicates every peer announces its expected peer ID.
+	PeerIdentityReasonMatched string = "IdentitiesMatch"
+	// PeerIdentityReasonMismatched indicates some peers run from repos initialized with another
+	// identity, usually stale PVCs which must be removed.
+	PeerIdentityReasonMismatched string = "IdentityMismatch"
+	// ConditionSwarmKeyRotated is a status condition type that indicates whether every peer has
+	// switched over to the current private swarm key.
+	ConditionSwarmKeyRotated string = "SwarmKeyRotated"
+	// ConditionExtendedResourcesSchedulable is a status condition type that indicates whether the
+	// extended resources requested by the IPFS containers, e.g. devices, are provided by any node.
+	ConditionExtendedResourcesSchedulable string = "ExtendedResourcesSchedulable"
+	// ExtendedResourcesReasonSatisfiable indicates every requested extended resource is available.
+	ExtendedResourcesReasonSatisfiable string = "Satisfiable"
+	// ExtendedResourcesReasonUnschedulable indicates the pods would stay Pending, since no node
+	// provides enough of a requested extended resource.
+	ExtendedResourcesReasonUnschedulable string = "Unschedulable"
 )
 
 type ReproviderStrategy string
@@ -55,6 +154,145 @@ type ReprovideSettings struct {
 	Interval string `json:"interval,omitempty"`
 }
 
+type DatastoreBackend string
+
+const (
+	// DatastoreBackendFlatfs Stores blocks as individual files on the filesystem.
+	DatastoreBackendFlatfs DatastoreBackend = "flatfs"
+	// DatastoreBackendBadger Stores blocks in a badger key-value store.
+	DatastoreBackendBadger DatastoreBackend = "badger"
+)
+
+// NodeRole Describes the part the IPFS nodes of a cluster play in the network.
+type NodeRole string
+
+const (
+	// NodeRolePeer Runs storage peers which pin the content of the cluster.
+	NodeRolePeer NodeRole = "peer"
+	// NodeRoleDHTServer Runs nodes dedicated to serving the DHT, which are bound by
+	// the size of their routing table rather than by their storage.
+	NodeRoleDHTServer NodeRole = "dht-server"
+)
+
+type DatastoreSettings struct {
+	// Backend specifies which datastore backend IPFS should use, defaults to 'flatfs'.
+	// The backend of an existing repo cannot be changed in place.
+	// +kubebuilder:validation:Enum={flatfs,badger}
+	// +optional
+	Backend DatastoreBackend `json:"backend,omitempty"`
+	// AllowMigration permits changing the backend of an existing repo.
+	// The repo must be migrated or recreated separately before this is set.
+	// +optional
+	AllowMigration bool `json:"allowMigration,omitempty"`
+	// BackupBeforeMigration archives the repo before any in-place migration runs,
+	// so that a failed migration can be recovered from.
+	// +optional
+	BackupBeforeMigration bool `json:"backupBeforeMigration,omitempty"`
+}
+
+type GatewaySettings struct {
+	// ExportScratchSize sets the size of the scratch space the gateway uses to stage
+	// CAR and archive exports. Defaults to 1Gi.
+	// +optional
+	ExportScratchSize *resource.Quantity `json:"exportScratchSize,omitempty"`
+	// TLSSecretName names the kubernetes.io/tls Secret holding the certificate the gateway
+	// is served with. The Secret is checked on each reconcile and reported by the
+	// GatewayTLSValid condition.
+	// +optional
+	TLSSecretName string `json:"tlsSecretName,omitempty"`
+	// Host sets the hostname the gateway is served at, which the certificate of the TLS
+	// Secret must be valid for.
+	// +optional
+	Host string `json:"host,omitempty"`
+}
+
+type S3BackupSettings struct {
+	// Endpoint is the URL of the S3 API, e.g. `https://s3.eu-west-1.amazonaws.com`.
+	Endpoint string `json:"endpoint"`
+	// Bucket names the bucket backups are uploaded to.
+	Bucket string `json:"bucket"`
+	// CredentialsSecretName names the Secret holding the AWS_ACCESS_KEY_ID and
+	// AWS_SECRET_ACCESS_KEY used to upload backups.
+	CredentialsSecretName string `json:"credentialsSecretName"`
+}
+
+type BackupSettings struct {
+	// Schedule sets when the pinset is backed up, in cron format, e.g. '0 3 * * *'.
+	Schedule string `json:"schedule"`
+	// VolumeClaimName names the PVC backups are written to.
+	// +optional
+	VolumeClaimName string `json:"volumeClaimName,omitempty"`
+	// S3 describes the S3 compatible bucket backups are uploaded to. Exactly one of
+	// volumeClaimName and s3 must be set.
+	// +optional
+	S3 *S3BackupSettings `json:"s3,omitempty"`
+}
+
+type RestoreSettings struct {
+	// VolumeClaimName names the PVC holding the backup to restore.
+	VolumeClaimName string `json:"volumeClaimName"`
+	// Path is the path of the backed up pinset within the volume claim,
+	// e.g. 'pinset-20221001030000.json'.
+	Path string `json:"path"`
+}
+
+type PeerTags struct {
+	// Ordinal is the StatefulSet ordinal of the peer the tags apply to.
+	Ordinal int32 `json:"ordinal"`
+	// Tags are merged over the tags shared by all peers.
+	Tags map[string]string `json:"tags"`
+}
+
+type ClusterTagSettings struct {
+	// Tags are set on every IPFS Cluster peer, e.g. "region: eu".
+	// Pins can be constrained to peers with matching tags through the allocator.
+	// +optional
+	Tags map[string]string `json:"tags,omitempty"`
+	// Peers sets additional tags on individual peers.
+	// +optional
+	Peers []PeerTags `json:"peers,omitempty"`
+}
+
+// TagsForPeer Returns the tags of the peer with the given ordinal, with
+// peer-specific tags taking precedence over the shared ones.
+func (s ClusterTagSettings) TagsForPeer(ordinal int32) map[string]string {
+	tags := make(map[string]string, len(s.Tags))
+	for k, v := range s.Tags {
+		tags[k] = v
+	}
+	for _, peer := range s.Peers {
+		if peer.Ordinal != ordinal {
+			continue
+		}
+		for k, v := range peer.Tags {
+			tags[k] = v
+		}
+	}
+	return tags
+}
+
+type StorageTier struct {
+	// StorageClassName is the StorageClass used by volumes in this tier.
+	StorageClassName string `json:"storageClassName"`
+	// MinSize is the smallest volume placed in this tier.
+	MinSize resource.Quantity `json:"minSize"`
+	// Roles restricts the tier to pods with one of the given roles, e.g. "peer" or "gateway".
+	// The tier applies to all roles if omitted.
+	// +optional
+	Roles []string `json:"roles,omitempty"`
+}
+
+type StorageSettings struct {
+	// Tiers selects the StorageClass of each IPFS volume by its size, the tier with the
+	// largest MinSize not exceeding the volume size is used.
+	// +optional
+	Tiers []StorageTier `json:"tiers,omitempty"`
+	// DefaultStorageClassName is used for volumes which fall into no tier.
+	// The cluster's default StorageClass is used if omitted.
+	// +optional
+	DefaultStorageClassName string `json:"defaultStorageClassName,omitempty"`
+}
+
 type followParams struct {
 	Name     string `json:"name"`
 	Template string `json:"template"`
@@ -68,6 +306,12 @@ type NetworkConfig struct {
 	// the global IPFS network or create its own.
 	// +kubebuilder:default:=true
 	Public bool `json:"public,omitempty"`
+	// acceleratedDHTClient is a switch which defines whether the IPFS nodes run the
+	// accelerated DHT client, which is much faster at providing large pinsets at the
+	// cost of memory. The memory of the nodes is raised along when it is enabled.
+	// +kubebuilder:default:=true
+	// +optional
+	AcceleratedDHTClient bool `json:"acceleratedDHTClient"`
 }
 
 // IpfsClusterSpec defines the desired state of the IpfsCluster.
@@ -83,6 +327,10 @@ type IpfsClusterSpec struct {
 	// follows defines the list of other IPFS Clusters this one should follow.
 	// +optional
 	Follows []*followParams `json:"follows,omitempty"`
+	// followerMode Runs the peers in IPFS Cluster follower mode, in which they reject the requests
+	// of the REST API which modify the pinset, so that it can only change by following upstream.
+	// +optional
+	FollowerMode bool `json:"followerMode,omitempty"`
 	// ipfsResources specifies the resource requirements for each IPFS container. If this
 	// value is omitted, then the operator will automatically determine these settings
 	// based on the storage sizes used.
@@ -92,11 +340,72 @@ type IpfsClusterSpec struct {
 	// should use when reproviding content.
 	// +optional
 	Reprovider ReprovideSettings `json:"reprovider,omitempty"`
+	// identitySecretRef references the Secret holding the peer identities of this cluster.
+	// If omitted, a Secret named after the IpfsCluster is generated. Each Secret may only
+	// be used by a single IpfsCluster, since sharing it results in duplicate peer IDs.
+	// +optional
+	IdentitySecretRef *corev1.LocalObjectReference `json:"identitySecretRef,omitempty"`
+	// datastore Describes the datastore used by each IPFS node.
+	// +optional
+	Datastore DatastoreSettings `json:"datastore,omitempty"`
+	// gateway Describes the settings used by IPFS nodes serving the gateway.
+	// +optional
+	Gateway GatewaySettings `json:"gateway,omitempty"`
+	// backup Schedules backups of the pinset. The backup CronJob is only created once the
+	// destination has been found writable, as reported by the BackupDestinationReady condition.
+	// +optional
+	Backup *BackupSettings `json:"backup,omitempty"`
+	// restore Restores a backed up pinset into the cluster. The restore is only started while
+	// the cluster holds no pins, as reported by the StateRestoreAllowed condition.
+	// +optional
+	Restore *RestoreSettings `json:"restore,omitempty"`
+	// clusterTags Describes the tags IPFS Cluster peers advertise for allocation.
+	// +optional
+	ClusterTags ClusterTagSettings `json:"clusterTags,omitempty"`
+	// storage Describes how the StorageClass of each IPFS volume is selected.
+	// +optional
+	Storage StorageSettings `json:"storage,omitempty"`
+	// clusterAPIBasicAuthSecretRef references the Secret key holding the credentials of the
+	// IPFS Cluster REST API as comma-separated `user:password` pairs. The credentials are
+	// passed to the peers from the Secret and are never written into the ConfigMap.
+	// +optional
+	ClusterAPIBasicAuthSecretRef *corev1.SecretKeySelector `json:"clusterAPIBasicAuthSecretRef,omitempty"`
+	// clusterAPITLSSecretRef references the kubernetes.io/tls Secret holding the certificate
+	// served by the IPFS Cluster REST API. The peers don't reload a rotated certificate, so
+	// they are rolled out whenever the certificate material of the Secret changes.
+	// +optional
+	ClusterAPITLSSecretRef *corev1.LocalObjectReference `json:"clusterAPITLSSecretRef,omitempty"`
+	// role Describes the part the IPFS nodes play in the network, defaults to 'peer'.
+	// Nodes with the 'dht-server' role are sized by the number of peers they track
+	// rather than by their storage, and are evicted before storage peers.
+	// +kubebuilder:validation:Enum={peer,dht-server}
+	// +optional
+	Role NodeRole `json:"role,omitempty"`
+}
+
+// PeerStatus Describes a running IPFS Cluster peer, as announced by its REST API.
+type PeerStatus struct {
+	// Name is the name of the pod running the peer.
+	Name string `json:"name"`
+	// ClusterPeerID is the peer ID the IPFS Cluster peer announces.
+	// +optional
+	ClusterPeerID string `json:"clusterPeerID,omitempty"`
+	// PeerID is the peer ID the IPFS daemon of the peer announces.
+	// +optional
+	PeerID string `json:"peerID,omitempty"`
 }
 
 type IpfsClusterStatus struct {
 	Conditions    []metav1.Condition `json:"conditions,omitempty"`
 	CircuitRelays []string           `json:"circuitRelays,omitempty"`
+	// DatastoreBackend records the datastore backend the IPFS repos were initialized with.
+	DatastoreBackend DatastoreBackend `json:"datastoreBackend,omitempty"`
+	// OrphanedVolumeClaims lists the PVCs left behind by peers removed when scaling down.
+	OrphanedVolumeClaims []string `json:"orphanedVolumeClaims,omitempty"`
+	// ReclaimableStorage is the total size of the orphaned PVCs.
+	ReclaimableStorage *resource.Quantity `json:"reclaimableStorage,omitempty"`
+	// Peers lists the peers whose REST API could be reached during the last reconcile.
+	Peers []PeerStatus `json:"peers,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -111,6 +420,14 @@ type IpfsCluster struct {
 	Status IpfsClusterStatus `json:"status,omitempty"`
 }
 
+// IdentitySecretName Returns the name of the Secret holding the peer identities.
+func (m *IpfsCluster) IdentitySecretName() string {
+	if m.Spec.IdentitySecretRef != nil && m.Spec.IdentitySecretRef.Name != "" {
+		return m.Spec.IdentitySecretRef.Name
+	}
+	return "ipfs-cluster-" + m.Name
+}
+
 //+kubebuilder:object:root=true
 
 // IpfsList contains a list of Ipfs.
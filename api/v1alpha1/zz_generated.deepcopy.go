@@ -27,6 +27,26 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupSettings) DeepCopyInto(out *BackupSettings) {
+	*out = *in
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(S3BackupSettings)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupSettings.
+func (in *BackupSettings) DeepCopy() *BackupSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CircuitRelay) DeepCopyInto(out *CircuitRelay) {
 	*out = *in
@@ -122,6 +142,70 @@ func (in *CircuitRelayStatus) DeepCopy() *CircuitRelayStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterTagSettings) DeepCopyInto(out *ClusterTagSettings) {
+	*out = *in
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Peers != nil {
+		in, out := &in.Peers, &out.Peers
+		*out = make([]PeerTags, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterTagSettings.
+func (in *ClusterTagSettings) DeepCopy() *ClusterTagSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterTagSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatastoreSettings) DeepCopyInto(out *DatastoreSettings) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatastoreSettings.
+func (in *DatastoreSettings) DeepCopy() *DatastoreSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(DatastoreSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GatewaySettings) DeepCopyInto(out *GatewaySettings) {
+	*out = *in
+	if in.ExportScratchSize != nil {
+		in, out := &in.ExportScratchSize, &out.ExportScratchSize
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GatewaySettings.
+func (in *GatewaySettings) DeepCopy() *GatewaySettings {
+	if in == nil {
+		return nil
+	}
+	out := new(GatewaySettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IpfsCluster) DeepCopyInto(out *IpfsCluster) {
 	*out = *in
@@ -204,6 +288,35 @@ func (in *IpfsClusterSpec) DeepCopyInto(out *IpfsClusterSpec) {
 		(*in).DeepCopyInto(*out)
 	}
 	out.Reprovider = in.Reprovider
+	if in.IdentitySecretRef != nil {
+		in, out := &in.IdentitySecretRef, &out.IdentitySecretRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+	out.Datastore = in.Datastore
+	in.Gateway.DeepCopyInto(&out.Gateway)
+	if in.Backup != nil {
+		in, out := &in.Backup, &out.Backup
+		*out = new(BackupSettings)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Restore != nil {
+		in, out := &in.Restore, &out.Restore
+		*out = new(RestoreSettings)
+		**out = **in
+	}
+	in.ClusterTags.DeepCopyInto(&out.ClusterTags)
+	in.Storage.DeepCopyInto(&out.Storage)
+	if in.ClusterAPIBasicAuthSecretRef != nil {
+		in, out := &in.ClusterAPIBasicAuthSecretRef, &out.ClusterAPIBasicAuthSecretRef
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ClusterAPITLSSecretRef != nil {
+		in, out := &in.ClusterAPITLSSecretRef, &out.ClusterAPITLSSecretRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IpfsClusterSpec.
@@ -231,6 +344,21 @@ func (in *IpfsClusterStatus) DeepCopyInto(out *IpfsClusterStatus) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.OrphanedVolumeClaims != nil {
+		in, out := &in.OrphanedVolumeClaims, &out.OrphanedVolumeClaims
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ReclaimableStorage != nil {
+		in, out := &in.ReclaimableStorage, &out.ReclaimableStorage
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Peers != nil {
+		in, out := &in.Peers, &out.Peers
+		*out = make([]PeerStatus, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IpfsClusterStatus.
@@ -273,6 +401,43 @@ func (in *NetworkConfig) DeepCopy() *NetworkConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PeerStatus) DeepCopyInto(out *PeerStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PeerStatus.
+func (in *PeerStatus) DeepCopy() *PeerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PeerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PeerTags) DeepCopyInto(out *PeerTags) {
+	*out = *in
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PeerTags.
+func (in *PeerTags) DeepCopy() *PeerTags {
+	if in == nil {
+		return nil
+	}
+	out := new(PeerTags)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ReprovideSettings) DeepCopyInto(out *ReprovideSettings) {
 	*out = *in
@@ -287,3 +452,76 @@ func (in *ReprovideSettings) DeepCopy() *ReprovideSettings {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestoreSettings) DeepCopyInto(out *RestoreSettings) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoreSettings.
+func (in *RestoreSettings) DeepCopy() *RestoreSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoreSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *S3BackupSettings) DeepCopyInto(out *S3BackupSettings) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new S3BackupSettings.
+func (in *S3BackupSettings) DeepCopy() *S3BackupSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(S3BackupSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageSettings) DeepCopyInto(out *StorageSettings) {
+	*out = *in
+	if in.Tiers != nil {
+		in, out := &in.Tiers, &out.Tiers
+		*out = make([]StorageTier, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageSettings.
+func (in *StorageSettings) DeepCopy() *StorageSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageTier) DeepCopyInto(out *StorageTier) {
+	*out = *in
+	out.MinSize = in.MinSize.DeepCopy()
+	if in.Roles != nil {
+		in, out := &in.Roles, &out.Roles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageTier.
+func (in *StorageTier) DeepCopy() *StorageTier {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageTier)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+// backupDestination Returns the destination the backups of the given settings are written to.
+func backupDestination(backup *clusterv1alpha1.BackupSettings) utils.BackupDestination {
+	dest := utils.BackupDestination{PersistentVolumeClaimName: backup.VolumeClaimName}
+	if backup.S3 != nil {
+		dest.S3 = &utils.S3BackupDestination{
+			Endpoint:              backup.S3.Endpoint,
+			Bucket:                backup.S3.Bucket,
+			CredentialsSecretName: backup.S3.CredentialsSecretName,
+		}
+	}
+	return dest
+}
+
+// CheckBackupDestination Records on the status of the instance whether the given backup destination
+// can be written to. The backup CronJob must not be scheduled when an error is returned.
+func (r *IpfsClusterReconciler) CheckBackupDestination(
+	ctx context.Context,
+	m *clusterv1alpha1.IpfsCluster,
+	dest utils.BackupDestination,
+) error {
+	condition := metav1.Condition{
+		Type:    clusterv1alpha1.ConditionBackupDestinationReady,
+		Status:  metav1.ConditionTrue,
+		Reason:  clusterv1alpha1.BackupDestinationReasonReady,
+		Message: "backup destination is ready",
+	}
+	validationErr := utils.ValidateBackupDestination(ctx, r, m.Namespace, dest)
+	if validationErr != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = clusterv1alpha1.BackupDestinationReasonInvalid
+		condition.Message = validationErr.Error()
+	}
+	meta.SetStatusCondition(&m.Status.Conditions, condition)
+	return validationErr
+}
+
+// EnsureBackupCronJob Schedules the backups of the pinset requested by the instance, once their
+// destination has passed CheckBackupDestination. A CronJob already scheduled is left as is while
+// the destination is invalid, and removed along with the condition once backups are turned off.
+func (r *IpfsClusterReconciler) EnsureBackupCronJob(
+	ctx context.Context,
+	m *clusterv1alpha1.IpfsCluster,
+	svcName string,
+) error {
+	log := ctrllog.FromContext(ctx)
+	cronJob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ipfs-cluster-backup-" + m.Name,
+			Namespace: m.Namespace,
+		},
+	}
+	if m.Spec.Backup == nil {
+		meta.RemoveStatusCondition(&m.Status.Conditions, clusterv1alpha1.ConditionBackupDestinationReady)
+		if err := r.Delete(ctx, cronJob); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("could not delete backup cronjob: %w", err)
+		}
+		return nil
+	}
+	dest := backupDestination(m.Spec.Backup)
+	if err := r.CheckBackupDestination(ctx, m, dest); err != nil {
+		log.Info("not scheduling backups", "reason", err.Error())
+		return nil
+	}
+	desired := utils.BackupCronJob(
+		cronJob.Name, m.Namespace, ipfsClusterImage, clusterAPIMultiaddr(m, svcName), m.Spec.Backup.Schedule, dest,
+	)
+	op, err := ctrl.CreateOrUpdate(ctx, r.Client, cronJob, func() error {
+		cronJob.Spec = desired.Spec
+		return ctrl.SetControllerReference(m, cronJob, r.Scheme)
+	})
+	if err != nil {
+		return fmt.Errorf("could not ensure backup cronjob: %w", err)
+	}
+	if op != controllerutil.OperationResultNone {
+		log.Info("scheduled backups", "cronjob", client.ObjectKeyFromObject(cronJob), "operation", op)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+// clusterAPIClient Returns a client for the IPFS Cluster REST API served at the given host, which
+// authenticates with the credentials and trusts the certificate the instance configures the API with.
+func (r *IpfsClusterReconciler) clusterAPIClient(
+	ctx context.Context,
+	m *clusterv1alpha1.IpfsCluster,
+	host string,
+) (*utils.ClusterAPIClient, error) {
+	var credentials string
+	if ref := m.Spec.ClusterAPIBasicAuthSecretRef; ref != nil {
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: m.Namespace}, secret); err != nil {
+			return nil, fmt.Errorf("could not get cluster api credentials: %w", err)
+		}
+		credentials = string(secret.Data[ref.Key])
+	}
+	var tlsSecret *corev1.Secret
+	if ref := m.Spec.ClusterAPITLSSecretRef; ref != nil {
+		tlsSecret = &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: m.Namespace}, tlsSecret); err != nil {
+			return nil, fmt.Errorf("could not get cluster api tls secret: %w", err)
+		}
+	}
+	return utils.NewClusterAPIClient(host, portAPIHTTP, credentials, tlsSecret)
+}
+
+// clusterServiceHost Returns the in-cluster hostname of the given Service of the instance.
+func clusterServiceHost(m *clusterv1alpha1.IpfsCluster, svcName string) string {
+	return fmt.Sprintf("%s.%s.svc", svcName, m.Namespace)
+}
+
+// clusterAPIMultiaddr Returns the address of the IPFS Cluster REST API behind the given Service
+// of the instance, in the multiaddr form ipfs-cluster-ctl takes.
+func clusterAPIMultiaddr(m *clusterv1alpha1.IpfsCluster, svcName string) string {
+	return fmt.Sprintf("/dns4/%s/tcp/%d", clusterServiceHost(m, svcName), portAPIHTTP)
+}
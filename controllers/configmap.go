@@ -3,6 +3,7 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -10,11 +11,11 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	"github.com/alecthomas/units"
-	"github.com/ipfs/kubo/config"
 	"github.com/libp2p/go-libp2p/core/peer"
 	ma "github.com/multiformats/go-multiaddr"
 	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
 	"github.com/redhat-et/ipfs-operator/controllers/scripts"
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
 )
@@ -26,6 +27,10 @@ const (
 	// ScriptIPFSClusterEntryPoint Defines a shell script used as the entrypoint
 	// for the IPFS Cluster container.
 	ScriptIPFSClusterEntryPoint = "entrypoint.sh"
+	// KeyClusterPeerstore Holds the rendered IPFS Cluster peerstore file.
+	KeyClusterPeerstore = "peerstore"
+	// KeyClusterPeerstoreEntries Holds the peerstore entries along with their expiry.
+	KeyClusterPeerstoreEntries = "peerstore-entries.json"
 )
 
 // EnsureConfigMapScripts Returns a mutate function which loads the given configMap with scripts that
@@ -53,23 +58,14 @@ func (r *IpfsClusterReconciler) EnsureConfigMapScripts(
 
 	op, err := ctrl.CreateOrUpdate(ctx, r.Client, cm, func() error {
 		// TODO: compute these values in another function & place them here
-		// convert multiaddrs to strings
-		relayStaticStrs := make([]string, len(relayStatic))
-		for i, maddr := range relayStatic {
-			relayStaticStrs[i] = maddr.String()
-		}
-
-		relayConfig := config.RelayClient{
-			Enabled:      config.True,
-			StaticRelays: relayStaticStrs,
+		// reach peers behind NAT through the circuit relays
+		relayConfig, internalErr := scripts.RelayClientConfig(relayStatic)
+		if internalErr != nil {
+			return fmt.Errorf("could not configure relay client: %w", internalErr)
 		}
 
 		// compute storage sizes of IPFS volumes
-		sizei64, ok := m.Spec.IpfsStorage.AsInt64()
-		if !ok {
-			sizei64 = m.Spec.IpfsStorage.ToDec().Value()
-		}
-		maxStorage := MaxIPFSStorage(sizei64)
+		maxStorage := MaxIPFSStorage(utils.DataVolumesStorage(m.Spec.IpfsStorage))
 		maxStorageS := fmt.Sprintf("%dB", maxStorage)
 		bloomFilterSize := scripts.CalculateBloomFilterSize(maxStorage)
 
@@ -91,6 +87,8 @@ func (r *IpfsClusterReconciler) EnsureConfigMapScripts(
 			bloomFilterSize,
 			reproviderInterval,
 			string(reproviderStrategy),
+			desiredDatastoreBackend(m),
+			m.Spec.Networking.AcceleratedDHTClient,
 			bootstrapPeers,
 		)
 		if internalErr != nil {
@@ -114,6 +112,44 @@ func (r *IpfsClusterReconciler) EnsureConfigMapScripts(
 	return cm, nil
 }
 
+// EnsureClusterPeerstore Rewrites the IPFS Cluster peerstore held by the peerstore ConfigMap on each
+// reconcile, refreshing the entries of the given current peer addresses to expire after ttl, so that
+// addresses of peers gone for longer age out. Expiries are rounded down to a 24th of the ttl, which
+// keeps the ConfigMap from being updated on every reconcile.
+func (r *IpfsClusterReconciler) EnsureClusterPeerstore(
+	ctx context.Context,
+	m *clusterv1alpha1.IpfsCluster,
+	current []string,
+	ttl time.Duration,
+) (*corev1.ConfigMap, error) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      peerstoreConfigMapName(m),
+			Namespace: m.Namespace,
+		},
+	}
+	now := time.Now().Truncate(ttl / 24)
+	if _, err := ctrl.CreateOrUpdate(ctx, r.Client, cm, func() error {
+		entries, err := utils.UnmarshalPeerstoreEntries(cm.Data[KeyClusterPeerstoreEntries])
+		if err != nil {
+			return err
+		}
+		entries = utils.RefreshPeerstore(entries, current, now, ttl)
+		encoded, err := utils.MarshalPeerstoreEntries(entries)
+		if err != nil {
+			return err
+		}
+		cm.Data = map[string]string{
+			KeyClusterPeerstore:        utils.RenderPeerstore(entries),
+			KeyClusterPeerstoreEntries: encoded,
+		}
+		return ctrl.SetControllerReference(m, cm, r.Scheme)
+	}); err != nil {
+		return nil, fmt.Errorf("could not ensure peerstore configmap: %w", err)
+	}
+	return cm, nil
+}
+
 // staticAddrsFromRelayPeers Extracts all of the static addresses from the
 // given list of relayPeers.
 func staticAddrsFromRelayPeers(relayPeers []peer.AddrInfo) ([]ma.Multiaddr, error) {
@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+// desiredDatastoreBackend Returns the datastore backend requested by the given
+// IPFS cluster, defaulting to flatfs.
+func desiredDatastoreBackend(m *clusterv1alpha1.IpfsCluster) clusterv1alpha1.DatastoreBackend {
+	if m.Spec.Datastore.Backend == "" {
+		return clusterv1alpha1.DatastoreBackendFlatfs
+	}
+	return m.Spec.Datastore.Backend
+}
+
+// ensureDatastoreBackend Blocks a change of the datastore backend unless a migration
+// was explicitly allowed, and records the backend in use on the status of the instance.
+func (r *IpfsClusterReconciler) ensureDatastoreBackend(
+	ctx context.Context,
+	m *clusterv1alpha1.IpfsCluster,
+) error {
+	log := ctrllog.FromContext(ctx)
+	desired := desiredDatastoreBackend(m)
+	condition := metav1.Condition{
+		Type:    clusterv1alpha1.ConditionDatastoreMigrationBlocked,
+		Status:  metav1.ConditionFalse,
+		Reason:  clusterv1alpha1.DatastoreMigrationReasonNone,
+		Message: fmt.Sprintf("using datastore backend %q", desired),
+	}
+	// the repos of an existing StatefulSet were initialized before the backend was recorded
+	sts := &appsv1.StatefulSet{}
+	err := r.Get(ctx, client.ObjectKey{Namespace: m.Namespace, Name: "ipfs-cluster-" + m.Name}, sts)
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("could not get statefulset: %w", err)
+	}
+	initialized := err == nil
+	migrationErr := utils.CheckDatastoreMigration(
+		m.Status.DatastoreBackend, desired, initialized, m.Spec.Datastore.AllowMigration,
+	)
+	if migrationErr != nil {
+		log.Info("blocking datastore backend change", "recorded", m.Status.DatastoreBackend, "desired", desired)
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = clusterv1alpha1.DatastoreMigrationReasonBackendChanged
+		condition.Message = migrationErr.Error()
+	} else {
+		m.Status.DatastoreBackend = desired
+	}
+	meta.SetStatusCondition(&m.Status.Conditions, condition)
+	return migrationErr
+}
@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+// CheckGatewayTLSSecret Records on the status of the instance whether the TLS Secret referenced for
+// the gateway exists and contains a valid certificate and key pair for the gateway host. The
+// condition is removed when no TLS Secret is referenced.
+func (r *IpfsClusterReconciler) CheckGatewayTLSSecret(
+	ctx context.Context,
+	m *clusterv1alpha1.IpfsCluster,
+) error {
+	log := ctrllog.FromContext(ctx)
+	secretName := m.Spec.Gateway.TLSSecretName
+	if secretName == "" {
+		meta.RemoveStatusCondition(&m.Status.Conditions, clusterv1alpha1.ConditionGatewayTLSValid)
+		return nil
+	}
+	var secret *corev1.Secret
+	found := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: m.Namespace}, found)
+	switch {
+	case err == nil:
+		secret = found
+	case !errors.IsNotFound(err):
+		return fmt.Errorf("could not get gateway tls secret: %w", err)
+	}
+	condition := utils.GatewayTLSCondition(secretName, secret, m.Spec.Gateway.Host)
+	if condition.Reason == clusterv1alpha1.GatewayTLSReasonInvalid {
+		log.Info("gateway tls secret is invalid", "secret", secretName, "reason", condition.Message)
+	}
+	meta.SetStatusCondition(&m.Status.Conditions, condition)
+	return nil
+}
@@ -22,7 +22,9 @@ import (
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -30,11 +32,14 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/libp2p/go-libp2p/core/peer"
 	ma "github.com/multiformats/go-multiaddr"
 	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
 )
 
 const (
@@ -49,11 +54,14 @@ type IpfsClusterReconciler struct {
 
 //+kubebuilder:rbac:groups=*,resources=*,verbs=get;list
 //+kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=cluster.ipfs.io,resources=ipfsclusters,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=cluster.ipfs.io,resources=ipfsclusters/finalizers,verbs=update
 //+kubebuilder:rbac:groups=cluster.ipfs.io,resources=ipfsclusters/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;delete
 //+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
@@ -93,27 +101,47 @@ func (r *IpfsClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 }
 
 // createTrackedObjects Creates a mapping from client objects to their mutating functions.
+// The steps record their findings on the status of the instance, which is written once
+// at the end of the pass, even when a step fails.
 func (r *IpfsClusterReconciler) createTrackedObjects(
 	ctx context.Context,
 	instance *clusterv1alpha1.IpfsCluster,
-) error {
-	var err error
+) (err error) {
+	original := instance.Status.DeepCopy()
+	defer func() {
+		if statusErr := r.updateStatus(ctx, instance, original); statusErr != nil && err == nil {
+			err = statusErr
+		}
+	}()
 	var svc *corev1.Service
-	var secret *corev1.Secret
+	var sts *appsv1.StatefulSet
+	var secret, ipfsSecret, clusterSecret *corev1.Secret
 	var cmScripts *corev1.ConfigMap
 	var relayPeers []peer.AddrInfo
 	var relayStatic []ma.Multiaddr
 	var bootstrapPeers []string
 
+	if err = r.ensureDatastoreBackend(ctx, instance); err != nil {
+		return fmt.Errorf("could not ensure datastore backend: %w", err)
+	}
 	if _, err = r.ensureSA(ctx, instance); err != nil {
 		return fmt.Errorf("retrieved error while ensuring SA: %w", err)
 	}
 	if svc, err = r.ensureServiceCluster(ctx, instance); err != nil {
 		return fmt.Errorf("could not ensure service cluster: %w", err)
 	}
+	if err = utils.ValidateIdentitySecret(ctx, r.Client, instance); err != nil {
+		return fmt.Errorf("invalid identity secret: %w", err)
+	}
+	if err = r.CheckGatewayTLSSecret(ctx, instance); err != nil {
+		return fmt.Errorf("could not check gateway tls secret: %w", err)
+	}
 	if secret, err = r.EnsureSecretConfig(ctx, instance); err != nil {
 		return fmt.Errorf("failed to ensure secret config: %w", err)
 	}
+	if ipfsSecret, clusterSecret, err = r.EnsureComponentSecrets(ctx, instance, secret); err != nil {
+		return fmt.Errorf("failed to ensure component secrets: %w", err)
+	}
 	if err = r.EnsureCircuitRelay(ctx, instance, secret); err != nil {
 		return fmt.Errorf("failed to ensure circuit relays: %w", err)
 	}
@@ -132,9 +160,46 @@ func (r *IpfsClusterReconciler) createTrackedObjects(
 	if cmScripts, err = r.EnsureConfigMapScripts(ctx, instance, relayPeers, relayStatic, bootstrapPeers); err != nil {
 		return fmt.Errorf("could not ensure configmap scripts: %w", err)
 	}
-	if _, err = r.StatefulSet(ctx, instance, svc.Name, secret.ObjectMeta.Name, cmScripts.ObjectMeta.Name); err != nil {
+	if sts, err = r.StatefulSet(
+		ctx, instance, svc.Name, ipfsSecret.ObjectMeta.Name, clusterSecret.ObjectMeta.Name, cmScripts.ObjectMeta.Name,
+	); err != nil {
 		return fmt.Errorf("could not ensure statefulset: %w", err)
 	}
+	if err = r.recreateOutdatedPods(ctx, sts); err != nil {
+		return fmt.Errorf("could not recreate outdated pods: %w", err)
+	}
+	if err = r.refreshPeerstore(ctx, instance, sts); err != nil {
+		return fmt.Errorf("could not refresh peerstore: %w", err)
+	}
+	if _, err = r.CheckPeerIdentities(instance, secret); err != nil {
+		return fmt.Errorf("could not check peer identities: %w", err)
+	}
+	if err = r.EnsureBackupCronJob(ctx, instance, svc.Name); err != nil {
+		return fmt.Errorf("could not ensure backup cronjob: %w", err)
+	}
+	if err = r.EnsureStateRestore(ctx, instance, svc.Name); err != nil {
+		return fmt.Errorf("could not ensure state restore: %w", err)
+	}
+	r.reportVolumeClaimTemplateDrift(ctx, instance, sts)
+	r.reportStorageShrink(ctx, instance, sts)
+	if err = r.reportOrphanedVolumeClaims(ctx, instance); err != nil {
+		return fmt.Errorf("could not report orphaned volume claims: %w", err)
+	}
+	return nil
+}
+
+// updateStatus Writes the status of the instance, unless it is unchanged from the original.
+func (r *IpfsClusterReconciler) updateStatus(
+	ctx context.Context,
+	instance *clusterv1alpha1.IpfsCluster,
+	original *clusterv1alpha1.IpfsClusterStatus,
+) error {
+	if equality.Semantic.DeepEqual(original, &instance.Status) {
+		return nil
+	}
+	if err := r.Status().Update(ctx, instance); err != nil {
+		return fmt.Errorf("could not update status: %w", err)
+	}
 	return nil
 }
 
@@ -159,7 +224,17 @@ func (r *IpfsClusterReconciler) ensureIPFSCluster(
 }
 
 // SetupWithManager sets up the controller with the Manager.
+// The number of workers is derived from the IpfsClusters present at startup.
 func (r *IpfsClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// the cache isn't running yet, so the clusters are counted through the API reader
+	clusters := &clusterv1alpha1.IpfsClusterList{}
+	clusterCount := 0
+	if err := mgr.GetAPIReader().List(context.Background(), clusters); err != nil {
+		ctrllog.Log.Error(err, "could not count ipfs clusters, running a single reconcile worker")
+	} else {
+		clusterCount = len(clusters.Items)
+	}
+	maxConcurrentReconciles := utils.SuggestedMaxConcurrentReconciles(clusterCount, mgr.GetConfig().QPS)
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&clusterv1alpha1.IpfsCluster{}).
 		Owns(&appsv1.StatefulSet{}, builder.OnlyMetadata).
@@ -167,12 +242,29 @@ func (r *IpfsClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Owns(&corev1.ServiceAccount{}, builder.OnlyMetadata).
 		Owns(&corev1.Secret{}, builder.OnlyMetadata).
 		Owns(&corev1.ConfigMap{}, builder.OnlyMetadata).
+		Owns(&batchv1.CronJob{}, builder.OnlyMetadata).
 		Owns(&clusterv1alpha1.IpfsCluster{}, builder.OnlyMetadata).
+		Watches(
+			&source.Kind{Type: &corev1.Secret{}},
+			handler.EnqueueRequestsFromMapFunc(r.clustersServingClusterAPITLSSecret),
+			builder.OnlyMetadata,
+		).
 		WithOptions(controller.Options{
-			MaxConcurrentReconciles: 1,
+			MaxConcurrentReconciles: maxConcurrentReconciles,
 		}).Complete(r)
 }
 
+// clustersServingClusterAPITLSSecret Maps a Secret to the IpfsClusters of its namespace which serve
+// their REST API with it, since a rotated TLS Secret isn't owned by the cluster it is served by.
+func (r *IpfsClusterReconciler) clustersServingClusterAPITLSSecret(secret client.Object) []ctrl.Request {
+	clusters := &clusterv1alpha1.IpfsClusterList{}
+	if err := r.List(context.Background(), clusters, client.InNamespace(secret.GetNamespace())); err != nil {
+		ctrllog.Log.Error(err, "could not list ipfs clusters serving secret", "secret", secret.GetName())
+		return nil
+	}
+	return utils.ClusterAPITLSSecretRequests(secret, clusters.Items)
+}
+
 func getBootstrapAddrs(secret *corev1.Secret, relayPeers []peer.AddrInfo) ([]string, error) {
 	bootstrapPeers := []string{}
 	peer0IDKey := KeyPeerIDPrefix + "0"
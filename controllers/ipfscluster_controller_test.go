@@ -11,12 +11,19 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/redhat-et/ipfs-operator/api/v1alpha1"
 	"github.com/redhat-et/ipfs-operator/controllers"
+	"github.com/redhat-et/ipfs-operator/controllers/scripts"
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
 )
 
 var _ = Describe("IPFS Reconciler", func() {
@@ -84,6 +91,21 @@ var _ = Describe("IPFS Reconciler", func() {
 		})
 	})
 
+	When("the peerstore is refreshed", func() {
+		const peer0 = "/ip4/10.0.0.1/tcp/9096/p2p/12D3KooWDpJ7As7BWAwRMfu1VU2WCqNjvq387JEYKDBj4kx6nXTN"
+		It("renders the current peers", func() {
+			cm, err := ipfsReconciler.EnsureClusterPeerstore(ctx, ipfs, []string{peer0}, utils.DefaultPeerstoreTTL)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cm.Name).To(Equal("ipfs-cluster-peerstore-" + myName))
+			Expect(cm.Data[controllers.KeyClusterPeerstore]).To(Equal(peer0 + "\n"))
+
+			// the address is kept until it expires, even once the peer is gone
+			cm, err = ipfsReconciler.EnsureClusterPeerstore(ctx, ipfs, nil, utils.DefaultPeerstoreTTL)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cm.Data[controllers.KeyClusterPeerstore]).To(Equal(peer0 + "\n"))
+		})
+	})
+
 	When("replicas are edited", func() {
 		// we always expect there to be cluster secrets, which have two values
 		const (
@@ -118,6 +140,127 @@ var _ = Describe("IPFS Reconciler", func() {
 			Expect(string(peerID)).NotTo(BeEmpty())
 		})
 	})
+
+	When("the stored swarm key is malformed", func() {
+		It("refuses to reconcile the secret", func() {
+			created, err := ipfsReconciler.EnsureSecretConfig(ctx, ipfs)
+			Expect(err).NotTo(HaveOccurred())
+			secretConfig := &v1.Secret{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(created), secretConfig)).To(Succeed())
+			secretConfig.Data[controllers.KeySwarmKey] = []byte("/key/swarm/psk/1.0.0/\n/base16/\nnot-hex")
+			Expect(k8sClient.Update(ctx, secretConfig)).To(Succeed())
+
+			_, err = ipfsReconciler.EnsureSecretConfig(ctx, ipfs)
+			Expect(err).To(MatchError(ContainSubstring("invalid swarm key")))
+		})
+	})
+
+	When("peers announce their identities", func() {
+		BeforeEach(func() {
+			ipfs.Spec.Replicas = 2
+		})
+		It("reports the peers announcing another peer ID", func() {
+			created, err := ipfsReconciler.EnsureSecretConfig(ctx, ipfs)
+			Expect(err).NotTo(HaveOccurred())
+			secretConfig := &v1.Secret{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(created), secretConfig)).To(Succeed())
+			peerID0 := string(secretConfig.Data[controllers.KeyPeerIDPrefix+"0"])
+			ipfs.Status.Peers = []v1alpha1.PeerStatus{
+				{Name: "ipfs-cluster-" + myName + "-0", PeerID: peerID0},
+				{Name: "ipfs-cluster-" + myName + "-1", PeerID: peerID0},
+			}
+
+			mismatched, err := ipfsReconciler.CheckPeerIdentities(ipfs, secretConfig)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mismatched).To(Equal([]int32{1}))
+			condition := meta.FindStatusCondition(ipfs.Status.Conditions, v1alpha1.ConditionPeerIdentityMismatch)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		})
+	})
+
+	When("a gateway TLS Secret is referenced", func() {
+		BeforeEach(func() {
+			ipfs.Spec.Gateway.TLSSecretName = "missing-gateway-tls"
+			ipfs.Spec.Gateway.Host = "gateway.example.com"
+		})
+		It("reports a missing secret as invalid", func() {
+			Expect(ipfsReconciler.CheckGatewayTLSSecret(ctx, ipfs)).To(Succeed())
+			condition := meta.FindStatusCondition(ipfs.Status.Conditions, v1alpha1.ConditionGatewayTLSValid)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(v1alpha1.GatewayTLSReasonInvalid))
+		})
+		It("removes the condition once the secret is no longer referenced", func() {
+			Expect(ipfsReconciler.CheckGatewayTLSSecret(ctx, ipfs)).To(Succeed())
+			ipfs.Spec.Gateway.TLSSecretName = ""
+			Expect(ipfsReconciler.CheckGatewayTLSSecret(ctx, ipfs)).To(Succeed())
+			Expect(meta.FindStatusCondition(ipfs.Status.Conditions, v1alpha1.ConditionGatewayTLSValid)).To(BeNil())
+		})
+	})
+
+	When("backups are scheduled", func() {
+		const svcName = "my-svc"
+		var cronJobKey types.NamespacedName
+		BeforeEach(func() {
+			ipfs.Spec.Backup = &v1alpha1.BackupSettings{
+				Schedule:        "0 3 * * *",
+				VolumeClaimName: "backups",
+			}
+			cronJobKey = types.NamespacedName{Name: "ipfs-cluster-backup-" + myName, Namespace: ipfs.Namespace}
+		})
+		It("holds the cronjob back while the volume claim is missing", func() {
+			Expect(ipfsReconciler.EnsureBackupCronJob(ctx, ipfs, svcName)).To(Succeed())
+			condition := meta.FindStatusCondition(ipfs.Status.Conditions, v1alpha1.ConditionBackupDestinationReady)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			err := k8sClient.Get(ctx, cronJobKey, &batchv1.CronJob{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+		It("schedules the cronjob once the volume claim exists", func() {
+			pvc := utils.BuildDataPVC("backups", *resource.NewQuantity(1, "Gi"), "")
+			pvc.Namespace = ipfs.Namespace
+			Expect(k8sClient.Create(ctx, &pvc)).To(Succeed())
+
+			Expect(ipfsReconciler.EnsureBackupCronJob(ctx, ipfs, svcName)).To(Succeed())
+			condition := meta.FindStatusCondition(ipfs.Status.Conditions, v1alpha1.ConditionBackupDestinationReady)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			cronJob := &batchv1.CronJob{}
+			Expect(k8sClient.Get(ctx, cronJobKey, cronJob)).To(Succeed())
+			Expect(cronJob.Spec.Schedule).To(Equal("0 3 * * *"))
+
+			ipfs.Spec.Backup = nil
+			Expect(ipfsReconciler.EnsureBackupCronJob(ctx, ipfs, svcName)).To(Succeed())
+			Expect(meta.FindStatusCondition(ipfs.Status.Conditions, v1alpha1.ConditionBackupDestinationReady)).To(BeNil())
+		})
+	})
+
+	When("a restore is requested", func() {
+		BeforeEach(func() {
+			ipfs.Spec.Restore = &v1alpha1.RestoreSettings{
+				VolumeClaimName: "backups",
+				Path:            "pinset-20221001030000.json",
+			}
+		})
+		It("only allows restoring into an empty pinset", func() {
+			Expect(ipfsReconciler.CheckStateRestoreTarget(ctx, ipfs, "my-svc", 0)).To(Succeed())
+			condition := meta.FindStatusCondition(ipfs.Status.Conditions, v1alpha1.ConditionStateRestoreAllowed)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+
+			Expect(ipfsReconciler.CheckStateRestoreTarget(ctx, ipfs, "my-svc", 3)).NotTo(Succeed())
+			condition = meta.FindStatusCondition(ipfs.Status.Conditions, v1alpha1.ConditionStateRestoreAllowed)
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(v1alpha1.StateRestoreReasonTargetNotEmpty))
+		})
+		It("holds the restore back while the pinset can't be listed", func() {
+			Expect(ipfsReconciler.EnsureStateRestore(ctx, ipfs, "my-svc")).To(Succeed())
+			key := types.NamespacedName{Name: "ipfs-cluster-restore-" + myName, Namespace: ipfs.Namespace}
+			err := k8sClient.Get(ctx, key, &batchv1.Job{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+	})
 })
 
 var _ = Describe("StatefulSet creation", func() {
@@ -131,11 +274,12 @@ var _ = Describe("StatefulSet creation", func() {
 	var ctx context.Context
 
 	const (
-		myName      = "my-fav-ipfs-node"
-		scriptsName = "my-scripts"
-		secretName  = "my-secret"
-		svcName     = "my-svc"
-		namespace   = "test"
+		myName            = "my-fav-ipfs-node"
+		scriptsName       = "my-scripts"
+		ipfsSecretName    = "my-ipfs-secret"
+		clusterSecretName = "my-cluster-secret"
+		svcName           = "my-svc"
+		namespace         = "test"
 	)
 	BeforeEach(func() {
 		ctx = context.TODO()
@@ -156,7 +300,7 @@ var _ = Describe("StatefulSet creation", func() {
 			},
 		}
 		Expect(k8sClient.Create(ctx, ipfs))
-		// secret = &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: secretName}}
+		// secret = &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: ipfsSecretName}}
 		// svc = &v1.Service{ObjectMeta: metav1.ObjectMeta{Name: svcName}}
 	})
 	AfterEach(func() {
@@ -193,7 +337,7 @@ var _ = Describe("StatefulSet creation", func() {
 		})
 		It("uses the IPFSCluster's IPFSResources setting", func() {
 			var err error
-			sts, err = ipfsReconciler.StatefulSet(ctx, ipfs, svcName, secretName, scriptsName)
+			sts, err = ipfsReconciler.StatefulSet(ctx, ipfs, svcName, ipfsSecretName, clusterSecretName, scriptsName)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(sts).NotTo(BeNil())
 
@@ -214,6 +358,24 @@ var _ = Describe("StatefulSet creation", func() {
 			Expect(ipfsResources.Requests[v1.ResourceCPU]).To(Equal(cpu))
 			Expect(ipfsResources.Requests[v1.ResourceMemory]).To(Equal(memory))
 		})
+		It("keeps the IPFSResources requests of dht servers", func() {
+			ipfs.Spec.Role = v1alpha1.NodeRoleDHTServer
+			ipfs.Spec.IPFSResources.Requests = v1.ResourceList{
+				v1.ResourceCPU:    cpuLimit,
+				v1.ResourceMemory: memoryLimit,
+			}
+			sts, err := ipfsReconciler.StatefulSet(ctx, ipfs, svcName, ipfsSecretName, clusterSecretName, scriptsName)
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, container := range sts.Spec.Template.Spec.Containers {
+				if container.Name == controllers.ContainerIPFS {
+					Expect(container.Resources.Requests).To(Equal(ipfs.Spec.IPFSResources.Requests))
+				}
+			}
+			Expect(sts.Spec.Template.Annotations).To(HaveKeyWithValue(
+				utils.AnnotationEvictionRole, string(utils.EvictionRoleDHTServer),
+			))
+		})
 	})
 
 	When("ipfsResources is omitted", func() {
@@ -223,7 +385,7 @@ var _ = Describe("StatefulSet creation", func() {
 		})
 		It("automatically computes resources requirements", func() {
 			var err error
-			sts, err = ipfsReconciler.StatefulSet(ctx, ipfs, svcName, secretName, scriptsName)
+			sts, err = ipfsReconciler.StatefulSet(ctx, ipfs, svcName, ipfsSecretName, clusterSecretName, scriptsName)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(sts).NotTo(BeNil())
 
@@ -251,6 +413,80 @@ var _ = Describe("StatefulSet creation", func() {
 			Expect(memory.Value()).NotTo(Equal(0))
 		})
 	})
+
+	When("followerMode is set", func() {
+		BeforeEach(func() {
+			ipfs.Spec.ClusterStorage = *resource.NewQuantity(1, "Gi")
+			ipfs.Spec.IpfsStorage = *resource.NewQuantity(1, "Gi")
+		})
+		It("rejects writes to the pinset on the cluster peers", func() {
+			followerEnv := v1.EnvVar{Name: scripts.EnvClusterFollowerMode, Value: "true"}
+			clusterEnv := func() []v1.EnvVar {
+				sts, err := ipfsReconciler.StatefulSet(ctx, ipfs, svcName, ipfsSecretName, clusterSecretName, scriptsName)
+				Expect(err).NotTo(HaveOccurred())
+				for _, container := range sts.Spec.Template.Spec.Containers {
+					if container.Name == controllers.ContainerIPFSCluster {
+						return container.Env
+					}
+				}
+				return nil
+			}
+			Expect(clusterEnv()).NotTo(ContainElement(followerEnv))
+
+			ipfs.Spec.FollowerMode = true
+			Expect(clusterEnv()).To(ContainElement(followerEnv))
+		})
+	})
+})
+
+var _ = Describe("StatefulSet secrets", func() {
+	var ipfsReconciler *controllers.IpfsClusterReconciler
+	var ipfs *v1alpha1.IpfsCluster
+	var ns *v1.Namespace
+	var ctx context.Context
+
+	const (
+		myName            = "my-fav-ipfs-node"
+		scriptsName       = "my-scripts"
+		ipfsSecretName    = "my-ipfs-secret"
+		clusterSecretName = "my-cluster-secret"
+		svcName           = "my-svc"
+		namespace         = "test"
+	)
+	BeforeEach(func() {
+		ctx = context.TODO()
+		ipfsReconciler = &controllers.IpfsClusterReconciler{
+			Scheme: k8sClient.Scheme(),
+			Client: k8sClient,
+		}
+		ns = &v1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				GenerateName: namespace,
+			},
+		}
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		ipfs = &v1alpha1.IpfsCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      myName,
+				Namespace: ns.Name,
+			},
+		}
+		ipfs.Spec.ClusterStorage = *resource.NewQuantity(1, "Gi")
+		ipfs.Spec.IpfsStorage = *resource.NewQuantity(1, "Gi")
+		Expect(k8sClient.Create(ctx, ipfs)).To(Succeed())
+	})
+	AfterEach(func() {
+		Expect(k8sClient.Delete(ctx, ns)).To(Succeed())
+	})
+
+	It("does not expose the cluster secret to the gateway container", func() {
+		sts, err := ipfsReconciler.StatefulSet(ctx, ipfs, svcName, ipfsSecretName, clusterSecretName, scriptsName)
+		Expect(err).NotTo(HaveOccurred())
+		podSpec := &sts.Spec.Template.Spec
+		Expect(utils.ContainerSecretNames(podSpec, controllers.ContainerIPFS)).NotTo(ContainElement(clusterSecretName))
+		Expect(utils.ContainerSecretNames(podSpec, controllers.ContainerInitIPFS)).NotTo(ContainElement(clusterSecretName))
+		Expect(utils.ContainerSecretNames(podSpec, controllers.ContainerIPFSCluster)).To(Equal([]string{clusterSecretName}))
+	})
 })
 
 // The k8s client will encode and copy data from the StringData to Data
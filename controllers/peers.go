@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+// peerstoreConfigMapName Returns the name of the ConfigMap holding the IPFS Cluster peerstore of the instance.
+func peerstoreConfigMapName(m *clusterv1alpha1.IpfsCluster) string {
+	return "ipfs-cluster-peerstore-" + m.Name
+}
+
+// reportPeers Records on the status of the instance the peers of the StatefulSet which answer on
+// their REST API, along with the peer IDs they announce, and returns their cluster swarm addresses.
+// Peers which don't answer yet are left out until a later reconcile.
+func (r *IpfsClusterReconciler) reportPeers(
+	ctx context.Context,
+	m *clusterv1alpha1.IpfsCluster,
+	sts *appsv1.StatefulSet,
+) ([]string, error) {
+	log := ctrllog.FromContext(ctx)
+	pods := &corev1.PodList{}
+	if err := r.List(
+		ctx, pods, client.InNamespace(sts.Namespace), client.MatchingLabels(sts.Spec.Selector.MatchLabels),
+	); err != nil {
+		return nil, fmt.Errorf("could not list statefulset pods: %w", err)
+	}
+	peers := make([]clusterv1alpha1.PeerStatus, 0, len(pods.Items))
+	addrs := make([]string, 0, len(pods.Items))
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" {
+			continue
+		}
+		api, err := r.clusterAPIClient(ctx, m, pod.Status.PodIP)
+		if err != nil {
+			return nil, err
+		}
+		info, err := api.PeerInfo(ctx)
+		if err != nil {
+			log.Info("could not reach peer", "pod", pod.Name, "reason", err.Error())
+			continue
+		}
+		peers = append(peers, clusterv1alpha1.PeerStatus{
+			Name:          pod.Name,
+			ClusterPeerID: info.ID,
+			PeerID:        info.IPFS.ID,
+		})
+		addrs = append(addrs, fmt.Sprintf("/ip4/%s/tcp/%d/p2p/%s", pod.Status.PodIP, portClusterSwarm, info.ID))
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i].Name < peers[j].Name })
+	m.Status.Peers = peers
+	if len(peers) == 0 {
+		m.Status.Peers = nil
+	}
+	return addrs, nil
+}
+
+// refreshPeerstore Writes the addresses of the peers reached on this reconcile into the peerstore
+// ConfigMap, which the cluster containers copy into their state on startup.
+func (r *IpfsClusterReconciler) refreshPeerstore(
+	ctx context.Context,
+	m *clusterv1alpha1.IpfsCluster,
+	sts *appsv1.StatefulSet,
+) error {
+	current, err := r.reportPeers(ctx, m, sts)
+	if err != nil {
+		return err
+	}
+	_, err = r.EnsureClusterPeerstore(ctx, m, current, utils.DefaultPeerstoreTTL)
+	return err
+}
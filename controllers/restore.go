@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+// CheckStateRestoreTarget Records on the status of the instance whether the state of the given peer
+// holds no pins, given the number of pins it holds, so that a backed up pinset can be restored into
+// it. The restore must not be started when an error is returned.
+func (r *IpfsClusterReconciler) CheckStateRestoreTarget(
+	ctx context.Context,
+	m *clusterv1alpha1.IpfsCluster,
+	peer string,
+	pins int,
+) error {
+	condition := metav1.Condition{
+		Type:    clusterv1alpha1.ConditionStateRestoreAllowed,
+		Status:  metav1.ConditionTrue,
+		Reason:  clusterv1alpha1.StateRestoreReasonTargetEmpty,
+		Message: fmt.Sprintf("restore target %s holds no pins", peer),
+	}
+	validationErr := utils.ValidateRestoreTarget(peer, pins)
+	if validationErr != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = clusterv1alpha1.StateRestoreReasonTargetNotEmpty
+		condition.Message = validationErr.Error()
+	}
+	meta.SetStatusCondition(&m.Status.Conditions, condition)
+	return validationErr
+}
+
+// EnsureStateRestore Starts the restore of the backed up pinset requested by the instance, once
+// CheckStateRestoreTarget found that the pinset of the cluster, as served by the REST API behind
+// the given Service, is empty. The restore Job is only created once: from then on the cluster
+// holds the restored pins, and the Job is left to finish rather than being checked again.
+func (r *IpfsClusterReconciler) EnsureStateRestore(
+	ctx context.Context,
+	m *clusterv1alpha1.IpfsCluster,
+	svcName string,
+) error {
+	log := ctrllog.FromContext(ctx)
+	if m.Spec.Restore == nil {
+		meta.RemoveStatusCondition(&m.Status.Conditions, clusterv1alpha1.ConditionStateRestoreAllowed)
+		return nil
+	}
+	job := &batchv1.Job{}
+	key := client.ObjectKey{Name: "ipfs-cluster-restore-" + m.Name, Namespace: m.Namespace}
+	err := r.Get(ctx, key, job)
+	if err == nil {
+		return nil
+	}
+	if !errors.IsNotFound(err) {
+		return fmt.Errorf("could not get restore job: %w", err)
+	}
+	host := clusterServiceHost(m, svcName)
+	api, err := r.clusterAPIClient(ctx, m, host)
+	if err != nil {
+		return err
+	}
+	export, err := api.Pinset(ctx)
+	if err != nil {
+		// the peers are usually still starting, the restore is checked again once they changed
+		log.Info("could not list the pinset of the restore target", "reason", err.Error())
+		return nil
+	}
+	if err = r.CheckStateRestoreTarget(ctx, m, host, utils.CountExportedPins(export)); err != nil {
+		log.Info("not restoring the pinset", "reason", err.Error())
+		return nil
+	}
+	job = utils.RestoreJob(
+		key.Name, key.Namespace, ipfsClusterImage, clusterAPIMultiaddr(m, svcName),
+		m.Spec.Restore.VolumeClaimName, m.Spec.Restore.Path,
+	)
+	if err = ctrl.SetControllerReference(m, job, r.Scheme); err != nil {
+		return fmt.Errorf("could not own restore job: %w", err)
+	}
+	if err = r.Create(ctx, job); err != nil {
+		return fmt.Errorf("could not create restore job: %w", err)
+	}
+	log.Info("restoring the pinset", "job", key, "backup", m.Spec.Restore.Path)
+	return nil
+}
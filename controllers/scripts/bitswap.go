@@ -0,0 +1,63 @@
+package scripts
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/ipfs/kubo/config"
+)
+
+// Bitswap defaults of Kubo, which are sized for a single core and little memory.
+const (
+	defaultBitswapTaskWorkerCount             = 8
+	defaultBitswapEngineBlockstoreWorkerCount = 128
+	defaultBitswapEngineTaskWorkerCount       = 8
+	defaultBitswapMaxOutstandingBytesPerPeer  = 1 << 20
+)
+
+const (
+	// maxBitswapCores Bounds how many cores the worker counts scale with, past which the
+	// blockstore rather than the CPU limits how fast blocks are served.
+	maxBitswapCores = 16
+	// maxBitswapOutstandingBytesPerPeer Bounds how much a single peer may have queued.
+	maxBitswapOutstandingBytesPerPeer = 16 << 20
+)
+
+// ApplyBitswapTuning Sets Internal.Bitswap on the given Kubo configuration for a node with the
+// given CPU, in millicores, and memory limits, scaling Kubo's single-core defaults so that busy
+// gateways are not throttled by them. The worker counts grow with each core, up to 16 cores, and
+// each peer may have 1MiB of blocks outstanding per GiB of memory, up to 16MiB. Nodes smaller
+// than a core or a GiB keep the defaults.
+func ApplyBitswapTuning(conf *config.Config, milliCPU int64, memoryBytes int64) error {
+	if milliCPU < 0 || memoryBytes < 0 {
+		return fmt.Errorf("resources cannot be negative, got %dm of cpu and %d bytes of memory", milliCPU, memoryBytes)
+	}
+	cores := milliCPU / 1000
+	if cores < 1 {
+		cores = 1
+	}
+	if cores > maxBitswapCores {
+		cores = maxBitswapCores
+	}
+	outstanding := int64(defaultBitswapMaxOutstandingBytesPerPeer) * (memoryBytes >> 30)
+	if outstanding < defaultBitswapMaxOutstandingBytesPerPeer {
+		outstanding = defaultBitswapMaxOutstandingBytesPerPeer
+	}
+	if outstanding > maxBitswapOutstandingBytesPerPeer {
+		outstanding = maxBitswapOutstandingBytesPerPeer
+	}
+	bitswap := &config.InternalBitswap{}
+	for field, value := range map[*config.OptionalInteger]int64{
+		&bitswap.TaskWorkerCount:             defaultBitswapTaskWorkerCount * cores,
+		&bitswap.EngineBlockstoreWorkerCount: defaultBitswapEngineBlockstoreWorkerCount * cores,
+		&bitswap.EngineTaskWorkerCount:       defaultBitswapEngineTaskWorkerCount * cores,
+		&bitswap.MaxOutstandingBytesPerPeer:  outstanding,
+	} {
+		// Kubo has no constructor for optional integers, they are only ever decoded
+		if err := field.UnmarshalJSON([]byte(strconv.FormatInt(value, 10))); err != nil {
+			return fmt.Errorf("could not set bitswap tuning: %w", err)
+		}
+	}
+	conf.Internal.Bitswap = bitswap
+	return nil
+}
@@ -0,0 +1,44 @@
+package scripts_test
+
+import (
+	"github.com/ipfs/kubo/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/ipfs-operator/controllers/scripts"
+)
+
+var _ = Describe("Bitswap tuning", func() {
+	tune := func(milliCPU, memoryBytes int64) *config.InternalBitswap {
+		conf := &config.Config{}
+		Expect(scripts.ApplyBitswapTuning(conf, milliCPU, memoryBytes)).To(Succeed())
+		Expect(conf.Internal.Bitswap).NotTo(BeNil())
+		return conf.Internal.Bitswap
+	}
+
+	It("keeps the defaults on small nodes", func() {
+		bitswap := tune(250, 512<<20)
+		Expect(bitswap.TaskWorkerCount.WithDefault(0)).To(BeEquivalentTo(8))
+		Expect(bitswap.EngineBlockstoreWorkerCount.WithDefault(0)).To(BeEquivalentTo(128))
+		Expect(bitswap.EngineTaskWorkerCount.WithDefault(0)).To(BeEquivalentTo(8))
+		Expect(bitswap.MaxOutstandingBytesPerPeer.WithDefault(0)).To(BeEquivalentTo(1 << 20))
+	})
+
+	It("scales with the node's CPU and memory", func() {
+		bitswap := tune(4000, 8<<30)
+		Expect(bitswap.TaskWorkerCount.WithDefault(0)).To(BeEquivalentTo(32))
+		Expect(bitswap.EngineBlockstoreWorkerCount.WithDefault(0)).To(BeEquivalentTo(512))
+		Expect(bitswap.EngineTaskWorkerCount.WithDefault(0)).To(BeEquivalentTo(32))
+		Expect(bitswap.MaxOutstandingBytesPerPeer.WithDefault(0)).To(BeEquivalentTo(8 << 20))
+	})
+
+	It("bounds the tuning on large nodes", func() {
+		bitswap := tune(64000, 256<<30)
+		Expect(bitswap.TaskWorkerCount.WithDefault(0)).To(BeEquivalentTo(128))
+		Expect(bitswap.MaxOutstandingBytesPerPeer.WithDefault(0)).To(BeEquivalentTo(16 << 20))
+	})
+
+	It("rejects negative resources", func() {
+		Expect(scripts.ApplyBitswapTuning(&config.Config{}, -1, 0)).NotTo(Succeed())
+	})
+})
@@ -0,0 +1,785 @@
+package scripts
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ErrUnsupportedClusterOption Is returned when an option is requested which the bundled
+// IPFS Cluster version does not provide.
+var ErrUnsupportedClusterOption = errors.New("option is not supported by the bundled ipfs-cluster version")
+
+// Environment variables used to override the IPFS Cluster service.json configuration.
+const (
+	EnvClusterAllocateBy    = "CLUSTER_BALANCED_ALLOCATEBY"
+	EnvClusterTags          = "CLUSTER_TAGS_TAGS"
+	EnvClusterTagsMetricTTL = "CLUSTER_TAGS_METRICTTL"
+
+	EnvClusterRESTAPICORSAllowedOrigins   = "CLUSTER_RESTAPI_CORSALLOWEDORIGINS"
+	EnvClusterRESTAPICORSAllowCredentials = "CLUSTER_RESTAPI_CORSALLOWCREDENTIALS"
+	EnvClusterRESTAPICORSExposedHeaders   = "CLUSTER_RESTAPI_CORSEXPOSEDHEADERS"
+	EnvClusterRESTAPIBasicAuthCredentials = "CLUSTER_RESTAPI_BASICAUTHCREDENTIALS"
+	EnvClusterRESTAPISSLCertFile          = "CLUSTER_RESTAPI_SSLCERTFILE"
+	EnvClusterRESTAPISSLKeyFile           = "CLUSTER_RESTAPI_SSLKEYFILE"
+	EnvClusterRESTAPIMaxHeaderBytes       = "CLUSTER_RESTAPI_MAXHEADERBYTES"
+	EnvClusterRESTAPIReadHeaderTimeout    = "CLUSTER_RESTAPI_READHEADERTIMEOUT"
+	EnvClusterRESTAPIReadTimeout          = "CLUSTER_RESTAPI_READTIMEOUT"
+
+	EnvClusterPinSvcAPIHTTPListenMultiaddress = "CLUSTER_PINSVCAPI_HTTPLISTENMULTIADDRESS"
+	EnvClusterPinSvcAPIBasicAuthCredentials   = "CLUSTER_PINSVCAPI_BASICAUTHCREDENTIALS"
+
+	EnvClusterCRDTRebroadcastInterval    = "CLUSTER_CRDT_REBROADCASTINTERVAL"
+	EnvClusterCRDTClusterName            = "CLUSTER_CRDT_CLUSTERNAME"
+	EnvClusterCRDTTrustedPeers           = "CLUSTER_CRDT_TRUSTEDPEERS"
+	EnvClusterRaftHeartbeatTimeout       = "CLUSTER_RAFT_HEARTBEATTIMEOUT"
+	EnvClusterRaftElectionTimeout        = "CLUSTER_RAFT_ELECTIONTIMEOUT"
+	EnvClusterRaftNetworkTimeout         = "CLUSTER_RAFT_NETWORKTIMEOUT"
+	EnvClusterPinRecoverInterval         = "CLUSTER_PINRECOVERINTERVAL"
+	EnvClusterDisableRepinning           = "CLUSTER_DISABLEREPINNING"
+	EnvClusterIPFSHTTPUnpinDisable       = "CLUSTER_IPFSHTTP_UNPINDISABLE"
+	EnvClusterIPFSHTTPConnectSwarmsDelay = "CLUSTER_IPFSHTTP_CONNECTSWARMSDELAY"
+	EnvClusterFollowerMode               = "CLUSTER_FOLLOWERMODE"
+
+	EnvClusterPubsubMonCheckInterval = "CLUSTER_PUBSUBMON_CHECKINTERVAL"
+
+	EnvClusterBadgerGCInterval     = "CLUSTER_BADGER_GCINTERVAL"
+	EnvClusterBadgerGCDiscardRatio = "CLUSTER_BADGER_GCDISCARDRATIO"
+	EnvClusterBadgerGCSleep        = "CLUSTER_BADGER_GCSLEEP"
+
+	// EnvGoLogFormat and EnvGoLogOutput Are read by the logging library of IPFS Cluster, and
+	// apply to every log line of the peer rather than to the REST API alone.
+	EnvGoLogFormat = "GOLOG_LOG_FMT"
+	EnvGoLogOutput = "GOLOG_OUTPUT"
+
+	// EnvClusterLogLevel Is read by the entrypoint script rather than by IPFS Cluster, since
+	// log levels are only set through the --loglevel flag of ipfs-cluster-service.
+	EnvClusterLogLevel = "CLUSTER_LOGLEVEL"
+)
+
+const (
+	// ClusterAllocatorBalanced Groups peers by their "group" tag before sorting them
+	// by the allocation metrics, spreading pins across groups.
+	ClusterAllocatorBalanced = "balanced"
+	// ClusterAllocatorMetrics Sorts peers purely by the allocation metrics.
+	ClusterAllocatorMetrics = "metrics"
+
+	// ClusterMetricFreeSpace Prefers the peers with the most free space.
+	ClusterMetricFreeSpace = "freespace"
+	// ClusterMetricTagGroup Groups the peers by their "group" tag.
+	ClusterMetricTagGroup = "tag:group"
+)
+
+// DefaultPinSvcAPIPort Is the port IPFS Cluster serves the Pinning Service API on.
+const DefaultPinSvcAPIPort = 9097
+
+const (
+	// ClusterMonitorPubsub Broadcasts the peer metrics over pubsub, which keeps the chatter
+	// of large clusters down.
+	ClusterMonitorPubsub = "pubsub"
+	// ClusterMonitorMetrics Has each peer push its metrics to the others directly.
+	ClusterMonitorMetrics = "metrics"
+)
+
+// REST API request limits the operator uses when they aren't configured. They are well above the
+// IPFS Cluster defaults of 4KiB and 5s, which requests pinning many CIDs at once easily exceed.
+const (
+	DefaultRESTAPIMaxHeaderBytes    = 1 << 20
+	DefaultRESTAPIReadHeaderTimeout = 30 * time.Second
+)
+
+// DefaultConnectSwarmsDelay Is how long a starting IPFS Cluster peer waits before connecting the
+// swarm of its IPFS node to those of the other peers, leaving the IPFS daemon time to start.
+const DefaultConnectSwarmsDelay = 30 * time.Second
+
+// Raft timeouts IPFS Cluster uses when they aren't configured.
+const (
+	DefaultRaftHeartbeatTimeout = time.Second
+	DefaultRaftElectionTimeout  = time.Second
+)
+
+const (
+	// ClusterConsensusCRDT Replicates the pinset through a CRDT over pubsub.
+	ClusterConsensusCRDT = "crdt"
+	// ClusterConsensusRaft Replicates the pinset through a Raft log.
+	ClusterConsensusRaft = "raft"
+)
+
+// DefaultClusterLogLevel Is the log level of IPFS Cluster components without their own level.
+const DefaultClusterLogLevel = "info"
+
+// clusterLogLevels Lists the log levels IPFS Cluster accepts.
+var clusterLogLevels = []string{"debug", "info", "warn", "error", "dpanic", "panic", "fatal"}
+
+// clusterLogComponents Lists the logging facilities of IPFS Cluster which a level can be set for.
+var clusterLogComponents = []string{
+	"adder", "allocator", "apitypes", "ascendalloc", "balanced", "cluster", "config", "consensus",
+	"crdt", "descendalloc", "diskinfo", "dsstate", "ipfshttp", "ipfsproxy", "monitor", "numpin",
+	"observations", "optracker", "pinsvcapi", "pintracker", "pstoremgr", "raft", "restapi",
+	"restapilib", "restapilog", "service", "shutdown", "tags",
+}
+
+// ClusterAllocatorEnvs Returns the environment variables configuring the IPFS Cluster
+// allocator with the given type and metrics. Metrics are applied in the order given,
+// and peers are sorted by free space when no metrics are provided.
+func ClusterAllocatorEnvs(allocator string, metrics []string) ([]corev1.EnvVar, error) {
+	if len(metrics) == 0 {
+		metrics = []string{ClusterMetricFreeSpace}
+	}
+	allocateBy := make([]string, 0, len(metrics)+1)
+	switch allocator {
+	case "", ClusterAllocatorBalanced:
+		allocateBy = append(allocateBy, ClusterMetricTagGroup)
+	case ClusterAllocatorMetrics:
+	default:
+		return nil, fmt.Errorf("invalid cluster allocator: %s", allocator)
+	}
+
+	seen := make(map[string]bool, len(allocateBy)+len(metrics))
+	for _, metric := range allocateBy {
+		seen[metric] = true
+	}
+	for _, metric := range metrics {
+		if metric == "" {
+			return nil, fmt.Errorf("allocator metrics cannot be empty")
+		}
+		if seen[metric] {
+			continue
+		}
+		seen[metric] = true
+		allocateBy = append(allocateBy, metric)
+	}
+	return []corev1.EnvVar{
+		{
+			Name:  EnvClusterAllocateBy,
+			Value: strings.Join(allocateBy, ","),
+		},
+	}, nil
+}
+
+// ClusterPinQueueInformer Configures the pinqueue informer, which publishes the length of the pin
+// queue of each peer so that new pins are allocated away from overloaded peers.
+type ClusterPinQueueInformer struct {
+	// MetricTTL Is how often the queue length is published. Zero keeps the default.
+	MetricTTL time.Duration
+	// WeightBucketSize Is the queue length granularity peers are compared at: peers whose queues
+	// fall into the same bucket are considered equally loaded and sorted by the next metric.
+	// Zero keeps the default.
+	WeightBucketSize int
+}
+
+// ClusterPinQueueAllocationEnvs Returns the environment variables allocating pins by pin queue
+// length ahead of free space, as published by the given pinqueue informer. A nil informer leaves
+// the allocation to ClusterAllocatorEnvs alone. IPFS Cluster 1.0.4, which the operator runs, has
+// no pinqueue informer, so requesting one returns ErrUnsupportedClusterOption.
+func ClusterPinQueueAllocationEnvs(allocator string, informer *ClusterPinQueueInformer) ([]corev1.EnvVar, error) {
+	if informer != nil {
+		return nil, fmt.Errorf("pinqueue informer: %w", ErrUnsupportedClusterOption)
+	}
+	return ClusterAllocatorEnvs(allocator, nil)
+}
+
+// ClusterTagMetric Returns the allocator metric which groups peers by the given tag,
+// so that pins are spread across, or constrained to, the peers sharing its values.
+func ClusterTagMetric(tag string) string {
+	return "tag:" + tag
+}
+
+// ClusterTagsInformerEnvs Returns the environment variables configuring the tags
+// informer of an IPFS Cluster peer with the given tags. The informer publishes the
+// tags as metrics to the other peers every metricTTL; a zero metricTTL keeps the default.
+func ClusterTagsInformerEnvs(tags map[string]string, metricTTL time.Duration) ([]corev1.EnvVar, error) {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		v := tags[k]
+		if k == "" {
+			return nil, fmt.Errorf("cluster tag names cannot be empty")
+		}
+		if strings.ContainsAny(k, ":,") || strings.ContainsAny(v, ":,") {
+			return nil, fmt.Errorf("cluster tag %q cannot contain ':' or ','", k)
+		}
+		pairs = append(pairs, k+":"+v)
+	}
+	envs := []corev1.EnvVar{
+		{
+			Name:  EnvClusterTags,
+			Value: strings.Join(pairs, ","),
+		},
+	}
+	if metricTTL < 0 {
+		return nil, fmt.Errorf("tags metric ttl cannot be negative: %s", metricTTL)
+	}
+	if metricTTL > 0 {
+		envs = append(envs, corev1.EnvVar{
+			Name:  EnvClusterTagsMetricTTL,
+			Value: metricTTL.String(),
+		})
+	}
+	return envs, nil
+}
+
+// clusterRESTAPIProxyExposedHeaders Lists the response headers a browser behind the
+// reverse proxy needs to read from the REST API.
+var clusterRESTAPIProxyExposedHeaders = []string{
+	"Content-Type",
+	"X-Stream-Output",
+	"X-Chunked-Output",
+	"X-Content-Length",
+}
+
+// ClusterRESTAPIProxyEnvs Returns the environment variables configuring CORS on the IPFS
+// Cluster REST API when it is served behind a reverse proxy on the given origins.
+// Nothing is rendered unless enabled, keeping the REST API's defaults. IPFS Cluster does
+// not interpret X-Forwarded-* headers, so the proxy is trusted solely by its origin.
+// Credentials cannot be allowed for the wildcard origin.
+func ClusterRESTAPIProxyEnvs(enabled bool, allowedOrigins []string, allowCredentials bool) ([]corev1.EnvVar, error) {
+	if !enabled {
+		return nil, nil
+	}
+	if len(allowedOrigins) == 0 {
+		return nil, fmt.Errorf("at least one proxy origin is required")
+	}
+	for _, origin := range allowedOrigins {
+		if origin == "*" {
+			if allowCredentials {
+				return nil, fmt.Errorf("credentials cannot be allowed for the wildcard origin")
+			}
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy origin %q: %w", origin, err)
+		}
+		if u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			return nil, fmt.Errorf("proxy origin %q must be a scheme and host", origin)
+		}
+	}
+	return []corev1.EnvVar{
+		{
+			Name:  EnvClusterRESTAPICORSAllowedOrigins,
+			Value: strings.Join(allowedOrigins, ","),
+		},
+		{
+			Name:  EnvClusterRESTAPICORSAllowCredentials,
+			Value: fmt.Sprint(allowCredentials),
+		},
+		{
+			Name:  EnvClusterRESTAPICORSExposedHeaders,
+			Value: strings.Join(clusterRESTAPIProxyExposedHeaders, ","),
+		},
+	}, nil
+}
+
+// ClusterCRDTRebroadcastEnvs Returns the environment variables setting how often CRDT peers
+// rebroadcast the heads of their pinset, allowing peers on lossy networks to converge faster.
+// The interval only applies to the CRDT consensus, so setting it for Raft is an error.
+// An empty interval keeps the default.
+func ClusterCRDTRebroadcastEnvs(consensus, interval string) ([]corev1.EnvVar, error) {
+	if interval == "" {
+		return nil, nil
+	}
+	if consensus != "" && consensus != ClusterConsensusCRDT {
+		return nil, fmt.Errorf("rebroadcast interval requires the %s consensus, got %s", ClusterConsensusCRDT, consensus)
+	}
+	d, err := time.ParseDuration(interval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rebroadcast interval: %w", err)
+	}
+	if d <= 0 {
+		return nil, fmt.Errorf("rebroadcast interval must be positive, got %s", interval)
+	}
+	return []corev1.EnvVar{
+		{
+			Name:  EnvClusterCRDTRebroadcastInterval,
+			Value: d.String(),
+		},
+	}, nil
+}
+
+// ClusterCRDTClusterNameEnvs Returns the environment variables setting the cluster_name of the
+// CRDT consensus, which names the pubsub topic peers broadcast their pinset updates on. Clusters
+// sharing a name join the same topic, so each cluster must be given its own. The name only applies
+// to the CRDT consensus, so setting it for Raft is an error.
+func ClusterCRDTClusterNameEnvs(consensus, clusterName string) ([]corev1.EnvVar, error) {
+	if consensus != "" && consensus != ClusterConsensusCRDT {
+		return nil, fmt.Errorf("cluster name requires the %s consensus, got %s", ClusterConsensusCRDT, consensus)
+	}
+	if clusterName == "" {
+		return nil, fmt.Errorf("crdt cluster name cannot be empty")
+	}
+	return []corev1.EnvVar{
+		{
+			Name:  EnvClusterCRDTClusterName,
+			Value: clusterName,
+		},
+	}, nil
+}
+
+// ClusterBadgerGC Is the value log garbage collection of the badger store holding the IPFS Cluster
+// CRDT consensus state. It is unrelated to the garbage collection of the IPFS datastore, which
+// ApplyDatastoreGC configures. Zero values keep the IPFS Cluster defaults.
+type ClusterBadgerGC struct {
+	// Interval Is how often the value log is garbage collected.
+	Interval time.Duration
+	// DiscardRatio Is the fraction of a value log file which must be stale for it to be rewritten.
+	DiscardRatio float64
+	// Sleep Is how long to wait between rewriting consecutive value log files.
+	Sleep time.Duration
+}
+
+// ClusterBadgerGCEnvs Returns the environment variables setting the gc_interval, gc_discard_ratio
+// and gc_sleep of the IPFS Cluster badger datastore.
+func ClusterBadgerGCEnvs(gc ClusterBadgerGC) ([]corev1.EnvVar, error) {
+	if gc.Interval < 0 {
+		return nil, fmt.Errorf("badger gc interval cannot be negative, got %s", gc.Interval)
+	}
+	if gc.Sleep < 0 {
+		return nil, fmt.Errorf("badger gc sleep cannot be negative, got %s", gc.Sleep)
+	}
+	if gc.DiscardRatio < 0 || gc.DiscardRatio >= 1 {
+		return nil, fmt.Errorf("badger gc discard ratio must be between 0 and 1, got %v", gc.DiscardRatio)
+	}
+	var envs []corev1.EnvVar
+	if gc.Interval > 0 {
+		envs = append(envs, corev1.EnvVar{Name: EnvClusterBadgerGCInterval, Value: gc.Interval.String()})
+	}
+	if gc.DiscardRatio > 0 {
+		envs = append(envs, corev1.EnvVar{
+			Name:  EnvClusterBadgerGCDiscardRatio,
+			Value: strconv.FormatFloat(gc.DiscardRatio, 'f', -1, 64),
+		})
+	}
+	if gc.Sleep > 0 {
+		envs = append(envs, corev1.EnvVar{Name: EnvClusterBadgerGCSleep, Value: gc.Sleep.String()})
+	}
+	return envs, nil
+}
+
+// PruneTrustedPeers Returns the crdt trusted_peers following a change of the cluster peers, given
+// the trusted peers rendered so far, the peer IDs currently in the cluster, and the peers trusted
+// from outside of it, e.g. follower peers or an external pinning service. Trusted peers which have
+// left the cluster are pruned, so that their keys stop being trusted, while the external peers are
+// always kept. Peers of the cluster which were not trusted so far are not added.
+// The result is sorted and free of duplicates.
+func PruneTrustedPeers(trusted, current, external []string) []string {
+	inCluster := make(map[string]bool, len(current))
+	for _, p := range current {
+		inCluster[p] = true
+	}
+	seen := make(map[string]bool, len(trusted)+len(external))
+	pruned := make([]string, 0, len(trusted)+len(external))
+	keep := func(p string) {
+		if !seen[p] {
+			seen[p] = true
+			pruned = append(pruned, p)
+		}
+	}
+	for _, p := range trusted {
+		if inCluster[p] {
+			keep(p)
+		}
+	}
+	for _, p := range external {
+		keep(p)
+	}
+	sort.Strings(pruned)
+	return pruned
+}
+
+// ClusterCRDTTrustedPeersEnvs Returns the environment variables setting the crdt trusted_peers of
+// IPFS Cluster, which are the only peers allowed to modify the pinset, to the trusted peers pruned
+// by PruneTrustedPeers.
+func ClusterCRDTTrustedPeersEnvs(trusted, current, external []string) ([]corev1.EnvVar, error) {
+	pruned := PruneTrustedPeers(trusted, current, external)
+	if len(pruned) == 0 {
+		return nil, fmt.Errorf("at least one trusted peer is required")
+	}
+	for _, p := range pruned {
+		if _, err := peer.Decode(p); err != nil {
+			return nil, fmt.Errorf("invalid trusted peer %q: %w", p, err)
+		}
+	}
+	return []corev1.EnvVar{
+		{
+			Name:  EnvClusterCRDTTrustedPeers,
+			Value: strings.Join(pruned, ","),
+		},
+	}, nil
+}
+
+// ClusterPinRecoverIntervalEnvs Returns the environment variables setting how often each
+// IPFS Cluster peer retries pins which ended up in an error state. An empty interval keeps the default.
+func ClusterPinRecoverIntervalEnvs(interval string) ([]corev1.EnvVar, error) {
+	if interval == "" {
+		return nil, nil
+	}
+	d, err := time.ParseDuration(interval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pin recover interval: %w", err)
+	}
+	if d <= 0 {
+		return nil, fmt.Errorf("pin recover interval must be positive, got %s", interval)
+	}
+	return []corev1.EnvVar{
+		{
+			Name:  EnvClusterPinRecoverInterval,
+			Value: d.String(),
+		},
+	}, nil
+}
+
+// ClusterDisableRepinningEnvs Returns the environment variables setting the disable_repinning
+// flag of IPFS Cluster. By default, pins are re-allocated to other peers as soon as a peer
+// holding them misses its metrics, which on transient failures such as node restarts moves
+// whole pinsets across the cluster only to move them back. Disabling it avoids the storage
+// thrash, at the cost of pins staying under-replicated until an operator re-pins them.
+func ClusterDisableRepinningEnvs(disable bool) []corev1.EnvVar {
+	return []corev1.EnvVar{
+		{
+			Name:  EnvClusterDisableRepinning,
+			Value: strconv.FormatBool(disable),
+		},
+	}
+}
+
+// ClusterRESTAPIBasicAuthEnvs Returns the environment variables making IPFS Cluster read the
+// REST API basic_auth_credentials from the referenced Secret key, which holds comma-separated
+// `user:password` pairs. The credentials are resolved by the kubelet, so neither the ConfigMap
+// nor the pod spec ever contain them. A nil reference leaves the REST API without authentication.
+func ClusterRESTAPIBasicAuthEnvs(ref *corev1.SecretKeySelector) ([]corev1.EnvVar, error) {
+	if ref == nil {
+		return nil, nil
+	}
+	if ref.Name == "" || ref.Key == "" {
+		return nil, fmt.Errorf("basic auth secret reference needs both a name and a key")
+	}
+	return []corev1.EnvVar{
+		{
+			Name: EnvClusterRESTAPIBasicAuthCredentials,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: ref.DeepCopy(),
+			},
+		},
+	}, nil
+}
+
+// ClusterRESTAPITLSEnvs Returns the environment variables making the IPFS Cluster REST API serve
+// HTTPS with the certificate and key of a kubernetes.io/tls Secret mounted at the given directory.
+func ClusterRESTAPITLSEnvs(mountPath string) []corev1.EnvVar {
+	return []corev1.EnvVar{
+		{
+			Name:  EnvClusterRESTAPISSLCertFile,
+			Value: path.Join(mountPath, corev1.TLSCertKey),
+		},
+		{
+			Name:  EnvClusterRESTAPISSLKeyFile,
+			Value: path.Join(mountPath, corev1.TLSPrivateKeyKey),
+		},
+	}
+}
+
+// ClusterRESTAPILimits Are the limits the IPFS Cluster REST API puts on incoming requests.
+type ClusterRESTAPILimits struct {
+	// MaxHeaderBytes Is the largest size of the request headers, beyond which requests fail with a 431.
+	MaxHeaderBytes int
+	// ReadHeaderTimeout Is how long reading the request headers may take.
+	ReadHeaderTimeout time.Duration
+	// ReadTimeout Is how long reading a whole request may take. Zero never times out.
+	ReadTimeout time.Duration
+	// MaxBodyBytes Is the largest size of a request body. IPFS Cluster doesn't limit request bodies,
+	// so any 413 is returned by a proxy in front of it; setting it returns ErrUnsupportedClusterOption.
+	MaxBodyBytes int64
+}
+
+// ClusterRESTAPILimitsEnvs Returns the environment variables setting the max_header_bytes,
+// read_header_timeout and read_timeout of the IPFS Cluster REST API. Unset header limits use
+// DefaultRESTAPIMaxHeaderBytes and DefaultRESTAPIReadHeaderTimeout.
+func ClusterRESTAPILimitsEnvs(limits ClusterRESTAPILimits) ([]corev1.EnvVar, error) {
+	if limits.MaxBodyBytes != 0 {
+		return nil, fmt.Errorf("rest api max body bytes: %w", ErrUnsupportedClusterOption)
+	}
+	if limits.MaxHeaderBytes < 0 {
+		return nil, fmt.Errorf("rest api max header bytes cannot be negative, got %d", limits.MaxHeaderBytes)
+	}
+	if limits.ReadHeaderTimeout < 0 || limits.ReadTimeout < 0 {
+		return nil, fmt.Errorf("rest api read timeouts cannot be negative")
+	}
+	if limits.MaxHeaderBytes == 0 {
+		limits.MaxHeaderBytes = DefaultRESTAPIMaxHeaderBytes
+	}
+	if limits.ReadHeaderTimeout == 0 {
+		limits.ReadHeaderTimeout = DefaultRESTAPIReadHeaderTimeout
+	}
+	envs := []corev1.EnvVar{
+		{
+			Name:  EnvClusterRESTAPIMaxHeaderBytes,
+			Value: strconv.Itoa(limits.MaxHeaderBytes),
+		},
+		{
+			Name:  EnvClusterRESTAPIReadHeaderTimeout,
+			Value: limits.ReadHeaderTimeout.String(),
+		},
+	}
+	if limits.ReadTimeout > 0 {
+		envs = append(envs, corev1.EnvVar{
+			Name:  EnvClusterRESTAPIReadTimeout,
+			Value: limits.ReadTimeout.String(),
+		})
+	}
+	return envs, nil
+}
+
+// ClusterRESTAPIAccessLogEnvs Returns the environment variables making IPFS Cluster log to stdout
+// as JSON, so that the access log of its REST API can be collected from the container logs to
+// audit pin operations. With no http_log_file configured, the REST API logs each request through
+// the `restapilog` facility at the info level, as an Apache combined log format string, which ends
+// up as the message field of a JSON log line rather than as structured fields. GOLOG_LOG_FMT and
+// GOLOG_OUTPUT are read by the logging library of the whole process, so every log line of the peer
+// is written as JSON, not only the access log. Nothing is rendered unless enabled.
+func ClusterRESTAPIAccessLogEnvs(enabled bool) []corev1.EnvVar {
+	if !enabled {
+		return nil
+	}
+	return []corev1.EnvVar{
+		{
+			Name:  EnvGoLogFormat,
+			Value: "json",
+		},
+		{
+			Name:  EnvGoLogOutput,
+			Value: "stdout",
+		},
+	}
+}
+
+// ClusterUnpinDisableEnvs Returns the environment variables setting the unpin_disable flag of
+// the IPFS Cluster connector, which makes each peer refuse to unpin anything from its IPFS node.
+// Content unpinned from the cluster then stays pinned on the nodes, so it is never lost through
+// a cluster action, but IPFS garbage collection won't reclaim its space either; the nodes only
+// grow until the content is unpinned from them by hand.
+func ClusterUnpinDisableEnvs(disable bool) []corev1.EnvVar {
+	return []corev1.EnvVar{
+		{
+			Name:  EnvClusterIPFSHTTPUnpinDisable,
+			Value: strconv.FormatBool(disable),
+		},
+	}
+}
+
+// ClusterConnectSwarmsDelayEnvs Returns the environment variables setting the connect_swarms_delay
+// of the IPFS Cluster connector, after which a starting peer connects its IPFS node to the nodes of
+// the other peers. Connecting before the IPFS daemon is ready fails, so an empty delay uses
+// DefaultConnectSwarmsDelay.
+func ClusterConnectSwarmsDelayEnvs(delay string) ([]corev1.EnvVar, error) {
+	d := DefaultConnectSwarmsDelay
+	if delay != "" {
+		var err error
+		if d, err = time.ParseDuration(delay); err != nil {
+			return nil, fmt.Errorf("invalid connect swarms delay: %w", err)
+		}
+		if d < 0 {
+			return nil, fmt.Errorf("connect swarms delay cannot be negative, got %s", delay)
+		}
+	}
+	return []corev1.EnvVar{
+		{
+			Name:  EnvClusterIPFSHTTPConnectSwarmsDelay,
+			Value: d.String(),
+		},
+	}, nil
+}
+
+// ClusterFollowerModeEnvs Returns the environment variables setting the follower_mode flag of
+// IPFS Cluster. Peers of a follower cluster replicate the pinset of an upstream cluster, so in
+// follower mode they reject the requests adding, removing or recovering pins on the REST API, and
+// on the proxy and Pinning Service APIs, rather than letting local pins diverge from upstream.
+// Reads keep working.
+func ClusterFollowerModeEnvs(follower bool) []corev1.EnvVar {
+	return []corev1.EnvVar{
+		{
+			Name:  EnvClusterFollowerMode,
+			Value: strconv.FormatBool(follower),
+		},
+	}
+}
+
+// ClusterMonitorEnvs Returns the environment variables configuring the IPFS Cluster peer monitor
+// with the given backend, checking peer metrics at the given interval. An empty backend uses the
+// pubsub monitor and an empty interval keeps its default. The metrics-based monitor was removed
+// from IPFS Cluster in favour of pubsubmon, so selecting it returns ErrUnsupportedClusterOption.
+func ClusterMonitorEnvs(backend string, checkInterval string) ([]corev1.EnvVar, error) {
+	switch backend {
+	case "", ClusterMonitorPubsub:
+	case ClusterMonitorMetrics:
+		return nil, fmt.Errorf("cluster monitor %q: %w", backend, ErrUnsupportedClusterOption)
+	default:
+		return nil, fmt.Errorf("invalid cluster monitor: %s", backend)
+	}
+	if checkInterval == "" {
+		return nil, nil
+	}
+	d, err := time.ParseDuration(checkInterval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid monitor check interval: %w", err)
+	}
+	if d <= 0 {
+		return nil, fmt.Errorf("monitor check interval must be positive, got %s", checkInterval)
+	}
+	return []corev1.EnvVar{
+		{
+			Name:  EnvClusterPubsubMonCheckInterval,
+			Value: d.String(),
+		},
+	}, nil
+}
+
+// ClusterPinSvcAPIEnvs Returns the environment variables exposing the IPFS Pinning Service API
+// of IPFS Cluster on the given port of all interfaces, which otherwise only listens on localhost.
+// The API allows anyone reaching it to pin content, so it requires credentials from the
+// referenced Secret key unless allowUnauthenticated is set. A zero port uses DefaultPinSvcAPIPort.
+func ClusterPinSvcAPIEnvs(
+	enabled bool,
+	port int,
+	credentials *corev1.SecretKeySelector,
+	allowUnauthenticated bool,
+) ([]corev1.EnvVar, error) {
+	if !enabled {
+		return nil, nil
+	}
+	if port == 0 {
+		port = DefaultPinSvcAPIPort
+	}
+	if port < 0 || port > 65535 {
+		return nil, fmt.Errorf("invalid pinning service api port: %d", port)
+	}
+	envs := []corev1.EnvVar{
+		{
+			Name:  EnvClusterPinSvcAPIHTTPListenMultiaddress,
+			Value: fmt.Sprintf("/ip4/0.0.0.0/tcp/%d", port),
+		},
+	}
+	if credentials == nil {
+		if !allowUnauthenticated {
+			return nil, fmt.Errorf("the pinning service api requires credentials unless unauthenticated access is allowed")
+		}
+		return envs, nil
+	}
+	if credentials.Name == "" || credentials.Key == "" {
+		return nil, fmt.Errorf("pinning service api secret reference needs both a name and a key")
+	}
+	return append(envs, corev1.EnvVar{
+		Name: EnvClusterPinSvcAPIBasicAuthCredentials,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: credentials.DeepCopy(),
+		},
+	}), nil
+}
+
+// ClusterRaftTimeoutEnvs Returns the environment variables tuning the raft heartbeat, election
+// and network timeouts, which multi-region clusters raise so the latency between peers doesn't
+// keep triggering leader elections. Empty timeouts keep their defaults. Once the heartbeat or
+// election timeout is set, the resulting election timeout must exceed the heartbeat timeout,
+// otherwise followers start elections before the leader's heartbeat can reach them.
+func ClusterRaftTimeoutEnvs(consensus, heartbeat, election, network string) ([]corev1.EnvVar, error) {
+	if heartbeat == "" && election == "" && network == "" {
+		return nil, nil
+	}
+	if consensus != ClusterConsensusRaft {
+		return nil, fmt.Errorf("raft timeouts require the %s consensus, got %s", ClusterConsensusRaft, consensus)
+	}
+	var envs []corev1.EnvVar
+	timeouts := []struct {
+		name     string
+		env      string
+		value    string
+		fallback time.Duration
+		parsed   time.Duration
+	}{
+		{name: "heartbeat", env: EnvClusterRaftHeartbeatTimeout, value: heartbeat, fallback: DefaultRaftHeartbeatTimeout},
+		{name: "election", env: EnvClusterRaftElectionTimeout, value: election, fallback: DefaultRaftElectionTimeout},
+		{name: "network", env: EnvClusterRaftNetworkTimeout, value: network},
+	}
+	for i := range timeouts {
+		t := &timeouts[i]
+		t.parsed = t.fallback
+		if t.value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(t.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid raft %s timeout: %w", t.name, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("raft %s timeout must be positive, got %s", t.name, t.value)
+		}
+		t.parsed = d
+		envs = append(envs, corev1.EnvVar{
+			Name:  t.env,
+			Value: d.String(),
+		})
+	}
+	// the defaults are equal, so the ordering only holds once either of them is tuned
+	if heartbeat == "" && election == "" {
+		return envs, nil
+	}
+	if heartbeatTimeout, electionTimeout := timeouts[0].parsed, timeouts[1].parsed; electionTimeout <= heartbeatTimeout {
+		return nil, fmt.Errorf(
+			"raft election timeout %s must exceed the heartbeat timeout %s", electionTimeout, heartbeatTimeout,
+		)
+	}
+	return envs, nil
+}
+
+// ClusterLogLevelEnvs Returns the environment variables setting the log level of IPFS Cluster to
+// the given global level, overridden for each component of the map, e.g. `pintracker: debug` to
+// debug the pin tracker alone. An empty global level uses DefaultClusterLogLevel.
+func ClusterLogLevelEnvs(level string, components map[string]string) ([]corev1.EnvVar, error) {
+	isOneOf := func(value string, values []string) bool {
+		for _, v := range values {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	}
+	if level == "" {
+		level = DefaultClusterLogLevel
+	}
+	if !isOneOf(level, clusterLogLevels) {
+		return nil, fmt.Errorf("invalid cluster log level: %s", level)
+	}
+	names := make([]string, 0, len(components))
+	for component := range components {
+		names = append(names, component)
+	}
+	sort.Strings(names)
+	levels := []string{level}
+	for _, component := range names {
+		if !isOneOf(component, clusterLogComponents) {
+			return nil, fmt.Errorf("unknown cluster log component: %s", component)
+		}
+		if !isOneOf(components[component], clusterLogLevels) {
+			return nil, fmt.Errorf("invalid log level for component %s: %s", component, components[component])
+		}
+		levels = append(levels, component+":"+components[component])
+	}
+	return []corev1.EnvVar{
+		{
+			Name:  EnvClusterLogLevel,
+			Value: strings.Join(levels, ","),
+		},
+	}, nil
+}
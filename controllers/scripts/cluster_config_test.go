@@ -0,0 +1,582 @@
+package scripts_test
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+	"github.com/redhat-et/ipfs-operator/controllers/scripts"
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Cluster allocator", func() {
+	When("the balanced allocator is used", func() {
+		It("groups peers by tag before the metrics", func() {
+			envs, err := scripts.ClusterAllocatorEnvs(scripts.ClusterAllocatorBalanced, []string{"freespace", "pinqueue"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(envs).To(ConsistOf(corev1.EnvVar{
+				Name:  scripts.EnvClusterAllocateBy,
+				Value: "tag:group,freespace,pinqueue",
+			}))
+		})
+
+		It("is the default allocator", func() {
+			envs, err := scripts.ClusterAllocatorEnvs("", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(envs).To(HaveLen(1))
+			Expect(envs[0].Value).To(Equal("tag:group,freespace"))
+		})
+	})
+
+	When("the metrics allocator is used", func() {
+		It("keeps the metric ordering", func() {
+			envs, err := scripts.ClusterAllocatorEnvs(scripts.ClusterAllocatorMetrics, []string{"pinqueue", "freespace"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(envs).To(HaveLen(1))
+			Expect(envs[0].Value).To(Equal("pinqueue,freespace"))
+		})
+
+		It("drops duplicate metrics", func() {
+			envs, err := scripts.ClusterAllocatorEnvs(scripts.ClusterAllocatorMetrics, []string{"freespace", "freespace"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(envs[0].Value).To(Equal("freespace"))
+		})
+
+		It("sorts by free space without metrics", func() {
+			envs, err := scripts.ClusterAllocatorEnvs(scripts.ClusterAllocatorMetrics, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(envs[0].Value).To(Equal(scripts.ClusterMetricFreeSpace))
+		})
+	})
+
+	It("rejects an unknown allocator", func() {
+		_, err := scripts.ClusterAllocatorEnvs("random", nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects empty metrics", func() {
+		_, err := scripts.ClusterAllocatorEnvs(scripts.ClusterAllocatorMetrics, []string{""})
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Cluster tags informer", func() {
+	settings := clusterv1alpha1.ClusterTagSettings{
+		Tags: map[string]string{"region": "eu", "tier": "hot"},
+		Peers: []clusterv1alpha1.PeerTags{
+			{Ordinal: 1, Tags: map[string]string{"tier": "cold"}},
+			{Ordinal: 2, Tags: map[string]string{"rack": "b"}},
+		},
+	}
+
+	It("gives each peer its configured tags", func() {
+		expected := map[int32]string{
+			0: "region:eu,tier:hot",
+			1: "region:eu,tier:cold",
+			2: "rack:b,region:eu,tier:hot",
+		}
+		for ordinal, value := range expected {
+			envs, err := scripts.ClusterTagsInformerEnvs(settings.TagsForPeer(ordinal), 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(envs).To(ConsistOf(corev1.EnvVar{
+				Name:  scripts.EnvClusterTags,
+				Value: value,
+			}))
+		}
+	})
+
+	It("enables the informer with the given metric ttl", func() {
+		envs, err := scripts.ClusterTagsInformerEnvs(settings.TagsForPeer(0), 30*time.Second)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(envs).To(ContainElement(corev1.EnvVar{
+			Name:  scripts.EnvClusterTagsMetricTTL,
+			Value: "30s",
+		}))
+	})
+
+	It("rejects tags which cannot be encoded", func() {
+		_, err := scripts.ClusterTagsInformerEnvs(map[string]string{"zone": "eu:west"}, 0)
+		Expect(err).To(HaveOccurred())
+		_, err = scripts.ClusterTagsInformerEnvs(map[string]string{"": "eu"}, 0)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("renders the allocator metric for a tag", func() {
+		metrics := []string{scripts.ClusterTagMetric("region")}
+		envs, err := scripts.ClusterAllocatorEnvs(scripts.ClusterAllocatorMetrics, metrics)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(envs[0].Value).To(Equal("tag:region"))
+	})
+})
+
+var _ = Describe("Cluster REST API reverse proxy", func() {
+	It("is off by default", func() {
+		envs, err := scripts.ClusterRESTAPIProxyEnvs(false, []string{"https://cluster.example.com"}, true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(envs).To(BeEmpty())
+	})
+
+	It("trusts the configured proxy origins", func() {
+		envs, err := scripts.ClusterRESTAPIProxyEnvs(true, []string{"https://a.example.com", "https://b.example.com"}, true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(envs).To(ContainElements(
+			corev1.EnvVar{
+				Name:  scripts.EnvClusterRESTAPICORSAllowedOrigins,
+				Value: "https://a.example.com,https://b.example.com",
+			},
+			corev1.EnvVar{
+				Name:  scripts.EnvClusterRESTAPICORSAllowCredentials,
+				Value: "true",
+			},
+		))
+	})
+
+	It("rejects unsafe or malformed origins", func() {
+		_, err := scripts.ClusterRESTAPIProxyEnvs(true, nil, false)
+		Expect(err).To(HaveOccurred())
+		_, err = scripts.ClusterRESTAPIProxyEnvs(true, []string{"*"}, true)
+		Expect(err).To(HaveOccurred())
+		_, err = scripts.ClusterRESTAPIProxyEnvs(true, []string{"cluster.example.com"}, false)
+		Expect(err).To(HaveOccurred())
+		_, err = scripts.ClusterRESTAPIProxyEnvs(true, []string{"https://cluster.example.com/api"}, false)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Cluster CRDT rebroadcast interval", func() {
+	It("renders a valid interval", func() {
+		envs, err := scripts.ClusterCRDTRebroadcastEnvs(scripts.ClusterConsensusCRDT, "10s")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(envs).To(ConsistOf(corev1.EnvVar{
+			Name:  scripts.EnvClusterCRDTRebroadcastInterval,
+			Value: "10s",
+		}))
+	})
+
+	It("keeps the default when unset", func() {
+		envs, err := scripts.ClusterCRDTRebroadcastEnvs(scripts.ClusterConsensusRaft, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(envs).To(BeEmpty())
+	})
+
+	It("only applies in crdt mode", func() {
+		_, err := scripts.ClusterCRDTRebroadcastEnvs(scripts.ClusterConsensusRaft, "10s")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects invalid intervals", func() {
+		_, err := scripts.ClusterCRDTRebroadcastEnvs(scripts.ClusterConsensusCRDT, "often")
+		Expect(err).To(HaveOccurred())
+		_, err = scripts.ClusterCRDTRebroadcastEnvs(scripts.ClusterConsensusCRDT, "-1m")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Cluster pin recover interval", func() {
+	It("renders a valid interval", func() {
+		envs, err := scripts.ClusterPinRecoverIntervalEnvs("15m")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(envs).To(ConsistOf(corev1.EnvVar{
+			Name:  scripts.EnvClusterPinRecoverInterval,
+			Value: "15m0s",
+		}))
+	})
+
+	It("keeps the default when unset", func() {
+		envs, err := scripts.ClusterPinRecoverIntervalEnvs("")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(envs).To(BeEmpty())
+	})
+
+	It("rejects invalid intervals", func() {
+		_, err := scripts.ClusterPinRecoverIntervalEnvs("hourly")
+		Expect(err).To(HaveOccurred())
+		_, err = scripts.ClusterPinRecoverIntervalEnvs("0s")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Cluster repinning", func() {
+	It("renders the disable_repinning flag", func() {
+		Expect(scripts.ClusterDisableRepinningEnvs(true)).To(ConsistOf(corev1.EnvVar{
+			Name:  scripts.EnvClusterDisableRepinning,
+			Value: "true",
+		}))
+		Expect(scripts.ClusterDisableRepinningEnvs(false)).To(ConsistOf(corev1.EnvVar{
+			Name:  scripts.EnvClusterDisableRepinning,
+			Value: "false",
+		}))
+	})
+})
+
+var _ = Describe("Cluster REST API basic auth", func() {
+	ref := &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "cluster-api-auth"},
+		Key:                  "credentials",
+	}
+
+	It("references the credentials from the Secret", func() {
+		envs, err := scripts.ClusterRESTAPIBasicAuthEnvs(ref)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(envs).To(HaveLen(1))
+		Expect(envs[0].Name).To(Equal(scripts.EnvClusterRESTAPIBasicAuthCredentials))
+		Expect(envs[0].ValueFrom).NotTo(BeNil())
+		Expect(envs[0].ValueFrom.SecretKeyRef).To(Equal(ref))
+	})
+
+	It("never inlines the credentials", func() {
+		envs, err := scripts.ClusterRESTAPIBasicAuthEnvs(ref)
+		Expect(err).NotTo(HaveOccurred())
+		for _, env := range envs {
+			Expect(env.Value).To(BeEmpty())
+		}
+		Expect(scripts.IPFSClusterEntrypoint).NotTo(ContainSubstring("basic_auth_credentials"))
+		Expect(scripts.IPFSClusterEntrypoint).NotTo(ContainSubstring(scripts.EnvClusterRESTAPIBasicAuthCredentials))
+	})
+
+	It("leaves the REST API open without a reference", func() {
+		envs, err := scripts.ClusterRESTAPIBasicAuthEnvs(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(envs).To(BeEmpty())
+	})
+
+	It("rejects an incomplete reference", func() {
+		_, err := scripts.ClusterRESTAPIBasicAuthEnvs(&corev1.SecretKeySelector{Key: "credentials"})
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Cluster REST API TLS", func() {
+	It("serves the certificate of the mounted TLS Secret", func() {
+		Expect(scripts.ClusterRESTAPITLSEnvs("/cluster-api-tls")).To(ConsistOf(
+			corev1.EnvVar{Name: scripts.EnvClusterRESTAPISSLCertFile, Value: "/cluster-api-tls/tls.crt"},
+			corev1.EnvVar{Name: scripts.EnvClusterRESTAPISSLKeyFile, Value: "/cluster-api-tls/tls.key"},
+		))
+	})
+})
+
+var _ = Describe("Cluster peer monitor", func() {
+	It("renders the pubsub monitor", func() {
+		envs, err := scripts.ClusterMonitorEnvs(scripts.ClusterMonitorPubsub, "15s")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(envs).To(ConsistOf(corev1.EnvVar{
+			Name:  scripts.EnvClusterPubsubMonCheckInterval,
+			Value: "15s",
+		}))
+	})
+
+	It("uses the pubsub monitor by default", func() {
+		envs, err := scripts.ClusterMonitorEnvs("", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(envs).To(BeEmpty())
+	})
+
+	It("reports that the metrics monitor is unsupported", func() {
+		_, err := scripts.ClusterMonitorEnvs(scripts.ClusterMonitorMetrics, "15s")
+		Expect(err).To(MatchError(scripts.ErrUnsupportedClusterOption))
+	})
+
+	It("rejects unknown backends and invalid intervals", func() {
+		_, err := scripts.ClusterMonitorEnvs("gossip", "")
+		Expect(err).To(HaveOccurred())
+		_, err = scripts.ClusterMonitorEnvs(scripts.ClusterMonitorPubsub, "0s")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Cluster Pinning Service API", func() {
+	credentials := &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "pinsvc-auth"},
+		Key:                  "credentials",
+	}
+
+	It("listens on all interfaces with credentials from the Secret", func() {
+		envs, err := scripts.ClusterPinSvcAPIEnvs(true, 0, credentials, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(envs).To(ContainElement(corev1.EnvVar{
+			Name:  scripts.EnvClusterPinSvcAPIHTTPListenMultiaddress,
+			Value: "/ip4/0.0.0.0/tcp/9097",
+		}))
+		Expect(envs).To(ContainElement(corev1.EnvVar{
+			Name:      scripts.EnvClusterPinSvcAPIBasicAuthCredentials,
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: credentials},
+		}))
+	})
+
+	It("requires auth by default", func() {
+		_, err := scripts.ClusterPinSvcAPIEnvs(true, 0, nil, false)
+		Expect(err).To(HaveOccurred())
+
+		envs, err := scripts.ClusterPinSvcAPIEnvs(true, 19097, nil, true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(envs).To(ConsistOf(corev1.EnvVar{
+			Name:  scripts.EnvClusterPinSvcAPIHTTPListenMultiaddress,
+			Value: "/ip4/0.0.0.0/tcp/19097",
+		}))
+	})
+
+	It("stays on localhost when disabled", func() {
+		envs, err := scripts.ClusterPinSvcAPIEnvs(false, 0, nil, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(envs).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Cluster raft timeouts", func() {
+	It("renders valid tuning", func() {
+		envs, err := scripts.ClusterRaftTimeoutEnvs(scripts.ClusterConsensusRaft, "2s", "10s", "30s")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(envs).To(ConsistOf(
+			corev1.EnvVar{Name: scripts.EnvClusterRaftHeartbeatTimeout, Value: "2s"},
+			corev1.EnvVar{Name: scripts.EnvClusterRaftElectionTimeout, Value: "10s"},
+			corev1.EnvVar{Name: scripts.EnvClusterRaftNetworkTimeout, Value: "30s"},
+		))
+	})
+
+	It("keeps the defaults when unset", func() {
+		envs, err := scripts.ClusterRaftTimeoutEnvs(scripts.ClusterConsensusCRDT, "", "", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(envs).To(BeEmpty())
+	})
+
+	It("requires the election timeout to exceed the heartbeat timeout", func() {
+		_, err := scripts.ClusterRaftTimeoutEnvs(scripts.ClusterConsensusRaft, "5s", "5s", "")
+		Expect(err).To(HaveOccurred())
+		_, err = scripts.ClusterRaftTimeoutEnvs(scripts.ClusterConsensusRaft, "5s", "", "")
+		Expect(err).To(HaveOccurred())
+		envs, err := scripts.ClusterRaftTimeoutEnvs(scripts.ClusterConsensusRaft, "", "5s", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(envs).To(HaveLen(1))
+	})
+
+	It("keeps the default heartbeat and election timeouts when only the network timeout is set", func() {
+		envs, err := scripts.ClusterRaftTimeoutEnvs(scripts.ClusterConsensusRaft, "", "", "30s")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(envs).To(ConsistOf(corev1.EnvVar{Name: scripts.EnvClusterRaftNetworkTimeout, Value: "30s"}))
+	})
+
+	It("rejects invalid timeouts and other consensus", func() {
+		_, err := scripts.ClusterRaftTimeoutEnvs(scripts.ClusterConsensusRaft, "soon", "10s", "")
+		Expect(err).To(HaveOccurred())
+		_, err = scripts.ClusterRaftTimeoutEnvs(scripts.ClusterConsensusRaft, "", "", "-1s")
+		Expect(err).To(HaveOccurred())
+		_, err = scripts.ClusterRaftTimeoutEnvs(scripts.ClusterConsensusCRDT, "2s", "10s", "")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Cluster connector unpinning", func() {
+	It("renders the unpin_disable flag", func() {
+		Expect(scripts.ClusterUnpinDisableEnvs(true)).To(ConsistOf(corev1.EnvVar{
+			Name:  scripts.EnvClusterIPFSHTTPUnpinDisable,
+			Value: "true",
+		}))
+		Expect(scripts.ClusterUnpinDisableEnvs(false)).To(ConsistOf(corev1.EnvVar{
+			Name:  scripts.EnvClusterIPFSHTTPUnpinDisable,
+			Value: "false",
+		}))
+	})
+})
+
+var _ = Describe("Cluster REST API access log", func() {
+	It("renders nothing by default", func() {
+		Expect(scripts.ClusterRESTAPIAccessLogEnvs(false)).To(BeEmpty())
+	})
+
+	It("logs the whole peer to stdout as JSON when enabled", func() {
+		Expect(scripts.ClusterRESTAPIAccessLogEnvs(true)).To(ConsistOf(
+			corev1.EnvVar{Name: scripts.EnvGoLogFormat, Value: "json"},
+			corev1.EnvVar{Name: scripts.EnvGoLogOutput, Value: "stdout"},
+		))
+	})
+})
+
+var _ = Describe("Cluster log levels", func() {
+	It("renders the global level alone by default", func() {
+		envs, err := scripts.ClusterLogLevelEnvs("", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(envs).To(ConsistOf(corev1.EnvVar{
+			Name:  scripts.EnvClusterLogLevel,
+			Value: scripts.DefaultClusterLogLevel,
+		}))
+	})
+
+	It("renders per-component levels after the global one", func() {
+		envs, err := scripts.ClusterLogLevelEnvs("warn", map[string]string{
+			"pintracker": "debug",
+			"crdt":       "info",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(envs).To(ConsistOf(corev1.EnvVar{
+			Name:  scripts.EnvClusterLogLevel,
+			Value: "warn,crdt:info,pintracker:debug",
+		}))
+	})
+
+	It("rejects unknown levels and components", func() {
+		_, err := scripts.ClusterLogLevelEnvs("verbose", nil)
+		Expect(err).To(HaveOccurred())
+		_, err = scripts.ClusterLogLevelEnvs("", map[string]string{"pintracker": "trace"})
+		Expect(err).To(HaveOccurred())
+		_, err = scripts.ClusterLogLevelEnvs("", map[string]string{"scheduler": "debug"})
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Cluster follower mode", func() {
+	It("disables the write endpoints in follower mode", func() {
+		Expect(scripts.ClusterFollowerModeEnvs(true)).To(ConsistOf(corev1.EnvVar{
+			Name:  scripts.EnvClusterFollowerMode,
+			Value: "true",
+		}))
+	})
+
+	It("keeps writes enabled otherwise", func() {
+		Expect(scripts.ClusterFollowerModeEnvs(false)).To(ConsistOf(corev1.EnvVar{
+			Name:  scripts.EnvClusterFollowerMode,
+			Value: "false",
+		}))
+	})
+})
+
+var _ = Describe("Cluster connect swarms delay", func() {
+	It("waits for the IPFS daemon by default", func() {
+		envs, err := scripts.ClusterConnectSwarmsDelayEnvs("")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(envs).To(ConsistOf(corev1.EnvVar{
+			Name:  scripts.EnvClusterIPFSHTTPConnectSwarmsDelay,
+			Value: scripts.DefaultConnectSwarmsDelay.String(),
+		}))
+	})
+
+	It("renders a valid duration", func() {
+		envs, err := scripts.ClusterConnectSwarmsDelayEnvs("1m30s")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(envs).To(ConsistOf(corev1.EnvVar{
+			Name:  scripts.EnvClusterIPFSHTTPConnectSwarmsDelay,
+			Value: "1m30s",
+		}))
+	})
+
+	It("rejects invalid durations", func() {
+		_, err := scripts.ClusterConnectSwarmsDelayEnvs("later")
+		Expect(err).To(HaveOccurred())
+		_, err = scripts.ClusterConnectSwarmsDelayEnvs("-1s")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Cluster CRDT cluster name", func() {
+	It("renders the cluster name", func() {
+		envs, err := scripts.ClusterCRDTClusterNameEnvs(scripts.ClusterConsensusCRDT, "ipfs-cluster/test/ipfs")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(envs).To(ConsistOf(corev1.EnvVar{
+			Name:  scripts.EnvClusterCRDTClusterName,
+			Value: "ipfs-cluster/test/ipfs",
+		}))
+	})
+
+	It("rejects an empty name and other consensus", func() {
+		_, err := scripts.ClusterCRDTClusterNameEnvs("", "")
+		Expect(err).To(HaveOccurred())
+		_, err = scripts.ClusterCRDTClusterNameEnvs(scripts.ClusterConsensusRaft, "ipfs-cluster/test/ipfs")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Cluster REST API request limits", func() {
+	It("renders the configured limits", func() {
+		envs, err := scripts.ClusterRESTAPILimitsEnvs(scripts.ClusterRESTAPILimits{
+			MaxHeaderBytes:    64 << 10,
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       5 * time.Minute,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(envs).To(Equal([]corev1.EnvVar{
+			{Name: scripts.EnvClusterRESTAPIMaxHeaderBytes, Value: "65536"},
+			{Name: scripts.EnvClusterRESTAPIReadHeaderTimeout, Value: "10s"},
+			{Name: scripts.EnvClusterRESTAPIReadTimeout, Value: "5m0s"},
+		}))
+	})
+
+	It("defaults to generous header limits", func() {
+		envs, err := scripts.ClusterRESTAPILimitsEnvs(scripts.ClusterRESTAPILimits{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(envs).To(Equal([]corev1.EnvVar{
+			{Name: scripts.EnvClusterRESTAPIMaxHeaderBytes, Value: "1048576"},
+			{Name: scripts.EnvClusterRESTAPIReadHeaderTimeout, Value: "30s"},
+		}))
+	})
+
+	It("rejects invalid and unsupported limits", func() {
+		_, err := scripts.ClusterRESTAPILimitsEnvs(scripts.ClusterRESTAPILimits{MaxHeaderBytes: -1})
+		Expect(err).To(HaveOccurred())
+		_, err = scripts.ClusterRESTAPILimitsEnvs(scripts.ClusterRESTAPILimits{MaxBodyBytes: 1 << 20})
+		Expect(err).To(MatchError(scripts.ErrUnsupportedClusterOption))
+	})
+})
+
+var _ = Describe("Cluster CRDT trusted peers", func() {
+	var ids []string
+
+	BeforeEach(func() {
+		ids = make([]string, 4)
+		for i := range ids {
+			id, _, err := utils.GenerateIdentity()
+			Expect(err).NotTo(HaveOccurred())
+			ids[i] = id.String()
+		}
+	})
+
+	It("prunes a removed peer and keeps the external trusted peers", func() {
+		trusted := []string{ids[0], ids[1], ids[2], ids[3]}
+		current := []string{ids[0], ids[1]}
+		external := []string{ids[3]}
+		pruned := scripts.PruneTrustedPeers(trusted, current, external)
+		Expect(pruned).To(ConsistOf(ids[0], ids[1], ids[3]))
+		Expect(pruned).NotTo(ContainElement(ids[2]))
+	})
+
+	It("only keeps the peers of the cluster which were trusted", func() {
+		pruned := scripts.PruneTrustedPeers([]string{ids[0], ids[0]}, []string{ids[0], ids[1]}, []string{ids[3]})
+		Expect(pruned).To(HaveLen(2))
+		Expect(pruned).To(ConsistOf(ids[0], ids[3]))
+	})
+
+	It("renders the pruned trusted peers", func() {
+		envs, err := scripts.ClusterCRDTTrustedPeersEnvs([]string{ids[0], ids[2]}, []string{ids[0]}, []string{ids[3]})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(envs).To(HaveLen(1))
+		Expect(envs[0].Name).To(Equal(scripts.EnvClusterCRDTTrustedPeers))
+		Expect(strings.Split(envs[0].Value, ",")).To(ConsistOf(ids[0], ids[3]))
+	})
+
+	It("rejects invalid or missing trusted peers", func() {
+		_, err := scripts.ClusterCRDTTrustedPeersEnvs(nil, nil, []string{"not-a-peer"})
+		Expect(err).To(MatchError(ContainSubstring("invalid trusted peer")))
+		_, err = scripts.ClusterCRDTTrustedPeersEnvs([]string{ids[0]}, nil, nil)
+		Expect(err).To(MatchError(ContainSubstring("at least one trusted peer")))
+	})
+})
+
+var _ = Describe("Cluster pinqueue informer", func() {
+	It("reports that the informer is unsupported", func() {
+		_, err := scripts.ClusterPinQueueAllocationEnvs("", &scripts.ClusterPinQueueInformer{
+			MetricTTL:        15 * time.Second,
+			WeightBucketSize: 1000,
+		})
+		Expect(err).To(MatchError(scripts.ErrUnsupportedClusterOption))
+	})
+
+	It("leaves the allocation alone when disabled", func() {
+		envs, err := scripts.ClusterPinQueueAllocationEnvs("", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(envs).To(Equal([]corev1.EnvVar{
+			{Name: scripts.EnvClusterAllocateBy, Value: "tag:group,freespace"},
+		}))
+	})
+})
@@ -3,6 +3,7 @@ package scripts
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"strconv"
@@ -12,8 +13,14 @@ import (
 	"github.com/ipfs/kubo/config"
 	"github.com/libp2p/go-libp2p/core/peer"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
 )
 
+// ErrUnsupportedOption Is returned when an option is requested which the bundled
+// Kubo config does not yet provide.
+var ErrUnsupportedOption = errors.New("option is not supported by the bundled kubo version")
+
 type configureIpfsOpts struct {
 	FlattenedConfig string
 }
@@ -89,6 +96,9 @@ log() {
 #  BOOTSTRAP_PEER_PRIV_KEY (string) the private key of the bootstrap node
 #  BOOTSTRAP_ADDR (string) the address of the bootstrap node
 #  SVC_NAME (string) the name of the service to connect to
+#  /peerstore/peerstore (file) the peerstore rendered by the operator, replacing the one
+#    left behind by the previous run so that addresses of gone peers age out
+#  CLUSTER_LOGLEVEL (string) the global and per-component log levels, defaults to info
 ######################################
 run_ipfs_cluster() {
 	if [ ! -f /data/ipfs-cluster/service.json ]; then
@@ -96,6 +106,11 @@ run_ipfs_cluster() {
 		ipfs-cluster-service init --consensus crdt
 	fi
 
+	if [ -s /peerstore/peerstore ]; then
+		log "📇 refreshing the peerstore"
+		cp /peerstore/peerstore /data/ipfs-cluster/peerstore
+	fi
+
 	log "🔍 reading hostname"
 	PEER_HOSTNAME=$(cat /proc/sys/kernel/hostname)
 	log "starting ipfs-cluster on ${PEER_HOSTNAME}"
@@ -105,7 +120,7 @@ run_ipfs_cluster() {
 		log "starting ipfs-cluster using the provided peer ID and private key"
 		CLUSTER_ID="${BOOTSTRAP_PEER_ID}" \
 		CLUSTER_PRIVATEKEY="${BOOTSTRAP_PEER_PRIV_KEY}" \
-		exec ipfs-cluster-service daemon --upgrade
+		exec ipfs-cluster-service --loglevel "${CLUSTER_LOGLEVEL:-info}" daemon --upgrade
 	else
 		log "building the bootstrap address"
 		BOOTSTRAP_ADDR="/dns4/${SVC_NAME}-0.${SVC_NAME}/tcp/9096/ipfs/${BOOTSTRAP_PEER_ID}"
@@ -115,7 +130,8 @@ run_ipfs_cluster() {
 		fi
 		log "starting ipfs-cluster using the bootstrap address"
 		# Only ipfs user can get here
-		exec ipfs-cluster-service daemon --upgrade --bootstrap "${BOOTSTRAP_ADDR}" --leave
+		exec ipfs-cluster-service --loglevel "${CLUSTER_LOGLEVEL:-info}" \
+			daemon --upgrade --bootstrap "${BOOTSTRAP_ADDR}" --leave
 	fi
 }
 
@@ -131,6 +147,10 @@ for op in "${@}"; do
 			run_ipfs_cluster
 			log "✅ Done"
 			;;
+		"standby")
+			log "🛌 Standing by until this peer is promoted"
+			sleep infinity
+			;;
 		*)
 			log "😕 Operation '${op}' not defined"
 			exit 1
@@ -140,6 +160,39 @@ done
 `
 )
 
+const (
+	// ValidateSwarmKey Checks that the private swarm key provided through IPFS_SWARM_KEY
+	// is well-formed, failing with a clear message before the IPFS daemon starts.
+	ValidateSwarmKey = `
+#!/bin/sh
+fail() {
+	echo "❌ invalid swarm key: $1"
+	exit 1
+}
+
+header=$(printf '%s\n' "${IPFS_SWARM_KEY}" | sed -n 1p)
+encoding=$(printf '%s\n' "${IPFS_SWARM_KEY}" | sed -n 2p)
+key=$(printf '%s\n' "${IPFS_SWARM_KEY}" | sed -n 3p)
+
+if [ "${header}" != "/key/swarm/psk/1.0.0/" ]; then
+	fail "expected header /key/swarm/psk/1.0.0/, got '${header}'"
+fi
+case "${encoding}" in
+	"/base16/")
+		printf '%s' "${key}" | grep -Eq '^[0-9a-fA-F]{64}$' || fail "expected 64 hexadecimal characters"
+		;;
+	"/base64/")
+		size=$(printf '%s' "${key}" | base64 -d 2>/dev/null | wc -c)
+		[ "${size}" -ge 32 ] || fail "expected at least 32 bytes of base64-encoded key material"
+		;;
+	*)
+		fail "unsupported encoding '${encoding}'"
+		;;
+esac
+echo "✅ swarm key is valid"
+`
+)
+
 const (
 	// BloomFalsePositiveRate Defines the probability of the bloom filter detecting a given value
 	// as being stored.
@@ -149,8 +202,9 @@ const (
 	BloomBlockSize = 256 * units.Kibibyte
 )
 
-// CreateConfigureScript Accepts the given storageMax, peers, and relayClient
+// CreateConfigureScript Accepts the given storageMax, peers, relayClient, and datastore backend
 // and returns a completed configuration script which can be ran by the IPFS container config.
+// Any backend other than badger results in flatfs being used.
 func CreateConfigureScript(
 	storageMax string,
 	peers []peer.AddrInfo,
@@ -158,6 +212,8 @@ func CreateConfigureScript(
 	bloomFilterSize int64,
 	reproviderInterval string,
 	reproviderStrategy string,
+	datastoreBackend clusterv1alpha1.DatastoreBackend,
+	acceleratedDHTClient bool,
 	bootstrapAddrs []string,
 ) (string, error) {
 	// set settings
@@ -169,6 +225,8 @@ func CreateConfigureScript(
 		bloomFilterSize,
 		reproviderInterval,
 		reproviderStrategy,
+		datastoreBackend,
+		acceleratedDHTClient,
 	)
 	if err != nil {
 		return "", err
@@ -196,9 +254,9 @@ func CreateConfigureScript(
 	return configureBuf.String(), nil
 }
 
-// applyFlatfsServer Applies the given list of profiles to the kubo config object.
-func applyFlatfsServer(conf *config.Config) error {
-	for _, profile := range []string{"flatfs", "server"} {
+// applyProfiles Applies the given list of profiles to the kubo config object.
+func applyProfiles(conf *config.Config, profiles ...string) error {
+	for _, profile := range profiles {
 		transformer, ok := config.Profiles[profile]
 		if !ok {
 			return fmt.Errorf("invalid configuration profile: %s", profile)
@@ -248,20 +306,23 @@ func setFlatfsShardFunc(conf *config.Config, n int8) error {
 	return nil
 }
 
+// DefaultConnMgrHighWater Is the number of connections above which the connection
+// manager starts trimming, and thereby the number of peers a node is expected to hold.
+const DefaultConnMgrHighWater = 2000
+
 // applyIPFSClusterK8sDefaults Applies settings to the given Kubo configuration
 // which are customized specifically for running within a Kubernetes cluster.
 func applyIPFSClusterK8sDefaults(conf *config.Config, storageMax string, peers []peer.AddrInfo, rc config.RelayClient) {
 	conf.Bootstrap = config.DefaultBootstrapAddresses
 	conf.Addresses.API = config.Strings{"/ip4/0.0.0.0/tcp/5001"}
 	conf.Addresses.Gateway = config.Strings{"/ip4/0.0.0.0/tcp/8080"}
-	conf.Swarm.ConnMgr.HighWater = 2000
+	conf.Swarm.ConnMgr.HighWater = DefaultConnMgrHighWater
 	conf.Datastore.BloomFilterSize = 1048576
 	conf.Datastore.StorageMax = storageMax
 	conf.Addresses.Swarm = []string{"/ip4/0.0.0.0/tcp/4001", "/ip6/::/tcp/4001"}
 	conf.Swarm.EnableHolePunching = config.False
 	conf.Swarm.RelayClient = rc
 	conf.Peering.Peers = peers
-	conf.Experimental.AcceleratedDHTClient = true
 	// make sure that we're not announcing or filtering any addresses to ensure Kubo daemons can connect via LAN
 	// issue: https://github.com/ipfs-cluster/ipfs-operator/issues/34
 	conf.Addresses.NoAnnounce = make([]string, 0)
@@ -277,6 +338,8 @@ func createTemplateConfig(
 	bloomFilterSize int64,
 	reproviderInterval string,
 	reproviderStrategy string,
+	datastoreBackend clusterv1alpha1.DatastoreBackend,
+	acceleratedDHTClient bool,
 ) (conf config.Config, err error) {
 	// attempt to generate an identity
 
@@ -284,19 +347,30 @@ func createTemplateConfig(
 	conf.Identity.PeerID = "_peer-id_"
 	conf.Identity.PrivKey = "_private-key_"
 
-	// apply the server + flatfs profiles
-	if err = applyFlatfsServer(&conf); err != nil {
-		return
+	// apply the server + datastore profiles
+	if datastoreBackend == clusterv1alpha1.DatastoreBackendBadger {
+		if err = applyProfiles(&conf, "badgerds", "server"); err != nil {
+			return
+		}
+	} else {
+		if err = applyProfiles(&conf, "flatfs", "server"); err != nil {
+			return
+		}
+		if err = setFlatfsShardFunc(&conf, 3); err != nil {
+			return
+		}
 	}
-	if err = setFlatfsShardFunc(&conf, 3); err != nil {
+	applyIPFSClusterK8sDefaults(&conf, storageMax, peers, rc)
+	// the matching ulimit is raised on the IPFS container through EnvIPFSFDMax
+	if _, err = ApplyResourceMgrMaxFileDescriptors(&conf, DefaultConnMgrHighWater); err != nil {
 		return
 	}
-	applyIPFSClusterK8sDefaults(&conf, storageMax, peers, rc)
 
 	conf.Swarm.RelayClient = rc
 	conf.Datastore.BloomFilterSize = int(bloomFilterSize)
 	conf.Reprovider.Interval = reproviderInterval
 	conf.Reprovider.Strategy = reproviderStrategy
+	conf.Experimental.AcceleratedDHTClient = acceleratedDHTClient
 
 	return
 }
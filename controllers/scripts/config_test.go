@@ -1,6 +1,9 @@
 package scripts_test
 
 import (
+	"fmt"
+
+	"github.com/ipfs/kubo/config"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/redhat-et/ipfs-operator/controllers/scripts"
@@ -52,3 +55,15 @@ var _ = Describe("Bloom Filter", func() {
 		})
 	})
 })
+
+var _ = Describe("Configure script", func() {
+	It("sets the resource manager's file descriptor limit", func() {
+		script, err := scripts.CreateConfigureScript(
+			"8GB", nil, config.RelayClient{}, 1024, "12h", "all", "", true, nil,
+		)
+		Expect(err).NotTo(HaveOccurred())
+		resourceMgrFDs, _, err := scripts.ResourceMgrFileDescriptors(scripts.DefaultConnMgrHighWater)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(script).To(ContainSubstring(fmt.Sprintf(`"FD":%d`, resourceMgrFDs)))
+	})
+})
@@ -0,0 +1,120 @@
+package scripts
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/ipfs/kubo/config"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+)
+
+// DatastoreBackendLevelDB Stores small keys such as pins and provider records compactly. It only
+// serves as a tier of a mount datastore, so unlike flatfs and badger it isn't offered by the API.
+const DatastoreBackendLevelDB clusterv1alpha1.DatastoreBackend = "levelds"
+
+// DatastoreTier Is a datastore backend mounted at a key prefix of the Kubo datastore, e.g. on
+// an SSD for the hot keys and on an HDD for the cold blocks.
+type DatastoreTier struct {
+	// Mountpoint Is the key prefix stored by the tier. Keys outside of any other tier are stored
+	// by the tier mounted at `/`.
+	Mountpoint string
+	// Path Is the directory of the backend, relative to the repo unless absolute, so that it can
+	// lie on a volume of its own.
+	Path string
+	// Backend Is either flatfs, badger or DatastoreBackendLevelDB.
+	Backend clusterv1alpha1.DatastoreBackend
+}
+
+// TieredDatastoreSpec Returns a mount-type Datastore.Spec storing the keys under the mountpoint of
+// each tier in its backend, one of the tiers being mounted at `/` for the remaining keys. An empty hot
+// mountpoint mounts the hot tier at `/`, and an empty cold mountpoint mounts the cold tier at
+// `/blocks`, so that the blocks land on the cold tier and everything else on the hot one.
+func TieredDatastoreSpec(hot, cold DatastoreTier) (map[string]interface{}, error) {
+	if hot.Mountpoint == "" {
+		hot.Mountpoint = "/"
+	}
+	if cold.Mountpoint == "" {
+		cold.Mountpoint = "/blocks"
+	}
+	tiers := []struct {
+		name string
+		tier DatastoreTier
+	}{
+		{name: "cold", tier: cold},
+		{name: "hot", tier: hot},
+	}
+	mountpoints := make([]string, len(tiers))
+	for i, t := range tiers {
+		mountpoints[i] = path.Clean(t.tier.Mountpoint)
+		if !path.IsAbs(mountpoints[i]) {
+			return nil, fmt.Errorf("%s tier mountpoint must be an absolute key prefix, got %q", t.name, t.tier.Mountpoint)
+		}
+	}
+	if mountpoints[0] == mountpoints[1] {
+		return nil, fmt.Errorf("hot and cold tiers cannot share the mountpoint %s", mountpoints[0])
+	}
+	if mountpoints[0] != "/" && mountpoints[1] != "/" {
+		return nil, fmt.Errorf("one tier must be mounted at /, got %s and %s", mountpoints[1], mountpoints[0])
+	}
+	mounts := make([]interface{}, 0, len(tiers))
+	for i, t := range tiers {
+		if t.tier.Path == "" {
+			return nil, fmt.Errorf("%s tier requires a path", t.name)
+		}
+		child, err := datastoreChildSpec(t.tier.Backend, t.tier.Path)
+		if err != nil {
+			return nil, fmt.Errorf("%s tier: %w", t.name, err)
+		}
+		mounts = append(mounts, map[string]interface{}{
+			"mountpoint": mountpoints[i],
+			"type":       "measure",
+			"prefix":     t.name + ".datastore",
+			"child":      child,
+		})
+	}
+	return map[string]interface{}{
+		"type":   "mount",
+		"mounts": mounts,
+	}, nil
+}
+
+// ApplyTieredDatastore Sets Datastore.Spec on the given Kubo configuration to the mount of the hot
+// and cold tiers. Kubo refuses to open a repo whose datastore spec has changed since its creation,
+// so this only applies to new repos.
+func ApplyTieredDatastore(conf *config.Config, hot, cold DatastoreTier) error {
+	spec, err := TieredDatastoreSpec(hot, cold)
+	if err != nil {
+		return err
+	}
+	conf.Datastore.Spec = spec
+	return nil
+}
+
+// datastoreChildSpec Returns the spec of the given backend storing its data under the given path.
+func datastoreChildSpec(backend clusterv1alpha1.DatastoreBackend, dir string) (map[string]interface{}, error) {
+	switch backend {
+	case clusterv1alpha1.DatastoreBackendFlatfs:
+		return map[string]interface{}{
+			"type":      "flatfs",
+			"path":      dir,
+			"sync":      true,
+			"shardFunc": "/repo/flatfs/shard/v1/next-to-last/3",
+		}, nil
+	case DatastoreBackendLevelDB:
+		return map[string]interface{}{
+			"type":        "levelds",
+			"path":        dir,
+			"compression": "none",
+		}, nil
+	case clusterv1alpha1.DatastoreBackendBadger:
+		return map[string]interface{}{
+			"type":       "badgerds",
+			"path":       dir,
+			"syncWrites": false,
+			"truncate":   true,
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid datastore backend: %q", backend)
+	}
+}
@@ -0,0 +1,75 @@
+package scripts_test
+
+import (
+	"encoding/json"
+
+	"github.com/ipfs/kubo/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+	"github.com/redhat-et/ipfs-operator/controllers/scripts"
+)
+
+var _ = Describe("Tiered datastore", func() {
+	hot := scripts.DatastoreTier{Path: "/data/ssd/datastore", Backend: scripts.DatastoreBackendLevelDB}
+	cold := scripts.DatastoreTier{Path: "/data/hdd/blocks", Backend: clusterv1alpha1.DatastoreBackendFlatfs}
+
+	It("mounts both tiers at their prefixes", func() {
+		conf := &config.Config{}
+		Expect(scripts.ApplyTieredDatastore(conf, hot, cold)).To(Succeed())
+
+		// round-trip through JSON, as Kubo reads the spec from its config file
+		raw, err := json.Marshal(conf.Datastore.Spec)
+		Expect(err).NotTo(HaveOccurred())
+		var spec struct {
+			Type   string `json:"type"`
+			Mounts []struct {
+				Mountpoint string                 `json:"mountpoint"`
+				Type       string                 `json:"type"`
+				Prefix     string                 `json:"prefix"`
+				Child      map[string]interface{} `json:"child"`
+			} `json:"mounts"`
+		}
+		Expect(json.Unmarshal(raw, &spec)).To(Succeed())
+		Expect(spec.Type).To(Equal("mount"))
+		Expect(spec.Mounts).To(HaveLen(2))
+
+		Expect(spec.Mounts[0].Mountpoint).To(Equal("/blocks"))
+		Expect(spec.Mounts[0].Type).To(Equal("measure"))
+		Expect(spec.Mounts[0].Child).To(HaveKeyWithValue("type", "flatfs"))
+		Expect(spec.Mounts[0].Child).To(HaveKeyWithValue("path", "/data/hdd/blocks"))
+
+		Expect(spec.Mounts[1].Mountpoint).To(Equal("/"))
+		Expect(spec.Mounts[1].Child).To(HaveKeyWithValue("type", "levelds"))
+		Expect(spec.Mounts[1].Child).To(HaveKeyWithValue("path", "/data/ssd/datastore"))
+		Expect(spec.Mounts[0].Prefix).NotTo(Equal(spec.Mounts[1].Prefix))
+	})
+
+	It("honors custom mountpoints", func() {
+		badgerHot := scripts.DatastoreTier{
+			Mountpoint: "/", Path: "badgerds", Backend: clusterv1alpha1.DatastoreBackendBadger,
+		}
+		archive := scripts.DatastoreTier{
+			Mountpoint: "/blocks/", Path: "/archive", Backend: clusterv1alpha1.DatastoreBackendFlatfs,
+		}
+		spec, err := scripts.TieredDatastoreSpec(badgerHot, archive)
+		Expect(err).NotTo(HaveOccurred())
+		mounts := spec["mounts"].([]interface{})
+		Expect(mounts[0]).To(HaveKeyWithValue("mountpoint", "/blocks"))
+		Expect(mounts[1]).To(HaveKeyWithValue("mountpoint", "/"))
+	})
+
+	It("rejects invalid tiers", func() {
+		_, err := scripts.TieredDatastoreSpec(hot, scripts.DatastoreTier{Mountpoint: "/", Path: "x", Backend: "flatfs"})
+		Expect(err).To(HaveOccurred())
+		_, err = scripts.TieredDatastoreSpec(scripts.DatastoreTier{Mountpoint: "/pins", Path: "x", Backend: "levelds"}, cold)
+		Expect(err).To(HaveOccurred())
+		_, err = scripts.TieredDatastoreSpec(hot, scripts.DatastoreTier{Path: "x", Backend: "s3"})
+		Expect(err).To(HaveOccurred())
+		_, err = scripts.TieredDatastoreSpec(hot, scripts.DatastoreTier{Mountpoint: "blocks", Path: "x", Backend: "flatfs"})
+		Expect(err).To(HaveOccurred())
+		_, err = scripts.TieredDatastoreSpec(hot, scripts.DatastoreTier{Backend: "flatfs"})
+		Expect(err).To(HaveOccurred())
+	})
+})
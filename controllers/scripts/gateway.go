@@ -0,0 +1,203 @@
+package scripts
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ipfs/kubo/config"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// DefaultFastDirIndexThreshold Is Kubo's default number of directory entries beyond which the gateway
+// lists a directory from its root node alone, without fetching the size of each entry.
+const DefaultFastDirIndexThreshold = 100
+
+// ApplyGatewayRootRedirect Sets Gateway.RootRedirect on the given Kubo configuration,
+// so requests to `/` are redirected to the given path, e.g. `/ipfs/<cid>/index.html`.
+// The redirect must be an absolute, clean path without a scheme or host.
+// Gateway.PathPrefixes is left untouched since Kubo no longer supports it.
+func ApplyGatewayRootRedirect(conf *config.Config, redirect string) error {
+	if redirect == "" {
+		conf.Gateway.RootRedirect = ""
+		return nil
+	}
+	if err := validateGatewayPath(redirect); err != nil {
+		return fmt.Errorf("invalid gateway root redirect: %w", err)
+	}
+	conf.Gateway.RootRedirect = redirect
+	return nil
+}
+
+// validateGatewayPath Ensures the given value is a well-formed absolute URL path.
+func validateGatewayPath(p string) error {
+	if !strings.HasPrefix(p, "/") || strings.HasPrefix(p, "//") {
+		return fmt.Errorf("path %q must be absolute", p)
+	}
+	u, err := url.Parse(p)
+	if err != nil {
+		return fmt.Errorf("path %q cannot be parsed: %w", p, err)
+	}
+	if u.Scheme != "" || u.Host != "" || u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("path %q must not contain a scheme, host, query, or fragment", p)
+	}
+	cleaned := path.Clean(u.Path)
+	if cleaned != strings.TrimSuffix(u.Path, "/") && cleaned != u.Path {
+		return fmt.Errorf("path %q is not clean, expected %q", p, cleaned)
+	}
+	return nil
+}
+
+// ApplyGatewayCacheHeaders Exposes the ETag header to browsers so that clients can revalidate
+// cached responses, keeping any other configured headers. Kubo v0.16 already marks `/ipfs/` responses
+// as immutable, whereas Gateway.HTTPHeaders is sent on every response, including mutable `/ipns/`
+// paths and errors, so a custom max-age can't be scoped to `/ipfs/` and returns ErrUnsupportedOption.
+// A zero max-age keeps Kubo's caching.
+func ApplyGatewayCacheHeaders(conf *config.Config, maxAge time.Duration) error {
+	if maxAge < 0 {
+		return fmt.Errorf("cache max-age cannot be negative, got %s", maxAge)
+	}
+	if maxAge > 0 {
+		return fmt.Errorf("gateway cache max-age: %w", ErrUnsupportedOption)
+	}
+	if conf.Gateway.HTTPHeaders == nil {
+		conf.Gateway.HTTPHeaders = make(map[string][]string)
+	}
+	exposed := conf.Gateway.HTTPHeaders["Access-Control-Expose-Headers"]
+	for _, header := range []string{"ETag", "X-Ipfs-Path", "X-Ipfs-Roots"} {
+		if !containsString(exposed, header) {
+			exposed = append(exposed, header)
+		}
+	}
+	conf.Gateway.HTTPHeaders["Access-Control-Expose-Headers"] = exposed
+	return nil
+}
+
+// ApplyGatewayDisableHTMLErrors Requests that the gateway returns plain errors instead of
+// HTML error pages, which confuse programmatic clients. Kubo v0.16 always renders HTML
+// errors for browsers and has no setting to turn them off, so enabling this returns
+// ErrUnsupportedOption.
+func ApplyGatewayDisableHTMLErrors(conf *config.Config, disable bool) error {
+	if !disable {
+		return nil
+	}
+	return fmt.Errorf("gateway DisableHTMLErrors: %w", ErrUnsupportedOption)
+}
+
+const (
+	// GatewayErrorFormatHTML Renders error pages for browsers, Kubo's default.
+	GatewayErrorFormatHTML = "html"
+	// GatewayErrorFormatPlain Returns errors as plain text, see ApplyGatewayDisableHTMLErrors.
+	GatewayErrorFormatPlain = "plain"
+	// GatewayErrorFormatJSON Returns errors as a JSON envelope with a stable schema, so that
+	// API clients can parse them.
+	GatewayErrorFormatJSON = "json"
+)
+
+// ApplyGatewayErrorFormat Selects the format the gateway returns errors in. Unlike merely disabling
+// HTML errors, the JSON format wraps every error into an envelope clients can parse. Plain errors
+// are requested through ApplyGatewayDisableHTMLErrors, whereas JSON envelopes are not supported by
+// Kubo v0.16 and return ErrUnsupportedOption. An empty format keeps HTML.
+func ApplyGatewayErrorFormat(conf *config.Config, format string) error {
+	switch format {
+	case "", GatewayErrorFormatHTML:
+		return nil
+	case GatewayErrorFormatPlain:
+		return ApplyGatewayDisableHTMLErrors(conf, true)
+	case GatewayErrorFormatJSON:
+		return fmt.Errorf("gateway JSON error envelope: %w", ErrUnsupportedOption)
+	default:
+		return fmt.Errorf("invalid gateway error format: %s", format)
+	}
+}
+
+// ApplyGatewayExposeRoutingAPI Requests that the gateway serves the delegated routing API
+// under `/routing/v1` for lightweight clients, which stays closed by default. Kubo v0.16
+// predates Gateway.ExposeRoutingAPI and its gateway has no `/routing/v1` handler to expose, so
+// exposing it returns ErrUnsupportedOption.
+func ApplyGatewayExposeRoutingAPI(conf *config.Config, expose bool) error {
+	if !expose {
+		return nil
+	}
+	return fmt.Errorf("gateway ExposeRoutingAPI: %w", ErrUnsupportedOption)
+}
+
+// PublicGatewayRestrictions Restricts what a public gateway serves for a single host.
+type PublicGatewayRestrictions struct {
+	// Paths Lists the path prefixes served for the host, e.g. `/ipfs`, leaving every other path
+	// unanswered. No paths keeps the ones Kubo serves by default.
+	Paths []string
+	// DeniedPaths Lists path prefixes which must not be served for the host.
+	DeniedPaths []string
+	// NoDNSLink Stops the gateway from resolving the DNSLink of the host.
+	NoDNSLink bool
+}
+
+// ApplyPublicGatewayRestrictions Sets the entries of Gateway.PublicGateways for the given hosts on
+// the given Kubo configuration, keeping the entries of other hosts, so that abused gateways only
+// serve the paths they are meant to. Kubo v0.16 only restricts gateways to a list of served paths,
+// so denying paths returns ErrUnsupportedOption, and the allowed paths must be listed instead.
+func ApplyPublicGatewayRestrictions(conf *config.Config, hosts map[string]PublicGatewayRestrictions) error {
+	rendered := make(map[string]*config.GatewaySpec, len(hosts))
+	for host, restrictions := range hosts {
+		if errs := validation.IsDNS1123Subdomain(host); len(errs) > 0 {
+			return fmt.Errorf("invalid public gateway host %q: %v", host, errs)
+		}
+		if len(restrictions.DeniedPaths) > 0 {
+			return fmt.Errorf("public gateway %q denied paths: %w", host, ErrUnsupportedOption)
+		}
+		for _, p := range restrictions.Paths {
+			if err := validateGatewayPath(p); err != nil {
+				return fmt.Errorf("invalid path for public gateway %q: %w", host, err)
+			}
+		}
+		spec := &config.GatewaySpec{
+			NoDNSLink: restrictions.NoDNSLink,
+		}
+		if len(restrictions.Paths) > 0 {
+			spec.Paths = append([]string{}, restrictions.Paths...)
+		}
+		rendered[host] = spec
+	}
+	if conf.Gateway.PublicGateways == nil {
+		conf.Gateway.PublicGateways = make(map[string]*config.GatewaySpec, len(rendered))
+	}
+	for host, spec := range rendered {
+		conf.Gateway.PublicGateways[host] = spec
+	}
+	return nil
+}
+
+// ApplyGatewayFastDirIndexThreshold Sets Gateway.FastDirIndexThreshold on the given Kubo
+// configuration, the number of entries beyond which directory listings skip reading the size of
+// each entry, which makes listing huge UnixFS directories fast. A threshold of 0 lists every
+// directory this way. A nil threshold uses DefaultFastDirIndexThreshold.
+func ApplyGatewayFastDirIndexThreshold(conf *config.Config, threshold *int64) error {
+	value := int64(DefaultFastDirIndexThreshold)
+	if threshold != nil {
+		value = *threshold
+	}
+	if value < 0 {
+		return fmt.Errorf("fast directory index threshold cannot be negative, got %d", value)
+	}
+	// Kubo has no constructor for optional integers, they are only ever decoded
+	fastDirIndexThreshold := &config.OptionalInteger{}
+	if err := fastDirIndexThreshold.UnmarshalJSON([]byte(strconv.FormatInt(value, 10))); err != nil {
+		return fmt.Errorf("could not set fast directory index threshold: %w", err)
+	}
+	conf.Gateway.FastDirIndexThreshold = fastDirIndexThreshold
+	return nil
+}
+
+// containsString Returns whether the given value is present in the list.
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,202 @@
+package scripts_test
+
+import (
+	"time"
+
+	"github.com/ipfs/kubo/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/redhat-et/ipfs-operator/controllers/scripts"
+)
+
+var _ = Describe("Gateway root redirect", func() {
+	var conf *config.Config
+
+	BeforeEach(func() {
+		conf = &config.Config{}
+	})
+
+	It("sets a valid redirect", func() {
+		const redirect = "/ipfs/bafybeigdyrzt5sfp7udm7hu76uh7y26nf3efuylqabf3oclgtqy55fbzdi/index.html"
+		Expect(scripts.ApplyGatewayRootRedirect(conf, redirect)).To(Succeed())
+		Expect(conf.Gateway.RootRedirect).To(Equal(redirect))
+	})
+
+	It("accepts a directory redirect", func() {
+		Expect(scripts.ApplyGatewayRootRedirect(conf, "/ipns/docs.ipfs.tech/")).To(Succeed())
+		Expect(conf.Gateway.RootRedirect).To(Equal("/ipns/docs.ipfs.tech/"))
+	})
+
+	It("clears the redirect when omitted", func() {
+		conf.Gateway.RootRedirect = "/ipfs/old"
+		Expect(scripts.ApplyGatewayRootRedirect(conf, "")).To(Succeed())
+		Expect(conf.Gateway.RootRedirect).To(BeEmpty())
+	})
+
+	It("rejects malformed redirects", func() {
+		for _, redirect := range []string{
+			"ipfs/bafy",
+			"https://example.com/ipfs/bafy",
+			"//example.com/ipfs/bafy",
+			"/ipfs/../etc/passwd",
+			"/ipfs//bafy",
+			"/ipfs/bafy?download=true",
+		} {
+			Expect(scripts.ApplyGatewayRootRedirect(conf, redirect)).NotTo(Succeed(), redirect)
+			Expect(conf.Gateway.RootRedirect).To(BeEmpty())
+		}
+	})
+})
+
+var _ = Describe("Gateway cache headers", func() {
+	var conf *config.Config
+
+	BeforeEach(func() {
+		conf = &config.Config{}
+	})
+
+	It("leaves caching to Kubo by default", func() {
+		Expect(scripts.ApplyGatewayCacheHeaders(conf, 0)).To(Succeed())
+		Expect(conf.Gateway.HTTPHeaders).NotTo(HaveKey("Cache-Control"))
+	})
+
+	It("reports that a custom max-age is unsupported", func() {
+		Expect(scripts.ApplyGatewayCacheHeaders(conf, time.Hour)).To(MatchError(scripts.ErrUnsupportedOption))
+		Expect(conf.Gateway.HTTPHeaders).NotTo(HaveKey("Cache-Control"))
+	})
+
+	It("exposes the ETag header while keeping existing headers", func() {
+		conf.Gateway.HTTPHeaders = map[string][]string{
+			"Access-Control-Allow-Origin":   {"*"},
+			"Access-Control-Expose-Headers": {"Location", "ETag"},
+		}
+		Expect(scripts.ApplyGatewayCacheHeaders(conf, 0)).To(Succeed())
+		Expect(scripts.ApplyGatewayCacheHeaders(conf, 0)).To(Succeed())
+		Expect(conf.Gateway.HTTPHeaders["Access-Control-Allow-Origin"]).To(Equal([]string{"*"}))
+		Expect(conf.Gateway.HTTPHeaders["Access-Control-Expose-Headers"]).To(Equal(
+			[]string{"Location", "ETag", "X-Ipfs-Path", "X-Ipfs-Roots"},
+		))
+	})
+
+	It("rejects a negative max-age", func() {
+		Expect(scripts.ApplyGatewayCacheHeaders(conf, -time.Second)).NotTo(Succeed())
+	})
+})
+
+var _ = Describe("Gateway HTML errors", func() {
+	It("leaves the config untouched when HTML errors are kept", func() {
+		conf := &config.Config{}
+		Expect(scripts.ApplyGatewayDisableHTMLErrors(conf, false)).To(Succeed())
+		Expect(conf).To(Equal(&config.Config{}))
+	})
+
+	It("reports that disabling HTML errors is unsupported", func() {
+		err := scripts.ApplyGatewayDisableHTMLErrors(&config.Config{}, true)
+		Expect(err).To(MatchError(scripts.ErrUnsupportedOption))
+	})
+})
+
+var _ = Describe("Gateway error format", func() {
+	It("keeps HTML errors by default", func() {
+		conf := &config.Config{}
+		Expect(scripts.ApplyGatewayErrorFormat(conf, "")).To(Succeed())
+		Expect(scripts.ApplyGatewayErrorFormat(conf, scripts.GatewayErrorFormatHTML)).To(Succeed())
+		Expect(conf).To(Equal(&config.Config{}))
+	})
+
+	It("reports that plain and JSON errors are unsupported", func() {
+		for _, format := range []string{scripts.GatewayErrorFormatPlain, scripts.GatewayErrorFormatJSON} {
+			conf := &config.Config{}
+			Expect(scripts.ApplyGatewayErrorFormat(conf, format)).To(MatchError(scripts.ErrUnsupportedOption), format)
+			Expect(conf).To(Equal(&config.Config{}))
+		}
+		err := scripts.ApplyGatewayErrorFormat(&config.Config{}, scripts.GatewayErrorFormatJSON)
+		Expect(err.Error()).To(ContainSubstring("JSON"))
+	})
+
+	It("rejects an unknown format", func() {
+		err := scripts.ApplyGatewayErrorFormat(&config.Config{}, "xml")
+		Expect(err).To(HaveOccurred())
+		Expect(err).NotTo(MatchError(scripts.ErrUnsupportedOption))
+	})
+})
+
+var _ = Describe("Gateway routing API", func() {
+	It("keeps the routing API closed by default", func() {
+		conf := &config.Config{}
+		Expect(scripts.ApplyGatewayExposeRoutingAPI(conf, false)).To(Succeed())
+		Expect(conf).To(Equal(&config.Config{}))
+	})
+
+	It("reports that exposing the routing API is unsupported", func() {
+		err := scripts.ApplyGatewayExposeRoutingAPI(&config.Config{}, true)
+		Expect(err).To(MatchError(scripts.ErrUnsupportedOption))
+	})
+})
+
+var _ = Describe("Public gateway restrictions", func() {
+	var conf *config.Config
+
+	BeforeEach(func() {
+		conf = &config.Config{}
+		conf.Gateway.PublicGateways = map[string]*config.GatewaySpec{
+			"other.example.com": {UseSubdomains: true},
+		}
+	})
+
+	It("renders the restrictions of each host", func() {
+		Expect(scripts.ApplyPublicGatewayRestrictions(conf, map[string]scripts.PublicGatewayRestrictions{
+			"ipfs.example.com":    {Paths: []string{"/ipfs"}, NoDNSLink: true},
+			"dnslink.example.com": {},
+		})).To(Succeed())
+		Expect(conf.Gateway.PublicGateways).To(HaveLen(3))
+		Expect(conf.Gateway.PublicGateways["ipfs.example.com"]).To(Equal(&config.GatewaySpec{
+			Paths:     []string{"/ipfs"},
+			NoDNSLink: true,
+		}))
+		Expect(conf.Gateway.PublicGateways["dnslink.example.com"]).To(Equal(&config.GatewaySpec{}))
+		Expect(conf.Gateway.PublicGateways["other.example.com"].UseSubdomains).To(BeTrue())
+	})
+
+	It("rejects invalid hosts and paths", func() {
+		Expect(scripts.ApplyPublicGatewayRestrictions(conf, map[string]scripts.PublicGatewayRestrictions{
+			"Not A Host": {},
+		})).NotTo(Succeed())
+		Expect(scripts.ApplyPublicGatewayRestrictions(conf, map[string]scripts.PublicGatewayRestrictions{
+			"ipfs.example.com": {Paths: []string{"ipfs"}},
+		})).NotTo(Succeed())
+		Expect(conf.Gateway.PublicGateways).To(HaveLen(1))
+	})
+
+	It("reports that denying paths is unsupported", func() {
+		err := scripts.ApplyPublicGatewayRestrictions(conf, map[string]scripts.PublicGatewayRestrictions{
+			"ipfs.example.com": {DeniedPaths: []string{"/ipns"}},
+		})
+		Expect(err).To(MatchError(scripts.ErrUnsupportedOption))
+	})
+})
+
+var _ = Describe("Gateway fast directory index threshold", func() {
+	It("renders the given threshold", func() {
+		conf := &config.Config{}
+		threshold := int64(5000)
+		Expect(scripts.ApplyGatewayFastDirIndexThreshold(conf, &threshold)).To(Succeed())
+		Expect(conf.Gateway.FastDirIndexThreshold.WithDefault(0)).To(BeEquivalentTo(5000))
+
+		threshold = 0
+		Expect(scripts.ApplyGatewayFastDirIndexThreshold(conf, &threshold)).To(Succeed())
+		Expect(conf.Gateway.FastDirIndexThreshold.IsDefault()).To(BeFalse())
+		Expect(conf.Gateway.FastDirIndexThreshold.WithDefault(1)).To(BeEquivalentTo(0))
+	})
+
+	It("defaults to Kubo's threshold", func() {
+		conf := &config.Config{}
+		Expect(scripts.ApplyGatewayFastDirIndexThreshold(conf, nil)).To(Succeed())
+		Expect(conf.Gateway.FastDirIndexThreshold.WithDefault(0)).To(BeEquivalentTo(scripts.DefaultFastDirIndexThreshold))
+	})
+
+	It("rejects a negative threshold", func() {
+		threshold := int64(-1)
+		Expect(scripts.ApplyGatewayFastDirIndexThreshold(&config.Config{}, &threshold)).NotTo(Succeed())
+	})
+})
@@ -0,0 +1,140 @@
+package scripts
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ipfs/kubo/config"
+)
+
+const (
+	// DefaultGCPeriod Is Kubo's default interval between garbage collections.
+	DefaultGCPeriod = time.Hour
+	// MinGCPeriod Bounds how often garbage collection may run, since each run walks the pinset.
+	MinGCPeriod = 5 * time.Minute
+	// defaultGCWatermark Is Kubo's default StorageGCWatermark, in percent of StorageMax.
+	defaultGCWatermark = 90
+	// gcRunsPerHeadroom Is how many garbage collections should fit into the time it takes to
+	// fill the headroom above the watermark, so a single late run doesn't let the repo fill.
+	gcRunsPerHeadroom = 2
+)
+
+// GCPeriodForFillRate Returns the interval between garbage collections which lets the collector
+// run before a datastore filling at fillRate bytes per hour grows from the watermark, given in
+// percent of storageMax, until it hits storageMax and stops accepting blocks. The period never
+// exceeds DefaultGCPeriod nor drops below MinGCPeriod. A non-positive fill rate keeps the default.
+func GCPeriodForFillRate(storageMax int64, watermark int64, fillRate int64) (time.Duration, error) {
+	if storageMax <= 0 {
+		return 0, fmt.Errorf("storage max must be positive, got %d", storageMax)
+	}
+	if watermark == 0 {
+		watermark = defaultGCWatermark
+	}
+	if watermark < 0 || watermark >= 100 {
+		return 0, fmt.Errorf("gc watermark must be between 0 and 100, got %d", watermark)
+	}
+	if fillRate <= 0 {
+		return DefaultGCPeriod, nil
+	}
+	headroom := float64(storageMax) * float64(100-watermark) / 100
+	fillTime := time.Duration(headroom / float64(fillRate) * float64(time.Hour))
+	period := fillTime / gcRunsPerHeadroom
+	switch {
+	case period > DefaultGCPeriod:
+		return DefaultGCPeriod, nil
+	case period < MinGCPeriod:
+		return MinGCPeriod, nil
+	default:
+		return period.Truncate(time.Minute), nil
+	}
+}
+
+// ApplyGCPeriod Sets Datastore.GCPeriod on the given Kubo configuration for a datastore of
+// storageMax bytes which fills at fillRate bytes per hour, using the configured watermark.
+func ApplyGCPeriod(conf *config.Config, storageMax int64, fillRate int64) error {
+	period, err := GCPeriodForFillRate(storageMax, conf.Datastore.StorageGCWatermark, fillRate)
+	if err != nil {
+		return err
+	}
+	conf.Datastore.GCPeriod = period.String()
+	return nil
+}
+
+// ApplyDatastoreGC Sets Datastore.GCPeriod and Datastore.StorageGCWatermark on the given Kubo
+// configuration, which drive the garbage collection of unpinned blocks from the IPFS datastore.
+// It leaves the badger store of the IPFS Cluster consensus state alone, which ClusterBadgerGCEnvs
+// tunes separately. A zero period or watermark keeps the Kubo default.
+func ApplyDatastoreGC(conf *config.Config, period time.Duration, watermark int64) error {
+	if period != 0 && period < MinGCPeriod {
+		return fmt.Errorf("gc period must be at least %s, got %s", MinGCPeriod, period)
+	}
+	if watermark < 0 || watermark >= 100 {
+		return fmt.Errorf("gc watermark must be between 0 and 100, got %d", watermark)
+	}
+	if period == 0 {
+		period = DefaultGCPeriod
+	}
+	if watermark == 0 {
+		watermark = defaultGCWatermark
+	}
+	conf.Datastore.GCPeriod = period.String()
+	conf.Datastore.StorageGCWatermark = watermark
+	return nil
+}
+
+// gcWatermarkStages Tightens the GC watermark as the repo fills, in percent of StorageMax. Each
+// watermark lies below the upper bound of its stage, and past the first stage at or below its lower
+// bound, so that every stage triggers a collection and a fuller repo collects down to a lower
+// watermark, keeping enough headroom for a burst of writes before the next collection.
+var gcWatermarkStages = []struct {
+	below     int64
+	watermark int64
+}{
+	{below: 85, watermark: 80},
+	{below: 95, watermark: 75},
+}
+
+const (
+	// nearFullGCWatermark Is the watermark of repos filled beyond every stage.
+	nearFullGCWatermark = 70
+	// gcHysteresis Is how far, in percent of StorageMax, utilization must drop below the watermark
+	// before garbage collection stops, since GC frees little when run right at the watermark.
+	gcHysteresis = 10
+)
+
+// GCDecision Is whether garbage collection should be triggered for a repo, along with the watermark
+// the decision was made against, in percent of StorageMax.
+type GCDecision struct {
+	Watermark int64
+	Collect   bool
+}
+
+// StagedGCDecision Returns the GC decision for a repo at the given utilization in percent of
+// StorageMax, given the previous decision across reconciles. The watermark tightens as utilization
+// climbs, and garbage collection is triggered once utilization reaches it. Once triggered, it keeps
+// running until utilization drops gcHysteresis points below the watermark, rather than flapping.
+func StagedGCDecision(utilization int64, previous GCDecision) (GCDecision, error) {
+	if utilization < 0 || utilization > 100 {
+		return GCDecision{}, fmt.Errorf("utilization must be between 0 and 100, got %d", utilization)
+	}
+	decision := GCDecision{Watermark: nearFullGCWatermark}
+	for _, stage := range gcWatermarkStages {
+		if utilization < stage.below {
+			decision.Watermark = stage.watermark
+			break
+		}
+	}
+	switch {
+	case utilization >= decision.Watermark:
+		decision.Collect = true
+	case previous.Collect:
+		decision.Collect = utilization > decision.Watermark-gcHysteresis
+	}
+	return decision, nil
+}
+
+// ApplyGCDecision Sets Datastore.StorageGCWatermark on the given Kubo configuration to the
+// watermark of the given decision.
+func ApplyGCDecision(conf *config.Config, decision GCDecision) {
+	conf.Datastore.StorageGCWatermark = decision.Watermark
+}
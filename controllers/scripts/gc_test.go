@@ -0,0 +1,131 @@
+package scripts_test
+
+import (
+	"time"
+
+	"github.com/ipfs/kubo/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/redhat-et/ipfs-operator/controllers/scripts"
+)
+
+var _ = Describe("GC period", func() {
+	const storageMax = 100 << 30
+
+	It("is shorter for higher fill rates", func() {
+		slow, err := scripts.GCPeriodForFillRate(storageMax, 90, 8<<30)
+		Expect(err).NotTo(HaveOccurred())
+		fast, err := scripts.GCPeriodForFillRate(storageMax, 90, 40<<30)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fast).To(BeNumerically("<", slow))
+		Expect(slow).To(BeNumerically("<", scripts.DefaultGCPeriod))
+	})
+
+	It("runs before the headroom fills", func() {
+		period, err := scripts.GCPeriodForFillRate(storageMax, 90, 10<<30)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(period).To(Equal(30 * time.Minute))
+	})
+
+	It("is bounded", func() {
+		period, err := scripts.GCPeriodForFillRate(storageMax, 90, 1<<20)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(period).To(Equal(scripts.DefaultGCPeriod))
+		period, err = scripts.GCPeriodForFillRate(storageMax, 90, 1<<40)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(period).To(Equal(scripts.MinGCPeriod))
+		period, err = scripts.GCPeriodForFillRate(storageMax, 90, 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(period).To(Equal(scripts.DefaultGCPeriod))
+	})
+
+	It("uses the configured watermark", func() {
+		conf := &config.Config{}
+		conf.Datastore.StorageGCWatermark = 80
+		Expect(scripts.ApplyGCPeriod(conf, storageMax, 10<<30)).To(Succeed())
+		Expect(conf.Datastore.GCPeriod).To(Equal("1h0m0s"))
+
+		conf.Datastore.StorageGCWatermark = 100
+		Expect(scripts.ApplyGCPeriod(conf, storageMax, 10<<30)).NotTo(Succeed())
+	})
+})
+
+var _ = Describe("Staged GC watermark", func() {
+	decide := func(utilization int64, previous scripts.GCDecision) scripts.GCDecision {
+		decision, err := scripts.StagedGCDecision(utilization, previous)
+		Expect(err).NotTo(HaveOccurred())
+		return decision
+	}
+
+	It("leaves a mostly empty repo alone", func() {
+		Expect(decide(40, scripts.GCDecision{})).To(Equal(scripts.GCDecision{Watermark: 80}))
+	})
+
+	It("collects once a stage's watermark is reached", func() {
+		Expect(decide(79, scripts.GCDecision{})).To(Equal(scripts.GCDecision{Watermark: 80}))
+		Expect(decide(82, scripts.GCDecision{})).To(Equal(scripts.GCDecision{Watermark: 80, Collect: true}))
+		Expect(decide(90, scripts.GCDecision{})).To(Equal(scripts.GCDecision{Watermark: 75, Collect: true}))
+	})
+
+	It("collects a nearly full repo", func() {
+		decision := decide(96, scripts.GCDecision{})
+		Expect(decision).To(Equal(scripts.GCDecision{Watermark: 70, Collect: true}))
+		conf := &config.Config{}
+		scripts.ApplyGCDecision(conf, decision)
+		Expect(conf.Datastore.StorageGCWatermark).To(BeEquivalentTo(70))
+	})
+
+	It("keeps collecting until utilization drops below the hysteresis", func() {
+		collecting := scripts.GCDecision{Watermark: 80, Collect: true}
+		Expect(decide(75, collecting).Collect).To(BeTrue())
+		Expect(decide(75, scripts.GCDecision{}).Collect).To(BeFalse())
+		Expect(decide(69, collecting).Collect).To(BeFalse())
+	})
+
+	It("rejects invalid utilization", func() {
+		_, err := scripts.StagedGCDecision(101, scripts.GCDecision{})
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Separate datastore and cluster state GC", func() {
+	It("renders both GC configs independently", func() {
+		conf := &config.Config{}
+		Expect(scripts.ApplyDatastoreGC(conf, 2*time.Hour, 80)).To(Succeed())
+		envs, err := scripts.ClusterBadgerGCEnvs(scripts.ClusterBadgerGC{
+			Interval:     10 * time.Minute,
+			DiscardRatio: 0.3,
+			Sleep:        5 * time.Second,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(conf.Datastore.GCPeriod).To(Equal("2h0m0s"))
+		Expect(conf.Datastore.StorageGCWatermark).To(BeEquivalentTo(80))
+		Expect(envs).To(Equal([]corev1.EnvVar{
+			{Name: scripts.EnvClusterBadgerGCInterval, Value: "10m0s"},
+			{Name: scripts.EnvClusterBadgerGCDiscardRatio, Value: "0.3"},
+			{Name: scripts.EnvClusterBadgerGCSleep, Value: "5s"},
+		}))
+	})
+
+	It("keeps the datastore GC when only the cluster state GC is tuned", func() {
+		conf := &config.Config{}
+		Expect(scripts.ApplyDatastoreGC(conf, 0, 0)).To(Succeed())
+		envs, err := scripts.ClusterBadgerGCEnvs(scripts.ClusterBadgerGC{Interval: time.Minute})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(envs).To(HaveLen(1))
+		Expect(conf.Datastore.GCPeriod).To(Equal(scripts.DefaultGCPeriod.String()))
+		Expect(conf.Datastore.StorageGCWatermark).To(BeEquivalentTo(90))
+	})
+
+	It("rejects invalid settings", func() {
+		Expect(scripts.ApplyDatastoreGC(&config.Config{}, time.Minute, 80)).NotTo(Succeed())
+		Expect(scripts.ApplyDatastoreGC(&config.Config{}, time.Hour, 100)).NotTo(Succeed())
+		_, err := scripts.ClusterBadgerGCEnvs(scripts.ClusterBadgerGC{DiscardRatio: 1})
+		Expect(err).To(HaveOccurred())
+		_, err = scripts.ClusterBadgerGCEnvs(scripts.ClusterBadgerGC{Interval: -time.Second})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,78 @@
+package scripts
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// chunkerPattern Matches the chunker specs accepted by `ipfs add --chunker`.
+var chunkerPattern = regexp.MustCompile(`^(size-[1-9][0-9]*|rabin(-[1-9][0-9]*){0,3}|buzhash)$`)
+
+// ImportOptions Describes how content added through the node is encoded into UnixFS DAGs.
+type ImportOptions struct {
+	// CIDVersion Is the CID version newly added content is addressed under.
+	CIDVersion int
+	// RawLeaves Stores file data in raw leaf blocks instead of wrapping it in UnixFS nodes.
+	RawLeaves bool
+	// Chunker Is the chunking strategy, e.g. `size-262144` or `rabin`.
+	Chunker string
+}
+
+// DefaultImportOptions Are the import settings Kubo v0.16 always adds content with
+// unless the client overrides them per request.
+var DefaultImportOptions = ImportOptions{
+	CIDVersion: 0,
+	RawLeaves:  false,
+	Chunker:    "size-262144",
+}
+
+// ModernImportOptions Are the import settings to opt into once Kubo supports them, which address
+// content as CIDv1 with raw leaves so that identical files dedupe regardless of how they were added.
+var ModernImportOptions = ImportOptions{
+	CIDVersion: 1,
+	RawLeaves:  true,
+	Chunker:    "size-1048576",
+}
+
+// ImportOptionsFromSpec Returns the import options for the given settings, falling back
+// to DefaultImportOptions for each one which is unset.
+func ImportOptionsFromSpec(cidVersion *int, rawLeaves *bool, chunker string) (ImportOptions, error) {
+	opts := DefaultImportOptions
+	if cidVersion != nil {
+		opts.CIDVersion = *cidVersion
+	}
+	if rawLeaves != nil {
+		opts.RawLeaves = *rawLeaves
+	}
+	if chunker != "" {
+		opts.Chunker = chunker
+	}
+	return opts, opts.Validate()
+}
+
+// Validate Ensures the import options form a combination Kubo can add content with.
+func (o ImportOptions) Validate() error {
+	if o.CIDVersion != 0 && o.CIDVersion != 1 {
+		return fmt.Errorf("invalid cid version %d, must be 0 or 1", o.CIDVersion)
+	}
+	if o.CIDVersion == 0 && o.RawLeaves {
+		return fmt.Errorf("raw leaves require cid version 1")
+	}
+	if !chunkerPattern.MatchString(o.Chunker) {
+		return fmt.Errorf("invalid chunker %q", o.Chunker)
+	}
+	return nil
+}
+
+// ApplyImportOptions Sets the Import section of the Kubo configuration from the given options.
+// Kubo v0.16 has no Import section and always adds content with DefaultImportOptions, so any
+// other options return ErrUnsupportedOption; clients have to pass them on each `ipfs add` instead.
+func ApplyImportOptions(opts ImportOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	if opts != DefaultImportOptions {
+		return fmt.Errorf("import options: %w", ErrUnsupportedOption)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package scripts_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/ipfs-operator/controllers/scripts"
+)
+
+var _ = Describe("Import options", func() {
+	It("defaults to the settings built into Kubo", func() {
+		opts, err := scripts.ImportOptionsFromSpec(nil, nil, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(opts).To(Equal(scripts.DefaultImportOptions))
+		Expect(opts.CIDVersion).To(Equal(0))
+		Expect(opts.RawLeaves).To(BeFalse())
+		Expect(opts.Chunker).To(Equal("size-262144"))
+		Expect(scripts.ApplyImportOptions(opts)).To(Succeed())
+	})
+
+	It("overrides each setting from the spec", func() {
+		cidVersion := 1
+		rawLeaves := true
+		opts, err := scripts.ImportOptionsFromSpec(&cidVersion, &rawLeaves, "rabin-262144-524288-1048576")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(opts).To(Equal(scripts.ImportOptions{
+			CIDVersion: 1,
+			RawLeaves:  true,
+			Chunker:    "rabin-262144-524288-1048576",
+		}))
+	})
+
+	It("rejects invalid settings", func() {
+		cidVersion := 2
+		_, err := scripts.ImportOptionsFromSpec(&cidVersion, nil, "")
+		Expect(err).To(HaveOccurred())
+
+		rawLeaves := true
+		_, err = scripts.ImportOptionsFromSpec(nil, &rawLeaves, "")
+		Expect(err).To(HaveOccurred())
+
+		_, err = scripts.ImportOptionsFromSpec(nil, nil, "size-0")
+		Expect(err).To(HaveOccurred())
+		_, err = scripts.ImportOptionsFromSpec(nil, nil, "fixed")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("reports the modern settings as unsupported when requested", func() {
+		Expect(scripts.ApplyImportOptions(scripts.ModernImportOptions)).To(MatchError(scripts.ErrUnsupportedOption))
+
+		cidVersion := 1
+		opts, err := scripts.ImportOptionsFromSpec(&cidVersion, nil, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(scripts.ApplyImportOptions(opts)).To(MatchError(scripts.ErrUnsupportedOption))
+	})
+})
@@ -0,0 +1,30 @@
+package scripts
+
+import (
+	"github.com/ipfs/kubo/config"
+)
+
+const (
+	// RoutingTypeDHTClient Queries the DHT without serving records to other peers.
+	RoutingTypeDHTClient = "dhtclient"
+	// RoutingTypeNone Disables content routing, for nodes which never look content up.
+	RoutingTypeNone = "none"
+)
+
+// ApplyJobProfile Configures the given Kubo configuration for one-shot pods, which add content
+// and exit before the network would ever benefit from what they serve. Connection management,
+// reproviding, the AutoNAT and relay services and mDNS discovery are disabled, and the DHT is
+// only queried as a client, or not at all when offline is set.
+func ApplyJobProfile(conf *config.Config, offline bool) {
+	conf.Swarm.ConnMgr = config.ConnMgr{
+		Type: ConnMgrTypeNone,
+	}
+	conf.Routing.Type = RoutingTypeDHTClient
+	if offline {
+		conf.Routing.Type = RoutingTypeNone
+	}
+	conf.Reprovider.Interval = "0"
+	conf.AutoNAT.ServiceMode = config.AutoNATServiceDisabled
+	conf.Swarm.RelayService.Enabled = config.False
+	conf.Discovery.MDNS.Enabled = false
+}
@@ -0,0 +1,43 @@
+package scripts_test
+
+import (
+	"github.com/ipfs/kubo/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/ipfs-operator/controllers/scripts"
+)
+
+var _ = Describe("Job profile", func() {
+	var conf *config.Config
+
+	BeforeEach(func() {
+		conf = &config.Config{
+			Swarm: config.SwarmConfig{
+				ConnMgr: config.ConnMgr{
+					Type:      scripts.ConnMgrTypeBasic,
+					LowWater:  600,
+					HighWater: 2000,
+				},
+			},
+			Routing:    config.Routing{Type: "dht"},
+			Reprovider: config.Reprovider{Interval: "12h"},
+			Discovery:  config.Discovery{MDNS: config.MDNS{Enabled: true}},
+		}
+	})
+
+	It("disables the heavy subsystems", func() {
+		scripts.ApplyJobProfile(conf, false)
+		Expect(conf.Swarm.ConnMgr).To(Equal(config.ConnMgr{Type: scripts.ConnMgrTypeNone}))
+		Expect(conf.Routing.Type).To(Equal(scripts.RoutingTypeDHTClient))
+		Expect(conf.Reprovider.Interval).To(Equal("0"))
+		Expect(conf.AutoNAT.ServiceMode).To(Equal(config.AutoNATServiceDisabled))
+		Expect(conf.Swarm.RelayService.Enabled.WithDefault(true)).To(BeFalse())
+		Expect(conf.Discovery.MDNS.Enabled).To(BeFalse())
+	})
+
+	It("disables routing entirely when offline", func() {
+		scripts.ApplyJobProfile(conf, true)
+		Expect(conf.Routing.Type).To(Equal(scripts.RoutingTypeNone))
+	})
+})
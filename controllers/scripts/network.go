@@ -0,0 +1,231 @@
+package scripts
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/ipfs/kubo/config"
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// DefaultWSSPort Is the port browsers reach the ingress on for secure websockets.
+const DefaultWSSPort = 443
+
+const (
+	// PubsubRouterGossipsub Meshes messages through a subset of the peers and is
+	// backwards compatible with floodsub.
+	PubsubRouterGossipsub = "gossipsub"
+	// PubsubRouterFloodsub Is the legacy router flooding every message to all peers.
+	PubsubRouterFloodsub = "floodsub"
+)
+
+// ApplyAutoNATServiceMode Sets AutoNAT.ServiceMode on the given Kubo configuration.
+// The mode may be either "enabled" or "disabled". When no mode is provided, nodes
+// which are publicly reachable will offer the AutoNAT service, whereas nodes behind
+// a NAT will only consume it.
+func ApplyAutoNATServiceMode(conf *config.Config, mode string, publiclyReachable bool) error {
+	if mode == "" {
+		if publiclyReachable {
+			conf.AutoNAT.ServiceMode = config.AutoNATServiceEnabled
+		} else {
+			conf.AutoNAT.ServiceMode = config.AutoNATServiceDisabled
+		}
+		return nil
+	}
+	var serviceMode config.AutoNATServiceMode
+	if err := serviceMode.UnmarshalText([]byte(mode)); err != nil {
+		return fmt.Errorf("invalid autonat service mode: %w", err)
+	}
+	conf.AutoNAT.ServiceMode = serviceMode
+	return nil
+}
+
+// WSSAnnounceAddr Returns the secure websocket address `/dns4/<host>/tcp/<port>/wss/p2p/<id>`
+// under which browser peers reach the given peer through an Ingress or LoadBalancer
+// terminating TLS for the host. A zero port uses DefaultWSSPort.
+func WSSAnnounceAddr(host string, port int, id peer.ID) (ma.Multiaddr, error) {
+	if errs := validation.IsDNS1123Subdomain(host); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid ingress host %q: %v", host, errs)
+	}
+	if port == 0 {
+		port = DefaultWSSPort
+	}
+	if port < 0 || port > 65535 {
+		return nil, fmt.Errorf("invalid ingress port: %d", port)
+	}
+	if err := id.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid peer id: %w", err)
+	}
+	return ma.NewMultiaddr(fmt.Sprintf("/dns4/%s/tcp/%d/wss/p2p/%s", host, port, id))
+}
+
+// ApplyWSSAnnounce Adds the secure websocket address of the given peer behind the ingress
+// host to Addresses.AppendAnnounce on the given Kubo configuration, keeping the addresses
+// announced by default.
+func ApplyWSSAnnounce(conf *config.Config, host string, port int, id peer.ID) error {
+	addr, err := WSSAnnounceAddr(host, port, id)
+	if err != nil {
+		return err
+	}
+	for _, existing := range conf.Addresses.AppendAnnounce {
+		if existing == addr.String() {
+			return nil
+		}
+	}
+	conf.Addresses.AppendAnnounce = append(conf.Addresses.AppendAnnounce, addr.String())
+	return nil
+}
+
+// AnnounceSources Are the addresses a peer can be reached at, by where they come from.
+type AnnounceSources struct {
+	// Public Are the public IP addresses of the peer, e.g. of its LoadBalancer.
+	Public []string
+	// DNS Are the addresses resolved through DNS, e.g. of the ingress host.
+	DNS []string
+	// Relay Are the circuit addresses of the relays the peer holds a reservation on.
+	Relay []string
+}
+
+// AnnounceAddrs Assembles the addresses the given peer announces from its sources. Public addresses
+// come first since they are dialed directly, then DNS addresses, and relay addresses last since
+// relayed connections are the slowest; each source keeps its order. A trailing `/p2p/<id>` of the
+// peer itself is dropped, so that the same address given with and without it is announced once.
+// Addresses which don't match their source, or which name another peer, are rejected, since
+// dialers would fail on them.
+func AnnounceAddrs(id peer.ID, sources AnnounceSources) ([]string, error) {
+	if err := id.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid peer id: %w", err)
+	}
+	isPublic := func(addr ma.Multiaddr) bool {
+		return manet.IsPublicAddr(addr) && !isCircuitAddr(addr) && !isDNSAddr(addr)
+	}
+	isDNS := func(addr ma.Multiaddr) bool {
+		return isDNSAddr(addr) && !isCircuitAddr(addr)
+	}
+	groups := []struct {
+		source string
+		addrs  []string
+		valid  func(ma.Multiaddr) bool
+	}{
+		{source: "public", addrs: sources.Public, valid: isPublic},
+		{source: "dns", addrs: sources.DNS, valid: isDNS},
+		{source: "relay", addrs: sources.Relay, valid: isCircuitAddr},
+	}
+	seen := make(map[string]bool)
+	announce := make([]string, 0)
+	for _, group := range groups {
+		for _, raw := range group.addrs {
+			addr, err := ma.NewMultiaddr(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s address %q: %w", group.source, raw, err)
+			}
+			if rest, last := ma.SplitLast(addr); last != nil && last.Protocol().Code == ma.P_P2P {
+				if last.Value() != id.String() {
+					return nil, fmt.Errorf("%s address %q names another peer than %s", group.source, raw, id)
+				}
+				addr = rest
+			}
+			if addr == nil || !group.valid(addr) {
+				return nil, fmt.Errorf("%q is not a %s address", raw, group.source)
+			}
+			if seen[addr.String()] {
+				continue
+			}
+			seen[addr.String()] = true
+			announce = append(announce, addr.String())
+		}
+	}
+	return announce, nil
+}
+
+// ApplyAnnounceAddrs Sets Addresses.Announce on the given Kubo configuration to the addresses
+// assembled from the sources of the given peer, replacing the addresses it would detect itself.
+// Without any address the detected ones are announced.
+func ApplyAnnounceAddrs(conf *config.Config, id peer.ID, sources AnnounceSources) error {
+	announce, err := AnnounceAddrs(id, sources)
+	if err != nil {
+		return err
+	}
+	if len(announce) == 0 {
+		announce = nil
+	}
+	conf.Addresses.Announce = announce
+	return nil
+}
+
+// isCircuitAddr Returns whether the address dials the peer through a circuit relay.
+func isCircuitAddr(addr ma.Multiaddr) bool {
+	_, last := ma.SplitLast(addr)
+	return last != nil && last.Protocol().Code == ma.P_CIRCUIT
+}
+
+// isDNSAddr Returns whether the address starts with a name to be resolved through DNS.
+func isDNSAddr(addr ma.Multiaddr) bool {
+	first, _ := ma.SplitFirst(addr)
+	if first == nil {
+		return false
+	}
+	switch first.Protocol().Code {
+	case ma.P_DNS, ma.P_DNS4, ma.P_DNS6, ma.P_DNSADDR:
+		return true
+	}
+	return false
+}
+
+// ApplyPubsub Sets Pubsub.Enabled and Pubsub.Router on the given Kubo configuration so that
+// applications can message each other through the nodes. An empty router uses gossipsub.
+// When pubsub is disabled the router is cleared, since it would have no effect.
+func ApplyPubsub(conf *config.Config, enabled bool, router string) error {
+	if !enabled {
+		conf.Pubsub.Enabled = config.False
+		conf.Pubsub.Router = ""
+		return nil
+	}
+	switch router {
+	case "":
+		router = PubsubRouterGossipsub
+	case PubsubRouterGossipsub, PubsubRouterFloodsub:
+	default:
+		return fmt.Errorf("invalid pubsub router: %s", router)
+	}
+	conf.Pubsub.Enabled = config.True
+	conf.Pubsub.Router = router
+	return nil
+}
+
+// ApplyDNSResolvers Sets DNS.Resolvers on the given Kubo configuration, so that DNSLink names
+// under each domain are resolved through the given DNS-over-HTTPS endpoint, e.g. a private zone
+// `corp.example.` mapped onto `https://doh.corp.example/dns-query`. The domain `.` overrides the
+// resolver for every name. Domains are made fully qualified, and endpoints must be https URLs.
+func ApplyDNSResolvers(conf *config.Config, resolvers map[string]string) error {
+	if len(resolvers) == 0 {
+		conf.DNS.Resolvers = nil
+		return nil
+	}
+	rendered := make(map[string]string, len(resolvers))
+	for domain, endpoint := range resolvers {
+		fqdn := domain
+		if !strings.HasSuffix(fqdn, ".") {
+			fqdn += "."
+		}
+		if fqdn != "." {
+			if errs := validation.IsDNS1123Subdomain(strings.TrimSuffix(fqdn, ".")); len(errs) > 0 {
+				return fmt.Errorf("invalid resolver domain %q: %v", domain, errs)
+			}
+		}
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("invalid DoH resolver %q for %q: must be an https URL", endpoint, domain)
+		}
+		if _, ok := rendered[fqdn]; ok {
+			return fmt.Errorf("duplicate resolver for domain %q", fqdn)
+		}
+		rendered[fqdn] = endpoint
+	}
+	conf.DNS.Resolvers = rendered
+	return nil
+}
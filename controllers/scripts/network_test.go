@@ -0,0 +1,226 @@
+package scripts_test
+
+import (
+	"github.com/ipfs/kubo/config"
+	"github.com/libp2p/go-libp2p/core/peer"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/redhat-et/ipfs-operator/controllers/scripts"
+)
+
+var _ = Describe("AutoNAT", func() {
+	var conf *config.Config
+
+	BeforeEach(func() {
+		conf = &config.Config{}
+	})
+
+	When("a service mode is provided", func() {
+		It("enables the service", func() {
+			Expect(scripts.ApplyAutoNATServiceMode(conf, "enabled", false)).To(Succeed())
+			Expect(conf.AutoNAT.ServiceMode).To(Equal(config.AutoNATServiceEnabled))
+		})
+
+		It("disables the service", func() {
+			Expect(scripts.ApplyAutoNATServiceMode(conf, "disabled", true)).To(Succeed())
+			Expect(conf.AutoNAT.ServiceMode).To(Equal(config.AutoNATServiceDisabled))
+		})
+
+		It("rejects an unknown mode", func() {
+			Expect(scripts.ApplyAutoNATServiceMode(conf, "sometimes", true)).NotTo(Succeed())
+			Expect(conf.AutoNAT.ServiceMode).To(Equal(config.AutoNATServiceUnset))
+		})
+	})
+
+	When("the service mode is omitted", func() {
+		It("offers the service on publicly reachable nodes", func() {
+			Expect(scripts.ApplyAutoNATServiceMode(conf, "", true)).To(Succeed())
+			Expect(conf.AutoNAT.ServiceMode).To(Equal(config.AutoNATServiceEnabled))
+		})
+
+		It("only consumes the service behind a NAT", func() {
+			Expect(scripts.ApplyAutoNATServiceMode(conf, "", false)).To(Succeed())
+			Expect(conf.AutoNAT.ServiceMode).To(Equal(config.AutoNATServiceDisabled))
+		})
+	})
+})
+
+var _ = Describe("Secure websocket announce address", func() {
+	var id peer.ID
+
+	BeforeEach(func() {
+		var err error
+		id, err = peer.Decode("12D3KooWSWJeZsAHyUdcWbnoR2hjFzB7NwEFBLAy1MbYeJQ4WXio")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("renders the wss multiaddr of the ingress", func() {
+		addr, err := scripts.WSSAnnounceAddr("ipfs.example.com", 0, id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(addr.String()).To(Equal("/dns4/ipfs.example.com/tcp/443/wss/p2p/" + id.String()))
+	})
+
+	It("embeds the peer ID", func() {
+		addr, err := scripts.WSSAnnounceAddr("ipfs.example.com", 8443, id)
+		Expect(err).NotTo(HaveOccurred())
+		info, err := peer.AddrInfoFromP2pAddr(addr)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.ID).To(Equal(id))
+		Expect(info.Addrs[0].String()).To(Equal("/dns4/ipfs.example.com/tcp/8443/wss"))
+	})
+
+	It("appends the address to the announced addresses once", func() {
+		conf := &config.Config{}
+		conf.Addresses.AppendAnnounce = []string{"/ip4/1.2.3.4/tcp/4001"}
+		Expect(scripts.ApplyWSSAnnounce(conf, "ipfs.example.com", 0, id)).To(Succeed())
+		Expect(scripts.ApplyWSSAnnounce(conf, "ipfs.example.com", 0, id)).To(Succeed())
+		Expect(conf.Addresses.AppendAnnounce).To(Equal([]string{
+			"/ip4/1.2.3.4/tcp/4001",
+			"/dns4/ipfs.example.com/tcp/443/wss/p2p/" + id.String(),
+		}))
+	})
+
+	It("rejects invalid hosts and peer IDs", func() {
+		_, err := scripts.WSSAnnounceAddr("https://ipfs.example.com", 0, id)
+		Expect(err).To(HaveOccurred())
+		_, err = scripts.WSSAnnounceAddr("ipfs.example.com", 0, peer.ID(""))
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Announce addresses", func() {
+	const (
+		self  = "12D3KooWSWJeZsAHyUdcWbnoR2hjFzB7NwEFBLAy1MbYeJQ4WXio"
+		relay = "12D3KooWRBhwfeP2Y4TCx1SM6s9rUoHhR5STiGwxBhgFRcw3UERE"
+	)
+	var id peer.ID
+
+	BeforeEach(func() {
+		var err error
+		id, err = peer.Decode(self)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("deduplicates addresses across overlapping sources", func() {
+		addrs, err := scripts.AnnounceAddrs(id, scripts.AnnounceSources{
+			Public: []string{"/ip4/1.2.3.4/tcp/4001", "/ip4/1.2.3.4/tcp/4001/p2p/" + self},
+			DNS:    []string{"/dns4/ipfs.example.com/tcp/443/wss", "/dns4/ipfs.example.com/tcp/443/wss/p2p/" + self},
+			Relay: []string{
+				"/ip4/5.6.7.8/tcp/4001/p2p/" + relay + "/p2p-circuit",
+				"/ip4/5.6.7.8/tcp/4001/p2p/" + relay + "/p2p-circuit/p2p/" + self,
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(addrs).To(Equal([]string{
+			"/ip4/1.2.3.4/tcp/4001",
+			"/dns4/ipfs.example.com/tcp/443/wss",
+			"/ip4/5.6.7.8/tcp/4001/p2p/" + relay + "/p2p-circuit",
+		}))
+	})
+
+	It("orders public, then DNS, then relay addresses, keeping the order of each source", func() {
+		sources := scripts.AnnounceSources{
+			Relay:  []string{"/ip4/5.6.7.8/tcp/4001/p2p/" + relay + "/p2p-circuit"},
+			DNS:    []string{"/dns4/b.example.com/tcp/4001", "/dns4/a.example.com/tcp/4001"},
+			Public: []string{"/ip4/9.9.9.9/udp/4001/quic", "/ip4/1.2.3.4/tcp/4001"},
+		}
+		expected := []string{
+			"/ip4/9.9.9.9/udp/4001/quic",
+			"/ip4/1.2.3.4/tcp/4001",
+			"/dns4/b.example.com/tcp/4001",
+			"/dns4/a.example.com/tcp/4001",
+			"/ip4/5.6.7.8/tcp/4001/p2p/" + relay + "/p2p-circuit",
+		}
+		for i := 0; i < 3; i++ {
+			addrs, err := scripts.AnnounceAddrs(id, sources)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(addrs).To(Equal(expected))
+		}
+
+		conf := &config.Config{}
+		Expect(scripts.ApplyAnnounceAddrs(conf, id, sources)).To(Succeed())
+		Expect(conf.Addresses.Announce).To(Equal(expected))
+		Expect(scripts.ApplyAnnounceAddrs(conf, id, scripts.AnnounceSources{})).To(Succeed())
+		Expect(conf.Addresses.Announce).To(BeNil())
+	})
+
+	It("rejects addresses conflicting with their source or peer", func() {
+		invalid := []scripts.AnnounceSources{
+			{Public: []string{"/ip4/10.0.0.1/tcp/4001"}},
+			{Public: []string{"/ip4/1.2.3.4/tcp/4001/p2p/" + relay}},
+			{DNS: []string{"/ip4/1.2.3.4/tcp/4001"}},
+			{Relay: []string{"/ip4/5.6.7.8/tcp/4001/p2p/" + relay}},
+			{Relay: []string{"not-an-address"}},
+		}
+		for _, sources := range invalid {
+			_, err := scripts.AnnounceAddrs(id, sources)
+			Expect(err).To(HaveOccurred())
+		}
+	})
+})
+
+var _ = Describe("Pubsub", func() {
+	var conf *config.Config
+
+	BeforeEach(func() {
+		conf = &config.Config{}
+	})
+
+	It("enables gossipsub by default", func() {
+		Expect(scripts.ApplyPubsub(conf, true, "")).To(Succeed())
+		Expect(conf.Pubsub.Enabled.WithDefault(false)).To(BeTrue())
+		Expect(conf.Pubsub.Router).To(Equal(scripts.PubsubRouterGossipsub))
+	})
+
+	It("sets the floodsub router", func() {
+		Expect(scripts.ApplyPubsub(conf, true, scripts.PubsubRouterFloodsub)).To(Succeed())
+		Expect(conf.Pubsub.Router).To(Equal(scripts.PubsubRouterFloodsub))
+	})
+
+	It("disables pubsub", func() {
+		Expect(scripts.ApplyPubsub(conf, true, scripts.PubsubRouterGossipsub)).To(Succeed())
+		Expect(scripts.ApplyPubsub(conf, false, scripts.PubsubRouterGossipsub)).To(Succeed())
+		Expect(conf.Pubsub.Enabled.WithDefault(true)).To(BeFalse())
+		Expect(conf.Pubsub.Router).To(BeEmpty())
+	})
+
+	It("rejects an unknown router", func() {
+		Expect(scripts.ApplyPubsub(conf, true, "randomsub")).NotTo(Succeed())
+	})
+})
+
+var _ = Describe("DNS resolvers", func() {
+	var conf *config.Config
+
+	BeforeEach(func() {
+		conf = &config.Config{}
+	})
+
+	It("maps each TLD onto its DoH resolver", func() {
+		Expect(scripts.ApplyDNSResolvers(conf, map[string]string{
+			"eth":           "https://dns.eth.limo/dns-query",
+			"corp.example.": "https://doh.corp.example/dns-query",
+			".":             "https://cloudflare-dns.com/dns-query",
+		})).To(Succeed())
+		Expect(conf.DNS.Resolvers).To(Equal(map[string]string{
+			"eth.":          "https://dns.eth.limo/dns-query",
+			"corp.example.": "https://doh.corp.example/dns-query",
+			".":             "https://cloudflare-dns.com/dns-query",
+		}))
+	})
+
+	It("rejects malformed resolver URLs", func() {
+		Expect(scripts.ApplyDNSResolvers(conf, map[string]string{"eth": "dns.eth.limo"})).NotTo(Succeed())
+		Expect(scripts.ApplyDNSResolvers(conf, map[string]string{"eth": "http://dns.eth.limo"})).NotTo(Succeed())
+		Expect(scripts.ApplyDNSResolvers(conf, map[string]string{"eth": "https://"})).NotTo(Succeed())
+		Expect(conf.DNS.Resolvers).To(BeNil())
+	})
+
+	It("rejects invalid and duplicate domains", func() {
+		Expect(scripts.ApplyDNSResolvers(conf, map[string]string{"not a domain": "https://doh.example"})).NotTo(Succeed())
+		Expect(scripts.ApplyDNSResolvers(conf, map[string]string{
+			"eth":  "https://dns.eth.limo/dns-query",
+			"eth.": "https://doh.example/dns-query",
+		})).NotTo(Succeed())
+	})
+})
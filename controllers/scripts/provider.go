@@ -0,0 +1,47 @@
+package scripts
+
+import (
+	"fmt"
+
+	"github.com/ipfs/kubo/config"
+)
+
+const (
+	// pinsPerProviderWorker Is how many pins a single provider worker announces within a reprovide
+	// interval before the cycle overruns it.
+	pinsPerProviderWorker = 10000
+	// maxProviderWorkers Bounds the provider workers, since each holds DHT queries in flight.
+	maxProviderWorkers = 256
+)
+
+// ProviderWorkerCount Returns the number of provider workers which reprovide a pinset of the given
+// size within a reprovide interval: one worker per pinsPerProviderWorker pins, up to maxProviderWorkers.
+func ProviderWorkerCount(pins int64) (int, error) {
+	if pins < 0 {
+		return 0, fmt.Errorf("pin count cannot be negative, got %d", pins)
+	}
+	workers := (pins + pinsPerProviderWorker - 1) / pinsPerProviderWorker
+	switch {
+	case workers < 1:
+		return 1, nil
+	case workers > maxProviderWorkers:
+		return maxProviderWorkers, nil
+	default:
+		return int(workers), nil
+	}
+}
+
+// ApplyProviderWorkerCount Sets Provider.WorkerCount on the given Kubo configuration, scaled from the
+// number of pins so that large pinsets don't under-announce their content. Kubo v0.16 predates
+// Provider.WorkerCount and always reprovides with a single worker, so pinsets needing more workers
+// return ErrUnsupportedOption.
+func ApplyProviderWorkerCount(conf *config.Config, pins int64) error {
+	workers, err := ProviderWorkerCount(pins)
+	if err != nil {
+		return err
+	}
+	if workers > 1 {
+		return fmt.Errorf("provider WorkerCount %d: %w", workers, ErrUnsupportedOption)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package scripts_test
+
+import (
+	"github.com/ipfs/kubo/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/ipfs-operator/controllers/scripts"
+)
+
+var _ = Describe("Provider worker count", func() {
+	DescribeTable("scales with the pin count",
+		func(pins int64, expected int) {
+			workers, err := scripts.ProviderWorkerCount(pins)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(workers).To(Equal(expected))
+		},
+		Entry("without pins", int64(0), 1),
+		Entry("a small pinset", int64(5000), 1),
+		Entry("just above one worker", int64(10001), 2),
+		Entry("a large pinset", int64(1000000), 100),
+		Entry("beyond the cap", int64(100000000), 256),
+	)
+
+	It("grows monotonically", func() {
+		previous := 0
+		for pins := int64(0); pins <= 3000000; pins += 50000 {
+			workers, err := scripts.ProviderWorkerCount(pins)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(workers).To(BeNumerically(">=", previous))
+			previous = workers
+		}
+	})
+
+	It("only applies the single worker of the bundled kubo", func() {
+		Expect(scripts.ApplyProviderWorkerCount(&config.Config{}, 5000)).To(Succeed())
+		Expect(scripts.ApplyProviderWorkerCount(&config.Config{}, 1000000)).To(MatchError(scripts.ErrUnsupportedOption))
+		Expect(scripts.ApplyProviderWorkerCount(&config.Config{}, -1)).NotTo(Succeed())
+	})
+})
@@ -0,0 +1,269 @@
+package scripts
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ipfs/kubo/config"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	rcmgr "github.com/libp2p/go-libp2p/p2p/host/resource-manager"
+	ma "github.com/multiformats/go-multiaddr"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ResourceMgrMemoryFraction Defines the fraction of the container's memory limit
+// which the libp2p resource manager is allowed to use. The remainder is left
+// to the rest of the IPFS node, e.g. the datastore and bitswap.
+const ResourceMgrMemoryFraction = 0.5
+
+// ApplyResourceMgrMaxMemory Sets the resource manager's system memory limit from the
+// memory limit of the IPFS container. Left to its defaults, the resource manager
+// derives the limit from the host's total memory, which doesn't account for cgroup limits.
+func ApplyResourceMgrMaxMemory(conf *config.Config, memoryLimitBytes int64) error {
+	if memoryLimitBytes <= 0 {
+		return fmt.Errorf("memory limit must be positive, got %d", memoryLimitBytes)
+	}
+	limits := resourceMgrLimits(conf)
+	limits.System.Memory = int64(float64(memoryLimitBytes) * ResourceMgrMemoryFraction)
+	return nil
+}
+
+const (
+	// EnvIPFSFDMax Makes go-ipfs raise its file descriptor ulimit to the given value on startup.
+	EnvIPFSFDMax = "IPFS_FD_MAX"
+	// ResourceMgrFDFraction Defines the fraction of the process' file descriptors which
+	// the resource manager may hand out to connections and streams.
+	ResourceMgrFDFraction = 0.5
+	// fdsPerPeer Estimates the descriptors held per connected peer across transports.
+	fdsPerPeer = 2
+	// fdBaseline Covers the descriptors connections use on an idle node.
+	fdBaseline = 2048
+)
+
+// ResourceMgrFileDescriptors Returns the number of file descriptors the resource manager
+// should allow for the expected number of connected peers, along with the process ulimit
+// which leaves room for the datastore and the HTTP APIs next to it.
+func ResourceMgrFileDescriptors(expectedPeers int) (resourceMgrFDs, processFDs int, err error) {
+	if expectedPeers < 0 {
+		return 0, 0, fmt.Errorf("expected peers cannot be negative, got %d", expectedPeers)
+	}
+	resourceMgrFDs = fdBaseline + expectedPeers*fdsPerPeer
+	processFDs = int(float64(resourceMgrFDs) / ResourceMgrFDFraction)
+	return resourceMgrFDs, processFDs, nil
+}
+
+// ApplyResourceMgrMaxFileDescriptors Sets the resource manager's system file descriptor
+// limit scaled to the expected number of connected peers, and returns the environment
+// variable which raises the ulimit of the IPFS process to match. Left to its defaults,
+// the limit is derived from the container's default ulimit, which busy nodes exhaust.
+func ApplyResourceMgrMaxFileDescriptors(conf *config.Config, expectedPeers int) (corev1.EnvVar, error) {
+	resourceMgrFDs, processFDs, err := ResourceMgrFileDescriptors(expectedPeers)
+	if err != nil {
+		return corev1.EnvVar{}, err
+	}
+	limits := resourceMgrLimits(conf)
+	limits.System.FD = resourceMgrFDs
+	return corev1.EnvVar{
+		Name:  EnvIPFSFDMax,
+		Value: strconv.Itoa(processFDs),
+	}, nil
+}
+
+// DefaultBlockstoreCacheSize Is the number of entries of the blockstore ARC cache built into Kubo.
+const DefaultBlockstoreCacheSize = 64 << 10
+
+// ApplyBlockstoreCacheSize Sizes the ARC cache in front of the blockstore to the given number
+// of entries. Kubo v0.16 hardcodes the cache size and reads it from neither the config nor the
+// environment, so sizes other than DefaultBlockstoreCacheSize return ErrUnsupportedOption.
+func ApplyBlockstoreCacheSize(conf *config.Config, size int) error {
+	if size <= 0 {
+		return fmt.Errorf("blockstore cache size must be positive, got %d", size)
+	}
+	if size != DefaultBlockstoreCacheSize {
+		return fmt.Errorf("blockstore cache size: %w", ErrUnsupportedOption)
+	}
+	return nil
+}
+
+// ApplyResourceMgrAllowlist Adds the trusted infrastructure peers to Swarm.ResourceMgr.Allowlist on
+// the given Kubo configuration, so that cluster traffic is never throttled by the resource manager.
+// The allowlist matches peers by IP, which changes as sibling pods are rescheduled, so each sibling
+// is allowed from anywhere within the given pod CIDRs under its own peer ID. Relays are allowed
+// under their addresses, which must hold an IP and a /p2p component. Existing entries are kept.
+func ApplyResourceMgrAllowlist(
+	conf *config.Config,
+	podCIDRs []string,
+	siblings []peer.ID,
+	relays []ma.Multiaddr,
+) error {
+	entries := make([]string, 0, len(podCIDRs)*len(siblings)+len(relays))
+	for _, cidr := range podCIDRs {
+		ip, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("invalid pod CIDR: %w", err)
+		}
+		ones, _ := ipNet.Mask.Size()
+		family := "ip6"
+		if ip.To4() != nil {
+			family = "ip4"
+		}
+		for _, id := range siblings {
+			if err = id.Validate(); err != nil {
+				return fmt.Errorf("invalid sibling peer id: %w", err)
+			}
+			entries = append(entries, fmt.Sprintf("/%s/%s/ipcidr/%d/p2p/%s", family, ipNet.IP, ones, id))
+		}
+	}
+	for _, relay := range relays {
+		// keep the IP and peer ID, the allowlist ignores the transport
+		var ip, id string
+		ma.ForEach(relay, func(c ma.Component) bool {
+			switch c.Protocol().Code {
+			case ma.P_IP4, ma.P_IP6:
+				ip = "/" + c.Protocol().Name + "/" + c.Value()
+			case ma.P_P2P:
+				id = "/p2p/" + c.Value()
+			}
+			return true
+		})
+		if ip == "" || id == "" {
+			return fmt.Errorf("relay address %s must hold an IP and a peer ID", relay)
+		}
+		entries = append(entries, ip+id)
+	}
+	for _, entry := range entries {
+		if !containsString(conf.Swarm.ResourceMgr.Allowlist, entry) {
+			conf.Swarm.ResourceMgr.Allowlist = append(conf.Swarm.ResourceMgr.Allowlist, entry)
+		}
+	}
+	return nil
+}
+
+// ApplyResourceMgrDisabled Sets Swarm.ResourceMgr.Enabled on the given Kubo configuration,
+// turning the resource manager entirely off when disable is set, so that "resource limit exceeded"
+// errors can be told apart from other connection failures. Without the resource manager, a single
+// misbehaving peer can exhaust the node's memory and file descriptors, so it is only meant for
+// debugging. Otherwise the resource manager is enabled explicitly, since Kubo v0.16 leaves it off
+// by default.
+func ApplyResourceMgrDisabled(conf *config.Config, disable bool) {
+	if !disable {
+		conf.Swarm.ResourceMgr.Enabled = config.True
+		return
+	}
+	conf.Swarm.ResourceMgr.Enabled = config.False
+}
+
+// Protocols of the IPFS node which are given their own share of the resource manager's streams.
+const (
+	ProtocolBitswap = "/ipfs/bitswap/1.2.0"
+	ProtocolKadDHT  = "/ipfs/kad/1.0.0"
+)
+
+// BitswapProtocols Lists every protocol ID bitswap is spoken under, newest first. Older peers
+// negotiate one of the previous versions, which would otherwise fall into the default scope.
+var BitswapProtocols = []string{ProtocolBitswap, "/ipfs/bitswap/1.1.0", "/ipfs/bitswap/1.0.0", "/ipfs/bitswap"}
+
+// resourceMgrProtocolShares Splits the streams of the resource manager's system scope across the
+// protocols by default: bitswap transfers the blocks and gets the largest share, the DHT the next.
+// Every version of bitswap is given the bitswap share, since a stream only speaks one of them.
+// Every other protocol shares the default protocol scope.
+var resourceMgrProtocolShares = map[string]float64{
+	ProtocolKadDHT: 0.3,
+}
+
+// resourceMgrBitswapShare Is the share of the system streams of each bitswap protocol.
+const resourceMgrBitswapShare = 0.5
+
+const (
+	// resourceMgrProtocolDefaultShare Is the share of the system streams of each other protocol.
+	resourceMgrProtocolDefaultShare = 0.1
+	// resourceMgrDefaultPeerConns Is how many connections a single peer may hold by default.
+	resourceMgrDefaultPeerConns = 8
+	// resourceMgrPeerStreamShare Is the share of the system streams a single peer may hold by default.
+	resourceMgrPeerStreamShare = 0.02
+)
+
+// ResourceMgrScopeLimits Are the limits of the protocol and peer scopes of the resource manager,
+// which stop a single noisy protocol or peer from starving the others of the system limits.
+type ResourceMgrScopeLimits struct {
+	// ProtocolStreams Maps protocol IDs onto the streams open at once for each.
+	ProtocolStreams map[string]int
+	// DefaultProtocolStreams Is the streams open at once for every other protocol.
+	DefaultProtocolStreams int
+	// PeerConns Is the connections a single peer may hold.
+	PeerConns int
+	// PeerStreams Is the streams a single peer may hold open at once.
+	PeerStreams int
+}
+
+// DefaultResourceMgrScopeLimits Returns scope limits which split the given number of system streams
+// across bitswap, the DHT and the other protocols, and bound each peer to a small share of them.
+func DefaultResourceMgrScopeLimits(systemStreams int) (ResourceMgrScopeLimits, error) {
+	if systemStreams <= 0 {
+		return ResourceMgrScopeLimits{}, fmt.Errorf("system streams must be positive, got %d", systemStreams)
+	}
+	share := func(fraction float64) int {
+		if streams := int(float64(systemStreams) * fraction); streams > 0 {
+			return streams
+		}
+		return 1
+	}
+	limits := ResourceMgrScopeLimits{
+		ProtocolStreams:        make(map[string]int, len(resourceMgrProtocolShares)+len(BitswapProtocols)),
+		DefaultProtocolStreams: share(resourceMgrProtocolDefaultShare),
+		PeerConns:              resourceMgrDefaultPeerConns,
+		PeerStreams:            share(resourceMgrPeerStreamShare),
+	}
+	for id, fraction := range resourceMgrProtocolShares {
+		limits.ProtocolStreams[id] = share(fraction)
+	}
+	for _, id := range BitswapProtocols {
+		limits.ProtocolStreams[id] = share(resourceMgrBitswapShare)
+	}
+	return limits, nil
+}
+
+// ApplyResourceMgrScopeLimits Sets the protocol and peer scope limits of the resource manager on the
+// given Kubo configuration, and enables the resource manager, which Kubo v0.16 leaves off by default.
+// Connections are accounted per peer, since a connection carries the streams of every protocol,
+// whereas protocols are limited by their streams.
+func ApplyResourceMgrScopeLimits(conf *config.Config, limits ResourceMgrScopeLimits) error {
+	if limits.DefaultProtocolStreams <= 0 || limits.PeerConns <= 0 || limits.PeerStreams <= 0 {
+		return fmt.Errorf("resource manager scope limits must be positive")
+	}
+	ids := make([]string, 0, len(limits.ProtocolStreams))
+	for id := range limits.ProtocolStreams {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	protocols := make(map[protocol.ID]rcmgr.BaseLimit, len(ids))
+	for _, id := range ids {
+		streams := limits.ProtocolStreams[id]
+		if !strings.HasPrefix(id, "/") {
+			return fmt.Errorf("invalid protocol id %q", id)
+		}
+		if streams <= 0 {
+			return fmt.Errorf("streams of protocol %s must be positive, got %d", id, streams)
+		}
+		protocols[protocol.ID(id)] = rcmgr.BaseLimit{Streams: streams}
+	}
+	rc := resourceMgrLimits(conf)
+	rc.Protocol = protocols
+	rc.ProtocolDefault = rcmgr.BaseLimit{Streams: limits.DefaultProtocolStreams}
+	rc.PeerDefault = rcmgr.BaseLimit{Conns: limits.PeerConns, Streams: limits.PeerStreams}
+	conf.Swarm.ResourceMgr.Enabled = config.True
+	return nil
+}
+
+// resourceMgrLimits Returns the resource manager limits of the given config,
+// initializing them if they haven't been set yet.
+func resourceMgrLimits(conf *config.Config) *rcmgr.LimitConfig {
+	if conf.Swarm.ResourceMgr.Limits == nil {
+		conf.Swarm.ResourceMgr.Limits = &rcmgr.LimitConfig{}
+	}
+	return conf.Swarm.ResourceMgr.Limits
+}
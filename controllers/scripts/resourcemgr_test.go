@@ -0,0 +1,204 @@
+package scripts_test
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ipfs/kubo/config"
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/redhat-et/ipfs-operator/controllers/scripts"
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Resource manager memory", func() {
+	var conf *config.Config
+
+	BeforeEach(func() {
+		conf = &config.Config{}
+	})
+
+	It("tracks the container memory limit and stays below it", func() {
+		var previous int64
+		for _, storage := range []int64{1 << 30, 2 << 40, 8 << 40, 16 << 40} {
+			resources := utils.IPFSContainerResources(storage)
+			memoryLimit := resources.Limits[corev1.ResourceMemory]
+			Expect(scripts.ApplyResourceMgrMaxMemory(conf, memoryLimit.Value())).To(Succeed())
+
+			maxMemory := conf.Swarm.ResourceMgr.Limits.System.Memory
+			Expect(maxMemory).To(BeNumerically(">", 0))
+			Expect(maxMemory).To(BeNumerically("<", memoryLimit.Value()))
+			Expect(maxMemory).To(BeNumerically(">=", previous))
+			previous = maxMemory
+		}
+	})
+
+	It("keeps existing limits", func() {
+		Expect(scripts.ApplyResourceMgrMaxMemory(conf, 1<<30)).To(Succeed())
+		conf.Swarm.ResourceMgr.Limits.System.Conns = 100
+		Expect(scripts.ApplyResourceMgrMaxMemory(conf, 2<<30)).To(Succeed())
+		Expect(conf.Swarm.ResourceMgr.Limits.System.Conns).To(Equal(100))
+		Expect(conf.Swarm.ResourceMgr.Limits.System.Memory).To(Equal(int64(1 << 30)))
+	})
+
+	It("rejects a missing memory limit", func() {
+		Expect(scripts.ApplyResourceMgrMaxMemory(conf, 0)).NotTo(Succeed())
+	})
+})
+
+var _ = Describe("Resource manager file descriptors", func() {
+	It("scales the limit with the expected peer count", func() {
+		var previous int
+		for _, peers := range []int{0, 100, 2000, 10000} {
+			conf := &config.Config{}
+			env, err := scripts.ApplyResourceMgrMaxFileDescriptors(conf, peers)
+			Expect(err).NotTo(HaveOccurred())
+
+			fds := conf.Swarm.ResourceMgr.Limits.System.FD
+			Expect(fds).To(BeNumerically(">", previous))
+			Expect(fds).To(BeNumerically(">=", 2*peers))
+			previous = fds
+
+			Expect(env.Name).To(Equal(scripts.EnvIPFSFDMax))
+			processFDs, err := strconv.Atoi(env.Value)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(processFDs).To(BeNumerically(">", fds))
+		}
+	})
+
+	It("rejects a negative peer count", func() {
+		_, err := scripts.ApplyResourceMgrMaxFileDescriptors(&config.Config{}, -1)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Blockstore cache size", func() {
+	It("accepts the size built into kubo", func() {
+		Expect(scripts.ApplyBlockstoreCacheSize(&config.Config{}, scripts.DefaultBlockstoreCacheSize)).To(Succeed())
+	})
+
+	It("reports that other sizes are unsupported", func() {
+		err := scripts.ApplyBlockstoreCacheSize(&config.Config{}, 1<<20)
+		Expect(err).To(MatchError(scripts.ErrUnsupportedOption))
+		Expect(scripts.ApplyBlockstoreCacheSize(&config.Config{}, 0)).NotTo(Succeed())
+	})
+})
+
+var _ = Describe("Resource manager allowlist", func() {
+	var siblings []peer.ID
+
+	BeforeEach(func() {
+		siblings = make([]peer.ID, 2)
+		for i := range siblings {
+			id, _, err := utils.GenerateIdentity()
+			Expect(err).NotTo(HaveOccurred())
+			siblings[i] = id
+		}
+	})
+
+	It("allows the siblings from the pod network", func() {
+		conf := &config.Config{}
+		Expect(scripts.ApplyResourceMgrAllowlist(conf, []string{"10.128.0.0/14", "fd00::/48"}, siblings, nil)).
+			To(Succeed())
+		Expect(conf.Swarm.ResourceMgr.Allowlist).To(ConsistOf(
+			"/ip4/10.128.0.0/ipcidr/14/p2p/"+siblings[0].String(),
+			"/ip4/10.128.0.0/ipcidr/14/p2p/"+siblings[1].String(),
+			"/ip6/fd00::/ipcidr/48/p2p/"+siblings[0].String(),
+			"/ip6/fd00::/ipcidr/48/p2p/"+siblings[1].String(),
+		))
+		for _, entry := range conf.Swarm.ResourceMgr.Allowlist {
+			_, err := ma.NewMultiaddr(entry)
+			Expect(err).NotTo(HaveOccurred())
+		}
+	})
+
+	It("allows the relays and keeps existing entries", func() {
+		relay := ma.StringCast("/ip4/203.0.113.7/tcp/4001/p2p/" + siblings[1].String())
+		conf := &config.Config{}
+		conf.Swarm.ResourceMgr.Allowlist = []string{"/ip4/192.0.2.1"}
+		Expect(scripts.ApplyResourceMgrAllowlist(conf, nil, nil, []ma.Multiaddr{relay, relay})).To(Succeed())
+		Expect(conf.Swarm.ResourceMgr.Allowlist).To(Equal([]string{
+			"/ip4/192.0.2.1",
+			"/ip4/203.0.113.7/p2p/" + siblings[1].String(),
+		}))
+	})
+
+	It("rejects relays without an IP and invalid CIDRs", func() {
+		relay := ma.StringCast("/dns4/relay.example.com/tcp/4001/p2p/" + siblings[0].String())
+		Expect(scripts.ApplyResourceMgrAllowlist(&config.Config{}, nil, nil, []ma.Multiaddr{relay})).NotTo(Succeed())
+		Expect(scripts.ApplyResourceMgrAllowlist(&config.Config{}, []string{"10.0.0.0"}, siblings, nil)).NotTo(Succeed())
+	})
+})
+
+var _ = Describe("Resource manager debug disable", func() {
+	It("disables the resource manager when requested", func() {
+		conf := &config.Config{}
+		scripts.ApplyResourceMgrDisabled(conf, true)
+		Expect(conf.Swarm.ResourceMgr.Enabled.WithDefault(true)).To(BeFalse())
+	})
+
+	It("enables it otherwise, since Kubo leaves it off by default", func() {
+		conf := &config.Config{}
+		conf.Swarm.ResourceMgr.Enabled = config.False
+		scripts.ApplyResourceMgrDisabled(conf, false)
+		Expect(conf.Swarm.ResourceMgr.Enabled.WithDefault(false)).To(BeTrue())
+	})
+})
+
+var _ = Describe("Resource manager scope limits", func() {
+	It("splits the system streams across the protocols by default", func() {
+		limits, err := scripts.DefaultResourceMgrScopeLimits(10000)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(limits.ProtocolStreams[scripts.ProtocolBitswap]).
+			To(BeNumerically(">", limits.ProtocolStreams[scripts.ProtocolKadDHT]))
+		Expect(limits.ProtocolStreams[scripts.ProtocolKadDHT]).To(BeNumerically(">", limits.DefaultProtocolStreams))
+		for _, id := range []string{"/ipfs/bitswap/1.1.0", "/ipfs/bitswap/1.0.0", "/ipfs/bitswap"} {
+			Expect(limits.ProtocolStreams).To(HaveKeyWithValue(id, limits.ProtocolStreams[scripts.ProtocolBitswap]))
+		}
+		// a stream speaks a single version of bitswap, so its share is only counted once
+		total := limits.DefaultProtocolStreams + limits.ProtocolStreams[scripts.ProtocolBitswap]
+		for id, streams := range limits.ProtocolStreams {
+			if !strings.HasPrefix(id, "/ipfs/bitswap") {
+				total += streams
+			}
+		}
+		Expect(total).To(BeNumerically("<=", 10000))
+		Expect(limits.PeerStreams).To(BeNumerically("<", limits.DefaultProtocolStreams))
+
+		_, err = scripts.DefaultResourceMgrScopeLimits(0)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("renders the per-protocol and per-peer limits", func() {
+		conf := &config.Config{}
+		Expect(scripts.ApplyResourceMgrScopeLimits(conf, scripts.ResourceMgrScopeLimits{
+			ProtocolStreams:        map[string]int{scripts.ProtocolBitswap: 4000, scripts.ProtocolKadDHT: 2000},
+			DefaultProtocolStreams: 500,
+			PeerConns:              4,
+			PeerStreams:            64,
+		})).To(Succeed())
+		Expect(conf.Swarm.ResourceMgr.Enabled.WithDefault(false)).To(BeTrue())
+		rc := conf.Swarm.ResourceMgr.Limits
+		Expect(rc).NotTo(BeNil())
+		Expect(rc.Protocol[scripts.ProtocolBitswap].Streams).To(Equal(4000))
+		Expect(rc.Protocol[scripts.ProtocolKadDHT].Streams).To(Equal(2000))
+		Expect(rc.ProtocolDefault.Streams).To(Equal(500))
+		Expect(rc.PeerDefault.Conns).To(Equal(4))
+		Expect(rc.PeerDefault.Streams).To(Equal(64))
+	})
+
+	It("rejects invalid limits", func() {
+		valid, err := scripts.DefaultResourceMgrScopeLimits(1000)
+		Expect(err).NotTo(HaveOccurred())
+		invalid := valid
+		invalid.PeerConns = 0
+		Expect(scripts.ApplyResourceMgrScopeLimits(&config.Config{}, invalid)).NotTo(Succeed())
+		invalid = valid
+		invalid.ProtocolStreams = map[string]int{"bitswap": 10}
+		Expect(scripts.ApplyResourceMgrScopeLimits(&config.Config{}, invalid)).NotTo(Succeed())
+	})
+})
@@ -0,0 +1,202 @@
+package scripts
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ipfs/kubo/config"
+	ma "github.com/multiformats/go-multiaddr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// SwarmPortNameTCP Names the Service port of the TCP swarm transport.
+	SwarmPortNameTCP = "swarm-tcp"
+	// SwarmPortNameQUIC Names the Service port of the QUIC swarm transport.
+	SwarmPortNameQUIC = "swarm-quic"
+)
+
+const (
+	// ConnMgrTypeBasic Defines the default connection manager which prunes
+	// connections once the high watermark is reached.
+	ConnMgrTypeBasic = "basic"
+	// ConnMgrTypeNone Disables connection pruning entirely.
+	ConnMgrTypeNone = "none"
+)
+
+// ApplyConnMgrType Sets Swarm.ConnMgr.Type on the given Kubo configuration.
+// When the type is "none", the watermark and grace period settings are cleared
+// since they would have no effect. An empty type keeps the basic connection manager.
+func ApplyConnMgrType(conf *config.Config, connMgrType string) error {
+	switch connMgrType {
+	case "", ConnMgrTypeBasic:
+		conf.Swarm.ConnMgr.Type = ConnMgrTypeBasic
+	case ConnMgrTypeNone:
+		conf.Swarm.ConnMgr = config.ConnMgr{
+			Type: ConnMgrTypeNone,
+		}
+	default:
+		return fmt.Errorf("invalid connection manager type: %s", connMgrType)
+	}
+	return nil
+}
+
+// connMgrResourceMgrHeadroom Is the fraction of the resource manager's connection cap kept free above
+// the connection manager's high watermark, so that connections accepted while the connection manager
+// catches up with pruning don't hit the cap.
+const connMgrResourceMgrHeadroom = 0.1
+
+// ApplyConnMgrWatermarks Sets Swarm.ConnMgr.LowWater, HighWater and GracePeriod on the given Kubo
+// configuration. The high watermark is clamped so that it stays below the inbound and total
+// connection limits of the resource manager, if any: otherwise the resource manager refuses
+// connections before the connection manager prunes any, and peers keep flapping. The low watermark
+// is lowered along with it. A zero grace period uses Kubo's default.
+func ApplyConnMgrWatermarks(conf *config.Config, lowWater, highWater int, gracePeriod time.Duration) error {
+	if lowWater <= 0 || highWater <= lowWater {
+		return fmt.Errorf("connection manager watermarks must satisfy 0 < low < high, got %d and %d",
+			lowWater, highWater)
+	}
+	if gracePeriod < 0 {
+		return fmt.Errorf("connection manager grace period cannot be negative, got %s", gracePeriod)
+	}
+	if gracePeriod == 0 {
+		gracePeriod = config.DefaultConnMgrGracePeriod
+	}
+	if limit := resourceMgrConnLimit(conf); limit > 0 {
+		ceiling := limit - int(float64(limit)*connMgrResourceMgrHeadroom) - 1
+		if ceiling < 2 {
+			return fmt.Errorf("resource manager connection limit %d is too low for the connection manager", limit)
+		}
+		if highWater > ceiling {
+			lowWater = lowWater * ceiling / highWater
+			highWater = ceiling
+		}
+		if lowWater < 1 {
+			lowWater = 1
+		}
+	}
+	conf.Swarm.ConnMgr.LowWater = lowWater
+	conf.Swarm.ConnMgr.HighWater = highWater
+	conf.Swarm.ConnMgr.GracePeriod = gracePeriod.String()
+	return nil
+}
+
+// resourceMgrConnLimit Returns the lowest connection limit configured for the system scope of the
+// resource manager which bounds the connections the connection manager keeps, or 0 if none is set.
+func resourceMgrConnLimit(conf *config.Config) int {
+	if conf.Swarm.ResourceMgr.Limits == nil {
+		return 0
+	}
+	system := conf.Swarm.ResourceMgr.Limits.System
+	limit := 0
+	for _, l := range []int{system.Conns, system.ConnsInbound} {
+		if l > 0 && (limit == 0 || l < limit) {
+			limit = l
+		}
+	}
+	return limit
+}
+
+// ApplyDisableNatPortMap Sets Swarm.DisableNatPortMap on the given Kubo configuration.
+// Port mapping through UPnP and NAT-PMP is pointless in cloud environments, so unless
+// explicitly configured, it is disabled for cloud deployments and left enabled otherwise.
+func ApplyDisableNatPortMap(conf *config.Config, disable *bool, cloud bool) {
+	if disable == nil {
+		conf.Swarm.DisableNatPortMap = cloud
+		return
+	}
+	conf.Swarm.DisableNatPortMap = *disable
+}
+
+// RelayClientConfig Returns a Swarm.RelayClient configuration which enables the relay
+// client and uses the given relay addresses as static relays, so that peers behind NAT
+// become reachable through the cluster's relays. Each address must identify the relay
+// through a /p2p component.
+func RelayClientConfig(staticRelays []ma.Multiaddr) (config.RelayClient, error) {
+	relays := make([]string, 0, len(staticRelays))
+	for _, addr := range staticRelays {
+		if _, err := addr.ValueForProtocol(ma.P_P2P); err != nil {
+			return config.RelayClient{}, fmt.Errorf("static relay %s has no peer ID: %w", addr, err)
+		}
+		relays = append(relays, addr.String())
+	}
+	return config.RelayClient{
+		Enabled:      config.True,
+		StaticRelays: relays,
+	}, nil
+}
+
+// ApplyPrivateSwarmTransports Disables the circuit relay transport on the given Kubo configuration
+// when the swarm is private, along with the relay client and service which depend on it, since
+// relays reached through it could bridge the private swarm to the public network. Public swarms
+// keep the transport at its default, enabled setting.
+func ApplyPrivateSwarmTransports(conf *config.Config, private bool) {
+	if !private {
+		conf.Swarm.Transports.Network.Relay = config.Default
+		return
+	}
+	conf.Swarm.Transports.Network.Relay = config.False
+	conf.Swarm.RelayClient.Enabled = config.False
+	conf.Swarm.RelayClient.StaticRelays = nil
+	conf.Swarm.RelayService.Enabled = config.False
+}
+
+// ApplyHolePunching Sets Swarm.EnableHolePunching on the given Kubo configuration. Hole punching
+// coordinates the direct connection over a relayed one, so enabling it also enables the relay
+// client, keeping any static relays already configured. Disabling it leaves the relay client as is.
+func ApplyHolePunching(conf *config.Config, enable bool) {
+	if !enable {
+		conf.Swarm.EnableHolePunching = config.False
+		return
+	}
+	conf.Swarm.EnableHolePunching = config.True
+	conf.Swarm.RelayClient.Enabled = config.True
+}
+
+// SwarmListenAddrs Returns the Addresses.Swarm multiaddrs listening for TCP on tcpPort and for
+// QUIC on quicPort, on every IPv4 and IPv6 address. Distinct ports let per-pod Services expose
+// each transport under its own port.
+func SwarmListenAddrs(tcpPort, quicPort int32) ([]string, error) {
+	for _, port := range []int32{tcpPort, quicPort} {
+		if port <= 0 || port > 65535 {
+			return nil, fmt.Errorf("invalid swarm port: %d", port)
+		}
+	}
+	return []string{
+		fmt.Sprintf("/ip4/0.0.0.0/tcp/%d", tcpPort),
+		fmt.Sprintf("/ip6/::/tcp/%d", tcpPort),
+		fmt.Sprintf("/ip4/0.0.0.0/udp/%d/quic", quicPort),
+		fmt.Sprintf("/ip6/::/udp/%d/quic", quicPort),
+	}, nil
+}
+
+// ApplySwarmListenAddrs Sets Addresses.Swarm on the given Kubo configuration to listen for TCP
+// and QUIC on separate ports.
+func ApplySwarmListenAddrs(conf *config.Config, tcpPort, quicPort int32) error {
+	addrs, err := SwarmListenAddrs(tcpPort, quicPort)
+	if err != nil {
+		return err
+	}
+	conf.Addresses.Swarm = addrs
+	return nil
+}
+
+// SwarmServicePorts Returns the Service ports exposing the swarm listeners rendered by
+// SwarmListenAddrs: one TCP port for the TCP transport and one UDP port for QUIC.
+func SwarmServicePorts(tcpPort, quicPort int32) []corev1.ServicePort {
+	return []corev1.ServicePort{
+		{
+			Name:       SwarmPortNameTCP,
+			Protocol:   corev1.ProtocolTCP,
+			Port:       tcpPort,
+			TargetPort: intstr.FromInt(int(tcpPort)),
+		},
+		{
+			Name:       SwarmPortNameQUIC,
+			Protocol:   corev1.ProtocolUDP,
+			Port:       quicPort,
+			TargetPort: intstr.FromInt(int(quicPort)),
+		},
+	}
+}
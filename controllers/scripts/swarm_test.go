@@ -0,0 +1,228 @@
+package scripts_test
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/ipfs/kubo/config"
+	rcmgr "github.com/libp2p/go-libp2p/p2p/host/resource-manager"
+	ma "github.com/multiformats/go-multiaddr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/redhat-et/ipfs-operator/controllers/scripts"
+)
+
+var _ = Describe("Connection manager", func() {
+	var conf *config.Config
+
+	BeforeEach(func() {
+		conf = &config.Config{
+			Swarm: config.SwarmConfig{
+				ConnMgr: config.ConnMgr{
+					LowWater:    600,
+					HighWater:   2000,
+					GracePeriod: "20s",
+				},
+			},
+		}
+	})
+
+	It("uses the basic connection manager by default", func() {
+		Expect(scripts.ApplyConnMgrType(conf, "")).To(Succeed())
+		Expect(conf.Swarm.ConnMgr.Type).To(Equal(scripts.ConnMgrTypeBasic))
+		Expect(conf.Swarm.ConnMgr.HighWater).To(Equal(2000))
+	})
+
+	It("keeps the watermarks for the basic connection manager", func() {
+		Expect(scripts.ApplyConnMgrType(conf, scripts.ConnMgrTypeBasic)).To(Succeed())
+		Expect(conf.Swarm.ConnMgr.Type).To(Equal(scripts.ConnMgrTypeBasic))
+		Expect(conf.Swarm.ConnMgr.LowWater).To(Equal(600))
+		Expect(conf.Swarm.ConnMgr.HighWater).To(Equal(2000))
+		Expect(conf.Swarm.ConnMgr.GracePeriod).To(Equal("20s"))
+	})
+
+	It("omits the watermarks when disabled", func() {
+		Expect(scripts.ApplyConnMgrType(conf, scripts.ConnMgrTypeNone)).To(Succeed())
+		Expect(conf.Swarm.ConnMgr.Type).To(Equal(scripts.ConnMgrTypeNone))
+		Expect(conf.Swarm.ConnMgr.LowWater).To(BeZero())
+		Expect(conf.Swarm.ConnMgr.HighWater).To(BeZero())
+		Expect(conf.Swarm.ConnMgr.GracePeriod).To(BeEmpty())
+	})
+
+	It("rejects an unknown type", func() {
+		Expect(scripts.ApplyConnMgrType(conf, "aggressive")).NotTo(Succeed())
+	})
+})
+
+var _ = Describe("NAT port mapping", func() {
+	var conf *config.Config
+	enabled, disabled := false, true
+
+	BeforeEach(func() {
+		conf = &config.Config{}
+	})
+
+	It("disables port mapping by default in the cloud", func() {
+		scripts.ApplyDisableNatPortMap(conf, nil, true)
+		Expect(conf.Swarm.DisableNatPortMap).To(BeTrue())
+	})
+
+	It("keeps port mapping by default outside the cloud", func() {
+		scripts.ApplyDisableNatPortMap(conf, nil, false)
+		Expect(conf.Swarm.DisableNatPortMap).To(BeFalse())
+	})
+
+	It("uses the configured value", func() {
+		scripts.ApplyDisableNatPortMap(conf, &enabled, true)
+		Expect(conf.Swarm.DisableNatPortMap).To(BeFalse())
+		scripts.ApplyDisableNatPortMap(conf, &disabled, false)
+		Expect(conf.Swarm.DisableNatPortMap).To(BeTrue())
+	})
+
+	It("renders the flag", func() {
+		scripts.ApplyDisableNatPortMap(conf, nil, true)
+		rendered, err := json.Marshal(conf.Swarm)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(rendered)).To(ContainSubstring(`"DisableNatPortMap":true`))
+	})
+})
+
+var _ = Describe("Relay client", func() {
+	const relayID = "12D3KooWSWJeZsAHyUdcWbnoR2hjFzB7NwEFBLAy1MbYeJQ4WXio"
+
+	It("uses the relay peers as static relays", func() {
+		relays := []ma.Multiaddr{
+			ma.StringCast("/ip4/10.0.0.1/tcp/4001/p2p/" + relayID),
+			ma.StringCast("/ip4/10.0.0.1/udp/4001/quic/p2p/" + relayID),
+		}
+		rc, err := scripts.RelayClientConfig(relays)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rc.Enabled).To(Equal(config.True))
+		Expect(rc.StaticRelays).To(Equal([]string{
+			"/ip4/10.0.0.1/tcp/4001/p2p/" + relayID,
+			"/ip4/10.0.0.1/udp/4001/quic/p2p/" + relayID,
+		}))
+	})
+
+	It("enables the relay client without static relays", func() {
+		rc, err := scripts.RelayClientConfig(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rc.Enabled).To(Equal(config.True))
+		Expect(rc.StaticRelays).To(BeEmpty())
+	})
+
+	It("rejects relays without a peer ID", func() {
+		_, err := scripts.RelayClientConfig([]ma.Multiaddr{ma.StringCast("/ip4/10.0.0.1/tcp/4001")})
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Private swarm transports", func() {
+	It("disables the relay transport in private mode", func() {
+		conf := &config.Config{}
+		conf.Swarm.RelayClient.Enabled = config.True
+		conf.Swarm.RelayClient.StaticRelays = []string{"/ip4/10.0.0.1/tcp/4001/p2p/QmRelay"}
+		scripts.ApplyPrivateSwarmTransports(conf, true)
+		Expect(conf.Swarm.Transports.Network.Relay.WithDefault(true)).To(BeFalse())
+		Expect(conf.Swarm.RelayClient.Enabled.WithDefault(true)).To(BeFalse())
+		Expect(conf.Swarm.RelayClient.StaticRelays).To(BeEmpty())
+		Expect(conf.Swarm.RelayService.Enabled.WithDefault(true)).To(BeFalse())
+	})
+
+	It("keeps the relay transport available in public mode", func() {
+		conf := &config.Config{}
+		conf.Swarm.Transports.Network.Relay = config.False
+		scripts.ApplyPrivateSwarmTransports(conf, false)
+		Expect(conf.Swarm.Transports.Network.Relay.WithDefault(true)).To(BeTrue())
+	})
+})
+
+var _ = Describe("Hole punching", func() {
+	It("enables the relay client along with hole punching", func() {
+		conf := &config.Config{}
+		conf.Swarm.RelayClient.Enabled = config.False
+		conf.Swarm.RelayClient.StaticRelays = []string{"/ip4/10.0.0.1/tcp/4001/p2p/QmRelay"}
+		scripts.ApplyHolePunching(conf, true)
+		Expect(conf.Swarm.EnableHolePunching.WithDefault(false)).To(BeTrue())
+		Expect(conf.Swarm.RelayClient.Enabled.WithDefault(false)).To(BeTrue())
+		Expect(conf.Swarm.RelayClient.StaticRelays).To(HaveLen(1))
+	})
+
+	It("leaves the relay client alone when disabled", func() {
+		conf := &config.Config{}
+		conf.Swarm.RelayClient.Enabled = config.True
+		scripts.ApplyHolePunching(conf, false)
+		Expect(conf.Swarm.EnableHolePunching.WithDefault(true)).To(BeFalse())
+		Expect(conf.Swarm.RelayClient.Enabled.WithDefault(false)).To(BeTrue())
+	})
+})
+
+var _ = Describe("Swarm listen addresses", func() {
+	It("listens for TCP and QUIC on separate ports", func() {
+		conf := &config.Config{}
+		Expect(scripts.ApplySwarmListenAddrs(conf, 4001, 4002)).To(Succeed())
+		Expect(conf.Addresses.Swarm).To(ContainElements(
+			"/ip4/0.0.0.0/tcp/4001", "/ip6/::/tcp/4001",
+			"/ip4/0.0.0.0/udp/4002/quic", "/ip6/::/udp/4002/quic",
+		))
+		for _, addr := range conf.Addresses.Swarm {
+			_, err := ma.NewMultiaddr(addr)
+			Expect(err).NotTo(HaveOccurred())
+		}
+	})
+
+	It("rejects invalid ports", func() {
+		Expect(scripts.ApplySwarmListenAddrs(&config.Config{}, 0, 4002)).NotTo(Succeed())
+		Expect(scripts.ApplySwarmListenAddrs(&config.Config{}, 4001, 70000)).NotTo(Succeed())
+	})
+
+	It("exposes one TCP and one UDP service port", func() {
+		ports := scripts.SwarmServicePorts(4001, 4002)
+		Expect(ports).To(HaveLen(2))
+		Expect(ports[0].Name).To(Equal(scripts.SwarmPortNameTCP))
+		Expect(ports[0].Protocol).To(Equal(corev1.ProtocolTCP))
+		Expect(ports[0].Port).To(BeEquivalentTo(4001))
+		Expect(ports[0].TargetPort.IntValue()).To(Equal(4001))
+		Expect(ports[1].Name).To(Equal(scripts.SwarmPortNameQUIC))
+		Expect(ports[1].Protocol).To(Equal(corev1.ProtocolUDP))
+		Expect(ports[1].Port).To(BeEquivalentTo(4002))
+		Expect(ports[1].TargetPort.IntValue()).To(Equal(4002))
+	})
+})
+
+var _ = Describe("Connection manager watermarks", func() {
+	It("keeps the watermarks when the resource manager has no connection limit", func() {
+		conf := &config.Config{}
+		Expect(scripts.ApplyConnMgrWatermarks(conf, 1500, 2000, 0)).To(Succeed())
+		Expect(conf.Swarm.ConnMgr.LowWater).To(Equal(1500))
+		Expect(conf.Swarm.ConnMgr.HighWater).To(Equal(2000))
+		Expect(conf.Swarm.ConnMgr.GracePeriod).To(Equal(config.DefaultConnMgrGracePeriod.String()))
+	})
+
+	It("clamps the high watermark below the resource manager limit", func() {
+		conf := &config.Config{}
+		conf.Swarm.ResourceMgr.Limits = &rcmgr.LimitConfig{}
+		conf.Swarm.ResourceMgr.Limits.System.Conns = 4000
+		conf.Swarm.ResourceMgr.Limits.System.ConnsInbound = 1000
+		Expect(scripts.ApplyConnMgrWatermarks(conf, 1500, 2000, time.Minute)).To(Succeed())
+		Expect(conf.Swarm.ConnMgr.HighWater).To(BeNumerically("<", 1000))
+		Expect(conf.Swarm.ConnMgr.LowWater).To(BeNumerically("<", conf.Swarm.ConnMgr.HighWater))
+		Expect(conf.Swarm.ConnMgr.GracePeriod).To(Equal("1m0s"))
+	})
+
+	It("leaves watermarks already below the limit alone", func() {
+		conf := &config.Config{}
+		conf.Swarm.ResourceMgr.Limits = &rcmgr.LimitConfig{}
+		conf.Swarm.ResourceMgr.Limits.System.Conns = 4000
+		Expect(scripts.ApplyConnMgrWatermarks(conf, 600, 900, 0)).To(Succeed())
+		Expect(conf.Swarm.ConnMgr.LowWater).To(Equal(600))
+		Expect(conf.Swarm.ConnMgr.HighWater).To(Equal(900))
+	})
+
+	It("rejects inverted watermarks", func() {
+		Expect(scripts.ApplyConnMgrWatermarks(&config.Config{}, 900, 600, 0)).NotTo(Succeed())
+		Expect(scripts.ApplyConnMgrWatermarks(&config.Config{}, 0, 600, 0)).NotTo(Succeed())
+	})
+})
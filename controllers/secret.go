@@ -8,6 +8,7 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -30,7 +31,7 @@ func (r *IpfsClusterReconciler) EnsureSecretConfig(
 	ctx context.Context,
 	m *clusterv1alpha1.IpfsCluster,
 ) (*corev1.Secret, error) {
-	secName := "ipfs-cluster-" + m.Name
+	secName := m.IdentitySecretName()
 
 	expectedSecret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
@@ -54,18 +55,25 @@ func (r *IpfsClusterReconciler) EnsureSecretConfig(
 	// secret exists.
 	// test if we need to add more identities
 	op, err := ctrl.CreateOrUpdate(ctx, r.Client, expectedSecret, func() error {
-		numIdentities := countIdentities(expectedSecret)
-		if numIdentities != m.Spec.Replicas {
-			// create more identities if needed, otherwise they will be reused
-			// when scaling down and then up again
-			if numIdentities < m.Spec.Replicas {
-				// create more
-				err = generateNewIdentities(expectedSecret, numIdentities, m.Spec.Replicas)
-				if err != nil {
-					return fmt.Errorf("could not generate more identities: %w", err)
-				}
+		if err = r.migrateLegacyIdentities(ctx, expectedSecret, m.Spec.Replicas); err != nil {
+			return fmt.Errorf("could not migrate legacy identities: %w", err)
+		}
+		// swarm keys stored by earlier releases lack the trailing slash of the header
+		if swarmKey, rewritten := utils.NormalizeSwarmKey(string(expectedSecret.Data[KeySwarmKey])); rewritten {
+			expectedSecret.Data[KeySwarmKey] = []byte(swarmKey)
+		}
+		// a malformed key is refused here rather than crash-looping the peers
+		if swarmKey, ok := expectedSecret.Data[KeySwarmKey]; ok {
+			if _, err = utils.ParseSwarmKey(string(swarmKey)); err != nil {
+				return fmt.Errorf("invalid swarm key: %w", err)
 			}
 		}
+		// create identities for the ordinals missing one, existing identities are
+		// never removed so they are reused when scaling down and then up again
+		err = generateNewIdentities(expectedSecret, 0, m.Spec.Replicas)
+		if err != nil {
+			return fmt.Errorf("could not generate more identities: %w", err)
+		}
 		if ctrlErr := ctrl.SetControllerReference(m, expectedSecret, r.Scheme); ctrlErr != nil {
 			return ctrlErr
 		}
@@ -97,6 +105,9 @@ func (r *IpfsClusterReconciler) createNewSecret(ctx context.Context, m *clusterv
 	if peerID, bootstrapPrivateKey, err = utils.GenerateIdentity(); err != nil {
 		return fmt.Errorf("could not create new ipfs identity: %w", err)
 	}
+	if err = r.migrateLegacyIdentities(ctx, secret, m.Spec.Replicas); err != nil {
+		return fmt.Errorf("could not migrate legacy identities: %w", err)
+	}
 	err = generateNewIdentities(secret, 0, m.Spec.Replicas)
 	if err != nil {
 		return fmt.Errorf("could not place new identities in secret: %w", err)
@@ -118,33 +129,114 @@ func (r *IpfsClusterReconciler) createNewSecret(ctx context.Context, m *clusterv
 	return
 }
 
-// countIdentities Counts the amount of unique peer identities present in the secret.
-func countIdentities(secret *corev1.Secret) int32 {
-	var count int32
-	for key := range secret.Data {
-		if strings.Contains(key, KeyPeerIDPrefix) {
-			count++
-		}
-	}
-	return count
-}
-
-// generateNewIdentities Populates the secret data with new Peer IDs
-// and private keys which are mapped based on the replica number.
+// generateNewIdentities Populates the secret data with new Peer IDs and private keys
+// which are mapped based on the replica number, for each replica without an identity.
 func generateNewIdentities(secret *corev1.Secret, start, n int32) error {
 	if secret.StringData == nil {
 		secret.StringData = make(map[string]string, 0)
 	}
 	for i := start; i < n; i++ {
-		// generate new private key & peer id
-		peerID, privKey, err := utils.GenerateIdentity()
-		if err != nil {
-			return err
+		// existing identities are kept so that peers rejoin with their history
+		peerIDKey := KeyPeerIDPrefix + strconv.Itoa(int(i))
+		secretKey := KeyPrivateKeyPrefix + strconv.Itoa(int(i))
+		if _, _, err := utils.EnsureOrdinalIdentity(secret, peerIDKey, secretKey); err != nil {
+			return fmt.Errorf("could not ensure identity for replica %d: %w", i, err)
 		}
+	}
+	return nil
+}
+
+// migrateLegacyIdentities Copies the identities of the ordinals missing from the consolidated secret
+// out of their legacy per-ordinal Secrets, if any, so that these peers keep their keys rather than
+// being given new identities. The legacy Secrets are kept until every peer has been migrated.
+func (r *IpfsClusterReconciler) migrateLegacyIdentities(ctx context.Context, secret *corev1.Secret, n int32) error {
+	for i := int32(0); i < n; i++ {
 		peerIDKey := KeyPeerIDPrefix + strconv.Itoa(int(i))
-		secret.StringData[peerIDKey] = peerID.String()
 		secretKey := KeyPrivateKeyPrefix + strconv.Itoa(int(i))
-		secret.StringData[secretKey] = privKey
+		// incomplete identities are reported when ensuring them
+		if _, _, source, err := utils.ReadOrdinalIdentity(secret, nil, peerIDKey, secretKey); err != nil ||
+			source != utils.IdentitySourceNone {
+			continue
+		}
+		legacy := &corev1.Secret{}
+		key := client.ObjectKey{Namespace: secret.Namespace, Name: utils.LegacyIdentitySecretName(secret.Name, i)}
+		if err := r.Get(ctx, key, legacy); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("could not get legacy identity secret %q: %w", key.Name, err)
+		}
+		if _, err := utils.MigrateLegacyIdentity(secret, legacy, peerIDKey, secretKey); err != nil {
+			return fmt.Errorf("could not migrate identity for replica %d: %w", i, err)
+		}
 	}
 	return nil
 }
+
+// CheckPeerIdentities Compares the IPFS peer ID announced by each peer recorded on the status of the
+// instance against the one stored for its ordinal in the identity Secret, reporting the peers whose
+// PVCs need remediation as a condition. Returns the ordinals of the mismatched peers.
+func (r *IpfsClusterReconciler) CheckPeerIdentities(
+	m *clusterv1alpha1.IpfsCluster,
+	secret *corev1.Secret,
+) ([]int32, error) {
+	announced := make(map[int32]string, len(m.Status.Peers))
+	for _, p := range m.Status.Peers {
+		ordinal, err := strconv.ParseInt(p.Name[strings.LastIndex(p.Name, "-")+1:], 10, 32)
+		if err != nil || p.PeerID == "" {
+			continue
+		}
+		announced[int32(ordinal)] = p.PeerID
+	}
+	mismatched, err := utils.PeerIdentityMismatches(secret, KeyPeerIDPrefix, announced)
+	if err != nil {
+		return nil, err
+	}
+	meta.SetStatusCondition(&m.Status.Conditions, utils.PeerIdentityMismatchCondition(mismatched))
+	return mismatched, nil
+}
+
+// EnsureComponentSecrets Splits the material from the given secret into one Secret per component,
+// so that each container only has access to what it requires. The IPFS Secret holds the swarm key
+// and peer identities, whereas the IPFS Cluster Secret holds the cluster secret and bootstrap identity.
+// The Secrets are suffixed with a dot, which the name of another IPFS cluster cannot end up producing
+// since its StatefulSet name must be a DNS label. Secrets controlled by another owner are never taken over.
+func (r *IpfsClusterReconciler) EnsureComponentSecrets(
+	ctx context.Context,
+	m *clusterv1alpha1.IpfsCluster,
+	secret *corev1.Secret,
+) (ipfsSecret *corev1.Secret, clusterSecret *corev1.Secret, err error) {
+	ipfsSecret = utils.SecretSubset(secret, "ipfs-cluster-"+m.Name+".ipfs", isIPFSSecretKey)
+	clusterSecret = utils.SecretSubset(secret, "ipfs-cluster-"+m.Name+".cluster", isClusterSecretKey)
+	for _, expected := range []*corev1.Secret{ipfsSecret, clusterSecret} {
+		actual := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name:      expected.Name,
+			Namespace: expected.Namespace,
+		}}
+		data := expected.Data
+		if _, err = ctrl.CreateOrUpdate(ctx, r.Client, actual, func() error {
+			if owner := metav1.GetControllerOf(actual); owner != nil && owner.UID != m.UID {
+				return fmt.Errorf("secret is controlled by %s %q", owner.Kind, owner.Name)
+			}
+			actual.Data = data
+			return ctrl.SetControllerReference(m, actual, r.Scheme)
+		}); err != nil {
+			return nil, nil, fmt.Errorf("could not ensure component secret %q: %w", expected.Name, err)
+		}
+	}
+	return ipfsSecret, clusterSecret, nil
+}
+
+// isIPFSSecretKey Returns whether the given key is required by the IPFS containers.
+func isIPFSSecretKey(key string) bool {
+	return key == KeySwarmKey ||
+		strings.HasPrefix(key, KeyPeerIDPrefix) ||
+		strings.HasPrefix(key, KeyPrivateKeyPrefix)
+}
+
+// isClusterSecretKey Returns whether the given key is required by the IPFS Cluster container.
+func isClusterSecretKey(key string) bool {
+	return key == KeyClusterSecret ||
+		key == KeyBootstrapPeerID ||
+		key == KeyBootstrapPeerPrivateKey
+}
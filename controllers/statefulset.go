@@ -2,7 +2,9 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 
 	appsv1 "k8s.io/api/apps/v1"
@@ -10,9 +12,11 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
 
 	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+	"github.com/redhat-et/ipfs-operator/controllers/scripts"
 	"github.com/redhat-et/ipfs-operator/controllers/utils"
 )
 
@@ -58,6 +62,10 @@ const (
 	ipfsImage = "docker.io/ipfs/kubo:v0.16.0"
 	// ipfsNodeDataMountPath Defines the directory where secrets will be mounted.
 	ipfsNodeDataMountPath = "/node-data"
+	// clusterAPITLSMountPath Defines where the TLS Secret of the IPFS Cluster REST API is mounted.
+	clusterAPITLSMountPath = "/cluster-api-tls"
+	// clusterPeerstoreMountPath Defines where the peerstore ConfigMap is mounted.
+	clusterPeerstoreMountPath = "/peerstore"
 )
 
 const (
@@ -74,7 +82,8 @@ func (r *IpfsClusterReconciler) StatefulSet(
 	ctx context.Context,
 	m *clusterv1alpha1.IpfsCluster,
 	serviceName string,
-	secretName string,
+	ipfsSecretName string,
+	clusterSecretName string,
 	configMapBootstrapScriptName string,
 ) (sts *appsv1.StatefulSet, err error) {
 	log := ctrllog.FromContext(ctx)
@@ -86,27 +95,55 @@ func (r *IpfsClusterReconciler) StatefulSet(
 		},
 	}
 
+	optional := true
+	readinessProbe := utils.PeerReadinessProbe(m, portAPI, portAPIHTTP)
+	role := utils.ClusterEvictionRole(m)
 	var ipfsResources corev1.ResourceRequirements
 	if m.Spec.IPFSResources != nil {
 		ipfsResources = *m.Spec.IPFSResources
 	} else {
-		ipfsResources = utils.IPFSContainerResources(m.Spec.IpfsStorage.Value())
+		ipfsResources = utils.EvictionOrderedResources(utils.IPFSResourcesForRole(
+			role, m.Spec.IpfsStorage.Value(), scripts.DefaultConnMgrHighWater, m.Spec.Networking.AcceleratedDHTClient,
+		), role)
+	}
+
+	basicAuthEnvs, err := scripts.ClusterRESTAPIBasicAuthEnvs(m.Spec.ClusterAPIBasicAuthSecretRef)
+	if err != nil {
+		return nil, err
+	}
+	var clusterAPITLSSecret *corev1.Secret
+	if ref := m.Spec.ClusterAPITLSSecretRef; ref != nil {
+		clusterAPITLSSecret = &corev1.Secret{}
+		key := client.ObjectKey{Namespace: m.Namespace, Name: ref.Name}
+		if err = r.Get(ctx, key, clusterAPITLSSecret); err != nil {
+			return nil, fmt.Errorf("could not get cluster api tls secret %q: %w", ref.Name, err)
+		}
+	}
+	// raise the ulimit so the resource manager's share of it, set in the config script, covers the expected peers
+	_, processFDs, err := scripts.ResourceMgrFileDescriptors(scripts.DefaultConnMgrHighWater)
+	if err != nil {
+		return nil, err
 	}
 
 	op, err := ctrl.CreateOrUpdate(ctx, r.Client, sts, func() error {
+		// volume claim templates are immutable, drift is reported by reportVolumeClaimTemplateDrift
+		liveVolumeClaimTemplates := sts.Spec.VolumeClaimTemplates
+		// the hash of the served certificate only changes once its Secret is rotated
+		liveClusterAPITLSHash := sts.Spec.Template.Annotations[utils.AnnotationClusterAPITLSHash]
 		// configure envs
 		configureIPFSEnvs := []corev1.EnvVar{}
 		ipfsEnvs := []corev1.EnvVar{{
-			Name:  "IPFS_FD_MAX",
-			Value: "4096",
+			Name:  scripts.EnvIPFSFDMax,
+			Value: strconv.Itoa(processFDs),
 		}}
+		ipfsEnvs = append(ipfsEnvs, utils.IPFSGOGCEnvs(ipfsResources)...)
 		if !m.Spec.Networking.Public {
 			swarmKeySecret := corev1.EnvVar{
 				Name: EnvIPFSSwarmKey,
 				ValueFrom: &corev1.EnvVarSource{
 					SecretKeyRef: &corev1.SecretKeySelector{
 						LocalObjectReference: corev1.LocalObjectReference{
-							Name: secretName,
+							Name: ipfsSecretName,
 						},
 						Key: KeySwarmKey,
 					},
@@ -117,7 +154,8 @@ func (r *IpfsClusterReconciler) StatefulSet(
 		}
 
 		sts.Spec = appsv1.StatefulSetSpec{
-			Replicas: &m.Spec.Replicas,
+			Replicas:       &m.Spec.Replicas,
+			UpdateStrategy: utils.StatefulSetUpdateStrategy(m.Spec.Replicas),
 			Selector: &metav1.LabelSelector{
 				MatchLabels: map[string]string{
 					"app.kubernetes.io/name": ssName,
@@ -131,6 +169,11 @@ func (r *IpfsClusterReconciler) StatefulSet(
 				},
 				Spec: corev1.PodSpec{
 					ServiceAccountName: ssName,
+					Affinity: &corev1.Affinity{
+						PodAntiAffinity: utils.WeightedPodAntiAffinity(map[string]string{
+							"app.kubernetes.io/name": ssName,
+						}, nil),
+					},
 					InitContainers: []corev1.Container{
 						{
 							Name:  ContainerInitIPFS,
@@ -205,7 +248,8 @@ func (r *IpfsClusterReconciler) StatefulSet(
 									MountPath: ipfsMountPath,
 								},
 							},
-							Resources: ipfsResources,
+							ReadinessProbe: readinessProbe,
+							Resources:      ipfsResources,
 						},
 						{
 							Name:            ContainerIPFSCluster,
@@ -224,7 +268,7 @@ func (r *IpfsClusterReconciler) StatefulSet(
 									ValueFrom: &corev1.EnvVarSource{
 										SecretKeyRef: &corev1.SecretKeySelector{
 											LocalObjectReference: corev1.LocalObjectReference{
-												Name: secretName,
+												Name: clusterSecretName,
 											},
 											Key: "BOOTSTRAP_PEER_ID",
 										},
@@ -235,7 +279,7 @@ func (r *IpfsClusterReconciler) StatefulSet(
 									ValueFrom: &corev1.EnvVarSource{
 										SecretKeyRef: &corev1.SecretKeySelector{
 											LocalObjectReference: corev1.LocalObjectReference{
-												Name: secretName,
+												Name: clusterSecretName,
 											},
 											Key: "BOOTSTRAP_PEER_PRIV_KEY",
 										},
@@ -246,7 +290,7 @@ func (r *IpfsClusterReconciler) StatefulSet(
 									ValueFrom: &corev1.EnvVarSource{
 										SecretKeyRef: &corev1.SecretKeySelector{
 											LocalObjectReference: corev1.LocalObjectReference{
-												Name: secretName,
+												Name: clusterSecretName,
 											},
 											Key: "CLUSTER_SECRET",
 										},
@@ -297,8 +341,14 @@ func (r *IpfsClusterReconciler) StatefulSet(
 									Name:      "configure-script",
 									MountPath: "custom",
 								},
+								{
+									Name:      "cluster-peerstore",
+									MountPath: clusterPeerstoreMountPath,
+									ReadOnly:  true,
+								},
 							},
-							Resources: corev1.ResourceRequirements{},
+							ReadinessProbe: readinessProbe,
+							Resources:      corev1.ResourceRequirements{},
 						},
 					},
 					Volumes: []corev1.Volume{
@@ -316,51 +366,90 @@ func (r *IpfsClusterReconciler) StatefulSet(
 							Name: "ipfs-node-data",
 							VolumeSource: corev1.VolumeSource{
 								Secret: &corev1.SecretVolumeSource{
-									SecretName: secretName,
+									SecretName: ipfsSecretName,
 								},
 							},
 						},
-					},
-				},
-			},
-			VolumeClaimTemplates: []corev1.PersistentVolumeClaim{
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "cluster-storage",
-					},
-					Spec: corev1.PersistentVolumeClaimSpec{
-						AccessModes: []corev1.PersistentVolumeAccessMode{
-							corev1.ReadWriteOnce,
-						},
-						Resources: corev1.ResourceRequirements{
-							Requests: corev1.ResourceList{
-								corev1.ResourceStorage: m.Spec.ClusterStorage,
+						{
+							// the peerstore is only rendered once the peers have been reached
+							Name: "cluster-peerstore",
+							VolumeSource: corev1.VolumeSource{
+								ConfigMap: &corev1.ConfigMapVolumeSource{
+									LocalObjectReference: corev1.LocalObjectReference{
+										Name: peerstoreConfigMapName(m),
+									},
+									Optional: &optional,
+								},
 							},
 						},
 					},
 				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "ipfs-storage",
-					},
-					Spec: corev1.PersistentVolumeClaimSpec{
-						AccessModes: []corev1.PersistentVolumeAccessMode{
-							corev1.ReadWriteOnce,
-						},
-						Resources: corev1.ResourceRequirements{
-							Requests: corev1.ResourceList{
-								corev1.ResourceStorage: m.Spec.IpfsStorage,
-							},
-						},
+			},
+			VolumeClaimTemplates: desiredVolumeClaimTemplates(m),
+			ServiceName:          serviceName,
+		}
+
+		// Fail fast on a malformed swarm key before IPFS gets configured.
+		if !m.Spec.Networking.Public {
+			validateSwarmKey := utils.SwarmKeyValidationContainer(ipfsImage, ipfsSecretName, KeySwarmKey)
+			sts.Spec.Template.Spec.InitContainers = append(
+				[]corev1.Container{validateSwarmKey},
+				sts.Spec.Template.Spec.InitContainers...,
+			)
+		}
+
+		if !sts.CreationTimestamp.IsZero() {
+			sts.Spec.VolumeClaimTemplates = liveVolumeClaimTemplates
+		}
+
+		// Read the REST API credentials from their Secret rather than the ConfigMap, and reject
+		// writes to the pinset in follower mode.
+		for i := range sts.Spec.Template.Spec.Containers {
+			container := &sts.Spec.Template.Spec.Containers[i]
+			if container.Name == ContainerIPFSCluster {
+				container.Env = append(container.Env, basicAuthEnvs...)
+				if m.Spec.FollowerMode {
+					container.Env = append(container.Env, scripts.ClusterFollowerModeEnvs(true)...)
+				}
+			}
+		}
+
+		// Serve the REST API over HTTPS, rolling the peers out once the certificate is rotated.
+		if clusterAPITLSSecret != nil {
+			for i := range sts.Spec.Template.Spec.Containers {
+				container := &sts.Spec.Template.Spec.Containers[i]
+				if container.Name == ContainerIPFSCluster {
+					container.Env = append(container.Env, scripts.ClusterRESTAPITLSEnvs(clusterAPITLSMountPath)...)
+					container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+						Name:      "cluster-api-tls",
+						MountPath: clusterAPITLSMountPath,
+						ReadOnly:  true,
+					})
+				}
+			}
+			sts.Spec.Template.Spec.Volumes = append(sts.Spec.Template.Spec.Volumes, corev1.Volume{
+				Name: "cluster-api-tls",
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{
+						SecretName: clusterAPITLSSecret.Name,
 					},
 				},
-			},
-			ServiceName: serviceName,
+			})
 		}
 
 		// Add a follower container for each follow.
 		follows := followContainers(m)
 		sts.Spec.Template.Spec.Containers = append(sts.Spec.Template.Spec.Containers, follows...)
+		// the resources are ordered when sized above, so that the user's own are never rewritten
+		utils.AnnotateEvictionRole(&sts.Spec.Template, role, "")
+		if clusterAPITLSSecret != nil {
+			if liveClusterAPITLSHash != "" {
+				sts.Spec.Template.Annotations[utils.AnnotationClusterAPITLSHash] = liveClusterAPITLSHash
+			}
+			if utils.ApplyClusterAPITLSRotation(sts, clusterAPITLSSecret) {
+				log.Info("rolling out the cluster api tls certificate", "secret", clusterAPITLSSecret.Name)
+			}
+		}
 		if innerErr := ctrl.SetControllerReference(m, sts, r.Scheme); innerErr != nil {
 			return innerErr
 		}
@@ -375,6 +464,30 @@ func (r *IpfsClusterReconciler) StatefulSet(
 	return sts, nil
 }
 
+// recreateOutdatedPods Deletes the pods of a StatefulSet using the OnDelete strategy
+// which don't run its latest revision, so that they get recreated with it.
+func (r *IpfsClusterReconciler) recreateOutdatedPods(ctx context.Context, sts *appsv1.StatefulSet) error {
+	log := ctrllog.FromContext(ctx)
+	if sts.Spec.UpdateStrategy.Type != appsv1.OnDeleteStatefulSetStrategyType {
+		return nil
+	}
+	pods := &corev1.PodList{}
+	if err := r.List(
+		ctx, pods, client.InNamespace(sts.Namespace), client.MatchingLabels(sts.Spec.Selector.MatchLabels),
+	); err != nil {
+		return fmt.Errorf("could not list statefulset pods: %w", err)
+	}
+	outdated := utils.OutdatedPods(sts, pods.Items)
+	for i := range outdated {
+		pod := &outdated[i]
+		log.Info("recreating outdated pod", "pod", pod.Name, "revision", sts.Status.UpdateRevision)
+		if err := r.Delete(ctx, pod); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("could not delete pod %q: %w", pod.Name, err)
+		}
+	}
+	return nil
+}
+
 // followContainers Returns a list of container objects which follow the given followParams.
 func followContainers(m *clusterv1alpha1.IpfsCluster) []corev1.Container {
 	// objects need to be RFC-1123 compliant, and k8s uses this regex to test.
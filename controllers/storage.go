@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+// volumeClaimTemplates Lists the names of the volume claim templates of the IPFS Cluster StatefulSet.
+var volumeClaimTemplates = []string{"cluster-storage", "ipfs-storage"}
+
+// desiredVolumeClaimTemplates Returns the volume claim templates of the IPFS Cluster StatefulSet.
+func desiredVolumeClaimTemplates(m *clusterv1alpha1.IpfsCluster) []corev1.PersistentVolumeClaim {
+	ipfsStorageClass := utils.SelectStorageClass(m.Spec.Storage, m.Spec.IpfsStorage, utils.ClusterEvictionRole(m))
+	return []corev1.PersistentVolumeClaim{
+		utils.BuildDataPVC("cluster-storage", m.Spec.ClusterStorage, ""),
+		utils.BuildDataPVC("ipfs-storage", m.Spec.IpfsStorage, ipfsStorageClass),
+	}
+}
+
+// reportVolumeClaimTemplateDrift Records on the status of the instance whether the volume claim templates of
+// the live StatefulSet differ from the desired storage, which can't be applied by an update.
+func (r *IpfsClusterReconciler) reportVolumeClaimTemplateDrift(
+	ctx context.Context,
+	m *clusterv1alpha1.IpfsCluster,
+	sts *appsv1.StatefulSet,
+) {
+	log := ctrllog.FromContext(ctx)
+	condition := metav1.Condition{
+		Type:    clusterv1alpha1.ConditionVolumeClaimTemplatesDrifted,
+		Status:  metav1.ConditionFalse,
+		Reason:  clusterv1alpha1.VolumeClaimTemplatesReasonInSync,
+		Message: "volume claim templates match the desired storage",
+	}
+	drift := utils.VolumeClaimTemplateDrift(sts.Spec.VolumeClaimTemplates, desiredVolumeClaimTemplates(m))
+	if len(drift) > 0 {
+		log.Info("volume claim templates drifted from the desired storage", "drift", drift)
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = clusterv1alpha1.VolumeClaimTemplatesReasonDrifted
+		condition.Message = "recreate the statefulset or expand its volume claims to apply: " + strings.Join(drift, "; ")
+	}
+	meta.SetStatusCondition(&m.Status.Conditions, condition)
+}
+
+// reportStorageShrink Records on the status of the instance whether its storage was lowered below
+// the storage requested by the volume claim templates of the live StatefulSet, which the PVCs of the
+// peers were created with. PVCs can't shrink, so such a change is reported rather than applied.
+func (r *IpfsClusterReconciler) reportStorageShrink(
+	ctx context.Context,
+	m *clusterv1alpha1.IpfsCluster,
+	sts *appsv1.StatefulSet,
+) {
+	log := ctrllog.FromContext(ctx)
+	created := &clusterv1alpha1.IpfsCluster{}
+	for _, template := range sts.Spec.VolumeClaimTemplates {
+		switch template.Name {
+		case "ipfs-storage":
+			created.Spec.IpfsStorage = template.Spec.Resources.Requests[corev1.ResourceStorage]
+		case "cluster-storage":
+			created.Spec.ClusterStorage = template.Spec.Resources.Requests[corev1.ResourceStorage]
+		}
+	}
+	condition := metav1.Condition{
+		Type:    clusterv1alpha1.ConditionStorageShrinkRequested,
+		Status:  metav1.ConditionFalse,
+		Reason:  clusterv1alpha1.StorageShrinkReasonNotRequested,
+		Message: "the desired storage is at least the storage the volume claims were created with",
+	}
+	if err := utils.ValidateStorageUpdate(created, m); err != nil {
+		log.Info("not shrinking the storage", "reason", err.Error())
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = clusterv1alpha1.StorageShrinkReasonRequested
+		condition.Message = err.Error()
+	}
+	meta.SetStatusCondition(&m.Status.Conditions, condition)
+}
+
+// reportOrphanedVolumeClaims Records the PVCs left behind by removed peers on the status of the instance,
+// along with the storage that could be reclaimed by deleting them.
+func (r *IpfsClusterReconciler) reportOrphanedVolumeClaims(
+	ctx context.Context,
+	m *clusterv1alpha1.IpfsCluster,
+) error {
+	log := ctrllog.FromContext(ctx)
+	stsName := "ipfs-cluster-" + m.Name
+	orphaned, reclaimable, err := utils.OrphanedVolumeClaims(
+		ctx, r.Client, m.Namespace, stsName, volumeClaimTemplates, m.Spec.Replicas,
+	)
+	if err != nil {
+		return err
+	}
+	m.Status.OrphanedVolumeClaims = nil
+	m.Status.ReclaimableStorage = nil
+	if len(orphaned) > 0 {
+		names := make([]string, 0, len(orphaned))
+		for _, pvc := range orphaned {
+			names = append(names, pvc.Name)
+		}
+		log.Info("found orphaned volume claims", "pvcs", names, "reclaimable", reclaimable.String())
+		m.Status.OrphanedVolumeClaims = names
+		m.Status.ReclaimableStorage = &reclaimable
+	}
+	return nil
+}
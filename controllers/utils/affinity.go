@@ -0,0 +1,52 @@
+package utils
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// maxAffinityWeight Is the largest weight Kubernetes accepts for a preferred scheduling term.
+const maxAffinityWeight = 100
+
+// DefaultTopologyHierarchy Lists the failure domains peers are spread across, from the
+// widest to the narrowest.
+var DefaultTopologyHierarchy = []string{
+	corev1.LabelTopologyRegion,
+	corev1.LabelTopologyZone,
+	corev1.LabelHostname,
+}
+
+// WeightedPodAntiAffinity Returns a pod anti-affinity which prefers scheduling the pods matching
+// the given labels apart from each other across every level of the topology-key hierarchy.
+// Wider failure domains come first and weigh more, so peers are spread across regions before
+// zones, and across zones before nodes. Empty and repeated keys are skipped, and the
+// DefaultTopologyHierarchy is used when no keys are given.
+func WeightedPodAntiAffinity(matchLabels map[string]string, topologyKeys []string) *corev1.PodAntiAffinity {
+	if len(topologyKeys) == 0 {
+		topologyKeys = DefaultTopologyHierarchy
+	}
+	keys := make([]string, 0, len(topologyKeys))
+	seen := make(map[string]bool, len(topologyKeys))
+	for _, key := range topologyKeys {
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	terms := make([]corev1.WeightedPodAffinityTerm, 0, len(keys))
+	for i, key := range keys {
+		terms = append(terms, corev1.WeightedPodAffinityTerm{
+			Weight: int32(maxAffinityWeight * (len(keys) - i) / len(keys)),
+			PodAffinityTerm: corev1.PodAffinityTerm{
+				LabelSelector: &metav1.LabelSelector{
+					MatchLabels: matchLabels,
+				},
+				TopologyKey: key,
+			},
+		})
+	}
+	return &corev1.PodAntiAffinity{
+		PreferredDuringSchedulingIgnoredDuringExecution: terms,
+	}
+}
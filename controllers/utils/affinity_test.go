@@ -0,0 +1,41 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Pod anti-affinity", func() {
+	labels := map[string]string{"app.kubernetes.io/name": "ipfs-cluster-test"}
+
+	topologyKeys := func(terms []corev1.WeightedPodAffinityTerm) []string {
+		keys := make([]string, 0, len(terms))
+		for _, term := range terms {
+			keys = append(keys, term.PodAffinityTerm.TopologyKey)
+		}
+		return keys
+	}
+
+	It("weighs the wider failure domains more", func() {
+		terms := utils.WeightedPodAntiAffinity(labels, nil).PreferredDuringSchedulingIgnoredDuringExecution
+		Expect(topologyKeys(terms)).To(Equal(utils.DefaultTopologyHierarchy))
+		Expect(terms[0].Weight).To(BeEquivalentTo(100))
+		for i := 1; i < len(terms); i++ {
+			Expect(terms[i].Weight).To(BeNumerically("<", terms[i-1].Weight))
+			Expect(terms[i].Weight).To(BeNumerically(">", 0))
+		}
+		for _, term := range terms {
+			Expect(term.PodAffinityTerm.LabelSelector.MatchLabels).To(Equal(labels))
+		}
+	})
+
+	It("follows a custom hierarchy", func() {
+		hierarchy := []string{"example.com/datacenter", "", corev1.LabelTopologyZone, "example.com/datacenter"}
+		terms := utils.WeightedPodAntiAffinity(labels, hierarchy).PreferredDuringSchedulingIgnoredDuringExecution
+		Expect(topologyKeys(terms)).To(Equal([]string{"example.com/datacenter", corev1.LabelTopologyZone}))
+		Expect(terms[0].Weight).To(BeNumerically(">", terms[1].Weight))
+	})
+})
@@ -0,0 +1,112 @@
+package utils
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// PortNameIPFSAPI Defines the name of the go-ipfs HTTP API port on the IPFS container.
+	PortNameIPFSAPI = "api"
+	// maxPort Is the highest valid TCP or UDP port.
+	maxPort int32 = 65535
+)
+
+// IPFSAPIService Returns a ClusterIP Service exposing the go-ipfs HTTP API of the pods
+// matching the selector on the given port. The API grants full control over the node,
+// so the Service is never exposed outside of the cluster.
+func IPFSAPIService(name, namespace string, selector map[string]string, port int32) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: corev1.ServiceSpec{
+			Type:     corev1.ServiceTypeClusterIP,
+			Selector: selector,
+			Ports: []corev1.ServicePort{
+				{
+					Name:       PortNameIPFSAPI,
+					Protocol:   corev1.ProtocolTCP,
+					Port:       port,
+					TargetPort: intstr.FromString(PortNameIPFSAPI),
+				},
+			},
+		},
+	}
+}
+
+// portRange Returns a NetworkPolicy port covering the given range, or nil if it is empty.
+func portRange(protocol corev1.Protocol, start, end int32) *networkingv1.NetworkPolicyPort {
+	if start > end {
+		return nil
+	}
+	port := intstr.FromInt(int(start))
+	policyPort := &networkingv1.NetworkPolicyPort{
+		Protocol: &protocol,
+		Port:     &port,
+	}
+	if end > start {
+		policyPort.EndPort = &end
+	}
+	return policyPort
+}
+
+// IPFSAPINetworkPolicy Returns a NetworkPolicy which only admits pods matching the client
+// selector to the go-ipfs API port of the pods matching the pod selector.
+// Since a pod selected by any NetworkPolicy rejects all traffic not allowed by one, every
+// other port stays open so that swarm and gateway traffic is unaffected.
+func IPFSAPINetworkPolicy(
+	name, namespace string,
+	podSelector, clientSelector map[string]string,
+	port int32,
+) *networkingv1.NetworkPolicy {
+	tcp := corev1.ProtocolTCP
+	apiPort := intstr.FromInt(int(port))
+	otherPorts := make([]networkingv1.NetworkPolicyPort, 0, 4)
+	for _, r := range []*networkingv1.NetworkPolicyPort{
+		portRange(corev1.ProtocolTCP, 1, port-1),
+		portRange(corev1.ProtocolTCP, port+1, maxPort),
+		portRange(corev1.ProtocolUDP, 1, maxPort),
+	} {
+		if r != nil {
+			otherPorts = append(otherPorts, *r)
+		}
+	}
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{
+				MatchLabels: podSelector,
+			},
+			PolicyTypes: []networkingv1.PolicyType{
+				networkingv1.PolicyTypeIngress,
+			},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				{
+					From: []networkingv1.NetworkPolicyPeer{
+						{
+							PodSelector: &metav1.LabelSelector{
+								MatchLabels: clientSelector,
+							},
+						},
+					},
+					Ports: []networkingv1.NetworkPolicyPort{
+						{
+							Protocol: &tcp,
+							Port:     &apiPort,
+						},
+					},
+				},
+				{
+					Ports: otherPorts,
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,54 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("IPFS API service", func() {
+	podSelector := map[string]string{"app.kubernetes.io/name": "ipfs-cluster-test"}
+	clientSelector := map[string]string{"ipfs.io/api-client": "true"}
+
+	It("is only reachable from inside the cluster", func() {
+		svc := utils.IPFSAPIService("ipfs-api-test", "test", podSelector, 5001)
+		Expect(svc.Spec.Type).To(Equal(corev1.ServiceTypeClusterIP))
+		Expect(svc.Spec.Selector).To(Equal(podSelector))
+		Expect(svc.Spec.Ports).To(HaveLen(1))
+		Expect(svc.Spec.Ports[0].Port).To(BeEquivalentTo(5001))
+		Expect(svc.Spec.Ports[0].NodePort).To(BeZero())
+	})
+
+	It("restricts the API port to the labeled clients", func() {
+		policy := utils.IPFSAPINetworkPolicy("ipfs-api-test", "test", podSelector, clientSelector, 5001)
+		Expect(policy.Spec.PodSelector.MatchLabels).To(Equal(podSelector))
+		Expect(policy.Spec.Ingress).To(HaveLen(2))
+
+		apiRule := policy.Spec.Ingress[0]
+		Expect(apiRule.From).To(HaveLen(1))
+		Expect(apiRule.From[0].PodSelector.MatchLabels).To(Equal(clientSelector))
+		Expect(apiRule.From[0].NamespaceSelector).To(BeNil())
+		Expect(apiRule.Ports).To(HaveLen(1))
+		Expect(apiRule.Ports[0].Port.IntValue()).To(Equal(5001))
+	})
+
+	It("leaves every other port open", func() {
+		policy := utils.IPFSAPINetworkPolicy("ipfs-api-test", "test", podSelector, clientSelector, 5001)
+		openRule := policy.Spec.Ingress[1]
+		Expect(openRule.From).To(BeEmpty())
+		for _, p := range openRule.Ports {
+			if *p.Protocol != corev1.ProtocolTCP {
+				continue
+			}
+			start := int32(p.Port.IntValue())
+			end := start
+			if p.EndPort != nil {
+				end = *p.EndPort
+			}
+			Expect(start > 5001 || end < 5001).To(BeTrue(), "API port must not be open to everyone")
+		}
+		Expect(openRule.Ports).To(HaveLen(3))
+	})
+})
@@ -0,0 +1,245 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// S3AccessKeyIDKey Defines the key holding the access key id in S3 credential Secrets.
+	S3AccessKeyIDKey = "AWS_ACCESS_KEY_ID"
+	// S3SecretAccessKeyKey Defines the key holding the secret access key in S3 credential Secrets.
+	S3SecretAccessKeyKey = "AWS_SECRET_ACCESS_KEY"
+	// BackupMountPath Is where the backup CronJob writes the pinset to.
+	BackupMountPath = "/backup"
+	// RestoreMountPath Is where the restore Job reads the backed up pinset from.
+	RestoreMountPath = "/restore"
+	// S3UploadImage Is the image uploading backups to S3 compatible buckets.
+	S3UploadImage = "docker.io/amazon/aws-cli:2.8.3"
+)
+
+// S3BackupDestination Describes an S3 compatible bucket backups are uploaded to.
+type S3BackupDestination struct {
+	// Endpoint Is the URL of the S3 API, e.g. `https://s3.eu-west-1.amazonaws.com`.
+	Endpoint string
+	// Bucket Is the name of the bucket backups are stored in.
+	Bucket string
+	// CredentialsSecretName Names the Secret holding the S3AccessKeyIDKey and S3SecretAccessKeyKey.
+	CredentialsSecretName string
+}
+
+// BackupDestination Describes where backups are written to. Exactly one of
+// PersistentVolumeClaimName and S3 must be set.
+type BackupDestination struct {
+	PersistentVolumeClaimName string
+	S3                        *S3BackupDestination
+}
+
+// ValidateBackupDestination Ensures that the given destination can be written to by the
+// backup CronJobs in the namespace before they get scheduled, so a misconfigured destination
+// is reported once rather than failing silently on every run.
+func ValidateBackupDestination(ctx context.Context, c client.Reader, namespace string, dest BackupDestination) error {
+	switch {
+	case dest.PersistentVolumeClaimName != "" && dest.S3 != nil:
+		return fmt.Errorf("backup destination cannot be both a volume claim and an s3 bucket")
+	case dest.PersistentVolumeClaimName != "":
+		return validateBackupVolumeClaim(ctx, c, namespace, dest.PersistentVolumeClaimName)
+	case dest.S3 != nil:
+		return validateBackupBucket(ctx, c, namespace, dest.S3)
+	default:
+		return fmt.Errorf("no backup destination was given")
+	}
+}
+
+// validateBackupVolumeClaim Ensures the named claim exists, is bound and can be mounted read-write.
+func validateBackupVolumeClaim(ctx context.Context, c client.Reader, namespace, name string) error {
+	pvc := &corev1.PersistentVolumeClaim{}
+	err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, pvc)
+	if errors.IsNotFound(err) {
+		return fmt.Errorf("backup volume claim %q does not exist", name)
+	}
+	if err != nil {
+		return fmt.Errorf("could not get backup volume claim %q: %w", name, err)
+	}
+	if pvc.DeletionTimestamp != nil {
+		return fmt.Errorf("backup volume claim %q is being deleted", name)
+	}
+	if pvc.Status.Phase == corev1.ClaimLost {
+		return fmt.Errorf("backup volume claim %q lost its volume", name)
+	}
+	for _, mode := range pvc.Spec.AccessModes {
+		if mode != corev1.ReadOnlyMany {
+			return nil
+		}
+	}
+	return fmt.Errorf("backup volume claim %q cannot be mounted read-write", name)
+}
+
+// validateBackupBucket Ensures the bucket has a well-formed endpoint and that its credentials are present.
+func validateBackupBucket(ctx context.Context, c client.Reader, namespace string, dest *S3BackupDestination) error {
+	endpoint, err := url.Parse(dest.Endpoint)
+	if err != nil || (endpoint.Scheme != "https" && endpoint.Scheme != "http") || endpoint.Host == "" {
+		return fmt.Errorf("invalid s3 endpoint %q", dest.Endpoint)
+	}
+	if dest.Bucket == "" {
+		return fmt.Errorf("no s3 bucket was given")
+	}
+	if dest.CredentialsSecretName == "" {
+		return fmt.Errorf("no s3 credentials secret was given")
+	}
+	secret := &corev1.Secret{}
+	err = c.Get(ctx, types.NamespacedName{Name: dest.CredentialsSecretName, Namespace: namespace}, secret)
+	if errors.IsNotFound(err) {
+		return fmt.Errorf("s3 credentials secret %q does not exist", dest.CredentialsSecretName)
+	}
+	if err != nil {
+		return fmt.Errorf("could not get s3 credentials secret %q: %w", dest.CredentialsSecretName, err)
+	}
+	for _, key := range []string{S3AccessKeyIDKey, S3SecretAccessKeyKey} {
+		if len(secret.Data[key]) == 0 {
+			return fmt.Errorf("s3 credentials secret %q is missing %s", dest.CredentialsSecretName, key)
+		}
+	}
+	return nil
+}
+
+// backupScript Lists the pinset through the IPFS Cluster REST API at the given address into a
+// timestamped file below BackupMountPath. The listing is written to a temporary file first, so
+// that a failed run leaves no partial backup behind.
+const backupScript = `set -e
+backup="%[2]s/pinset-$(date +%%Y%%m%%d%%H%%M%%S).json"
+ipfs-cluster-ctl --host %[1]q --enc=json pin ls > "${backup}.tmp"
+mv "${backup}.tmp" "${backup}"
+echo "backed up the pinset to ${backup}"
+`
+
+// BackupCronJob Returns a CronJob which backs up the pinset through the IPFS Cluster REST API at
+// clusterAPIAddr on the given schedule. Backups are written straight into a volume claim, or staged
+// in an emptyDir and uploaded with the aws cli for S3 destinations. The destination is expected to
+// have been checked with ValidateBackupDestination.
+func BackupCronJob(name, namespace, image, clusterAPIAddr, schedule string, dest BackupDestination) *batchv1.CronJob {
+	var backoffLimit int32
+	backup := corev1.Container{
+		Name:    "backup",
+		Image:   image,
+		Command: []string{"sh", "-c", fmt.Sprintf(backupScript, clusterAPIAddr, BackupMountPath)},
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: "backup", MountPath: BackupMountPath},
+		},
+	}
+	podSpec := corev1.PodSpec{RestartPolicy: corev1.RestartPolicyNever}
+	if dest.S3 != nil {
+		podSpec.InitContainers = []corev1.Container{backup}
+		podSpec.Containers = []corev1.Container{
+			{
+				Name:  "upload",
+				Image: S3UploadImage,
+				Command: []string{
+					"aws", "--endpoint-url", dest.S3.Endpoint,
+					"s3", "cp", "--recursive", BackupMountPath, "s3://" + dest.S3.Bucket + "/",
+				},
+				EnvFrom: []corev1.EnvFromSource{
+					{
+						SecretRef: &corev1.SecretEnvSource{
+							LocalObjectReference: corev1.LocalObjectReference{Name: dest.S3.CredentialsSecretName},
+						},
+					},
+				},
+				VolumeMounts: backup.VolumeMounts,
+			},
+		}
+		podSpec.Volumes = []corev1.Volume{
+			{Name: "backup", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+		}
+	} else {
+		podSpec.Containers = []corev1.Container{backup}
+		podSpec.Volumes = []corev1.Volume{
+			{
+				Name: "backup",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+						ClaimName: dest.PersistentVolumeClaimName,
+					},
+				},
+			},
+		}
+	}
+	return &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule:          schedule,
+			ConcurrencyPolicy: batchv1.ForbidConcurrent,
+			JobTemplate: batchv1.JobTemplateSpec{
+				Spec: batchv1.JobSpec{
+					BackoffLimit: &backoffLimit,
+					Template:     corev1.PodTemplateSpec{Spec: podSpec},
+				},
+			},
+		},
+	}
+}
+
+// RestoreScript Returns a shell script which adds every pin of the backup at the given path through
+// the IPFS Cluster REST API at clusterAPIAddr, keeping the replication factors it was backed up with.
+func RestoreScript(clusterAPIAddr, backup string) string {
+	script := "set -e\n"
+	script += fmt.Sprintf("(%s) < %q | while read -r cid min max; do\n", pinsetFilter, backup)
+	script += fmt.Sprintf("  ipfs-cluster-ctl --host %q pin add ", clusterAPIAddr)
+	script += "--replication-min \"$min\" --replication-max \"$max\" \"$cid\"\n"
+	script += "done\n"
+	return script
+}
+
+// RestoreJob Returns a one-shot Job which restores the pinset backed up by the backup CronJob into
+// the given file of a volume claim. The target is expected to have been checked with
+// ValidateRestoreTarget, since the restored pins are merged into any pinset the cluster holds.
+func RestoreJob(name, namespace, image, clusterAPIAddr, claimName, backup string) *batchv1.Job {
+	var backoffLimit int32
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:    "restore",
+							Image:   image,
+							Command: []string{"sh", "-c", RestoreScript(clusterAPIAddr, path.Join(RestoreMountPath, backup))},
+							VolumeMounts: []corev1.VolumeMount{
+								{Name: "backup", MountPath: RestoreMountPath, ReadOnly: true},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "backup",
+							VolumeSource: corev1.VolumeSource{
+								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+									ClaimName: claimName,
+									ReadOnly:  true,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,157 @@
+package utils_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Backup destination validation", func() {
+	ctx := context.TODO()
+
+	newPVC := func(name string, modes ...corev1.PersistentVolumeAccessMode) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test"},
+			Spec:       corev1.PersistentVolumeClaimSpec{AccessModes: modes},
+			Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound},
+		}
+	}
+
+	s3Destination := func(secretName string) utils.BackupDestination {
+		return utils.BackupDestination{
+			S3: &utils.S3BackupDestination{
+				Endpoint:              "https://s3.example.com",
+				Bucket:                "backups",
+				CredentialsSecretName: secretName,
+			},
+		}
+	}
+
+	It("accepts an existing writable volume claim", func() {
+		c := fake.NewClientBuilder().WithObjects(newPVC("backups", corev1.ReadWriteOnce)).Build()
+		dest := utils.BackupDestination{PersistentVolumeClaimName: "backups"}
+		Expect(utils.ValidateBackupDestination(ctx, c, "test", dest)).To(Succeed())
+	})
+
+	It("reports a missing volume claim", func() {
+		c := fake.NewClientBuilder().Build()
+		dest := utils.BackupDestination{PersistentVolumeClaimName: "backups"}
+		err := utils.ValidateBackupDestination(ctx, c, "test", dest)
+		Expect(err).To(MatchError(ContainSubstring("does not exist")))
+	})
+
+	It("reports a read-only volume claim", func() {
+		c := fake.NewClientBuilder().WithObjects(newPVC("backups", corev1.ReadOnlyMany)).Build()
+		dest := utils.BackupDestination{PersistentVolumeClaimName: "backups"}
+		Expect(utils.ValidateBackupDestination(ctx, c, "test", dest)).NotTo(Succeed())
+	})
+
+	It("accepts a bucket with credentials", func() {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "s3-credentials", Namespace: "test"},
+			Data: map[string][]byte{
+				utils.S3AccessKeyIDKey:     []byte("id"),
+				utils.S3SecretAccessKeyKey: []byte("secret"),
+			},
+		}
+		c := fake.NewClientBuilder().WithObjects(secret).Build()
+		Expect(utils.ValidateBackupDestination(ctx, c, "test", s3Destination("s3-credentials"))).To(Succeed())
+	})
+
+	It("reports missing s3 credentials", func() {
+		c := fake.NewClientBuilder().Build()
+		err := utils.ValidateBackupDestination(ctx, c, "test", s3Destination("s3-credentials"))
+		Expect(err).To(MatchError(ContainSubstring("does not exist")))
+
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "s3-credentials", Namespace: "test"},
+			Data:       map[string][]byte{utils.S3AccessKeyIDKey: []byte("id")},
+		}
+		c = fake.NewClientBuilder().WithObjects(secret).Build()
+		err = utils.ValidateBackupDestination(ctx, c, "test", s3Destination("s3-credentials"))
+		Expect(err).To(MatchError(ContainSubstring(utils.S3SecretAccessKeyKey)))
+
+		err = utils.ValidateBackupDestination(ctx, c, "test", s3Destination(""))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("requires exactly one destination", func() {
+		c := fake.NewClientBuilder().Build()
+		Expect(utils.ValidateBackupDestination(ctx, c, "test", utils.BackupDestination{})).NotTo(Succeed())
+		dest := s3Destination("s3-credentials")
+		dest.PersistentVolumeClaimName = "backups"
+		Expect(utils.ValidateBackupDestination(ctx, c, "test", dest)).NotTo(Succeed())
+	})
+})
+
+var _ = Describe("Backup CronJob", func() {
+	const apiAddr = "/dns4/ipfs-cluster-test/tcp/9094"
+
+	It("writes the pinset into the volume claim", func() {
+		dest := utils.BackupDestination{PersistentVolumeClaimName: "backups"}
+		cronJob := utils.BackupCronJob("backup", "test", "cluster-image", apiAddr, "0 3 * * *", dest)
+		Expect(cronJob.Spec.Schedule).To(Equal("0 3 * * *"))
+		Expect(cronJob.Spec.ConcurrencyPolicy).To(Equal(batchv1.ForbidConcurrent))
+
+		podSpec := cronJob.Spec.JobTemplate.Spec.Template.Spec
+		Expect(podSpec.InitContainers).To(BeEmpty())
+		Expect(podSpec.Containers).To(HaveLen(1))
+		Expect(podSpec.Containers[0].Command[2]).To(ContainSubstring(
+			`ipfs-cluster-ctl --host "` + apiAddr + `" --enc=json pin ls`,
+		))
+		Expect(podSpec.Containers[0].VolumeMounts).To(ContainElement(
+			corev1.VolumeMount{Name: "backup", MountPath: utils.BackupMountPath},
+		))
+		Expect(podSpec.Volumes).To(HaveLen(1))
+		Expect(podSpec.Volumes[0].PersistentVolumeClaim.ClaimName).To(Equal("backups"))
+	})
+
+	It("stages the pinset before uploading it to the bucket", func() {
+		dest := utils.BackupDestination{
+			S3: &utils.S3BackupDestination{
+				Endpoint:              "https://s3.example.com",
+				Bucket:                "backups",
+				CredentialsSecretName: "s3-credentials",
+			},
+		}
+		cronJob := utils.BackupCronJob("backup", "test", "cluster-image", apiAddr, "0 3 * * *", dest)
+
+		podSpec := cronJob.Spec.JobTemplate.Spec.Template.Spec
+		Expect(podSpec.InitContainers).To(HaveLen(1))
+		Expect(podSpec.InitContainers[0].Name).To(Equal("backup"))
+		Expect(podSpec.Containers).To(HaveLen(1))
+		upload := podSpec.Containers[0]
+		Expect(upload.Image).To(Equal(utils.S3UploadImage))
+		Expect(upload.Command).To(ContainElements("https://s3.example.com", "s3://backups/"))
+		Expect(upload.EnvFrom[0].SecretRef.Name).To(Equal("s3-credentials"))
+		Expect(podSpec.Volumes).To(HaveLen(1))
+		Expect(podSpec.Volumes[0].EmptyDir).NotTo(BeNil())
+	})
+})
+
+var _ = Describe("Restore Job", func() {
+	const apiAddr = "/dns4/ipfs-cluster-test/tcp/9094"
+
+	It("re-adds the backed up pins at their replication factors", func() {
+		job := utils.RestoreJob("restore", "test", "cluster-image", apiAddr, "backups", "pinset-20221001030000.json")
+		podSpec := job.Spec.Template.Spec
+		Expect(podSpec.RestartPolicy).To(Equal(corev1.RestartPolicyNever))
+		Expect(podSpec.Containers).To(HaveLen(1))
+		script := podSpec.Containers[0].Command[2]
+		Expect(script).To(ContainSubstring(`< "/restore/pinset-20221001030000.json"`))
+		Expect(script).To(ContainSubstring(
+			`ipfs-cluster-ctl --host "` + apiAddr + `" pin add --replication-min "$min" --replication-max "$max" "$cid"`,
+		))
+		Expect(podSpec.Containers[0].VolumeMounts).To(ContainElement(
+			corev1.VolumeMount{Name: "backup", MountPath: utils.RestoreMountPath, ReadOnly: true},
+		))
+		Expect(podSpec.Volumes[0].PersistentVolumeClaim.ClaimName).To(Equal("backups"))
+	})
+})
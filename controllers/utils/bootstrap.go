@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// podOrdinal Returns the ordinal of a pod belonging to the given StatefulSet.
+func podOrdinal(stsName string, pod *corev1.Pod) (int32, bool) {
+	suffix := strings.TrimPrefix(pod.Name, stsName+"-")
+	if suffix == pod.Name {
+		return 0, false
+	}
+	ordinal, err := strconv.ParseInt(suffix, 10, 32)
+	if err != nil || ordinal < 0 {
+		return 0, false
+	}
+	return int32(ordinal), true
+}
+
+// isPodReady Returns whether the pod reports being ready and isn't terminating.
+func isPodReady(pod *corev1.Pod) bool {
+	if pod.DeletionTimestamp != nil {
+		return false
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// BootstrapFailoverPeers Returns the IPFS Cluster addresses of the ready peers of a StatefulSet,
+// which joining peers try in turn so that a join succeeds as long as any of them is up. Peers are
+// ordered by ordinal so every joining peer tries the same, longest-lived peers first. Pods whose
+// ordinal has no known peer ID, and pods which aren't ready, are left out.
+func BootstrapFailoverPeers(
+	pods []corev1.Pod,
+	stsName string,
+	serviceName string,
+	port int,
+	peerIDs map[int32]peer.ID,
+) []string {
+	ordinals := make([]int32, 0, len(pods))
+	for i := range pods {
+		pod := &pods[i]
+		ordinal, ok := podOrdinal(stsName, pod)
+		if !ok || !isPodReady(pod) {
+			continue
+		}
+		if _, ok = peerIDs[ordinal]; !ok {
+			continue
+		}
+		ordinals = append(ordinals, ordinal)
+	}
+	sort.Slice(ordinals, func(i, j int) bool {
+		return ordinals[i] < ordinals[j]
+	})
+	addrs := make([]string, 0, len(ordinals))
+	for _, ordinal := range ordinals {
+		addrs = append(addrs, fmt.Sprintf("/dns4/%s-%d.%s/tcp/%d/p2p/%s",
+			stsName, ordinal, serviceName, port, peerIDs[ordinal]))
+	}
+	return addrs
+}
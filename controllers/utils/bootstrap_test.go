@@ -0,0 +1,73 @@
+package utils_test
+
+import (
+	"github.com/libp2p/go-libp2p/core/peer"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Bootstrap failover peers", func() {
+	const sts = "ipfs-cluster-test"
+
+	newPod := func(name string, ready corev1.ConditionStatus) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: corev1.PodStatus{
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: ready}},
+			},
+		}
+	}
+
+	var peerIDs map[int32]peer.ID
+
+	BeforeEach(func() {
+		peerIDs = map[int32]peer.ID{}
+		for i := int32(0); i < 4; i++ {
+			id, _, err := utils.GenerateIdentity()
+			Expect(err).NotTo(HaveOccurred())
+			peerIDs[i] = id
+		}
+	})
+
+	It("excludes unready peers", func() {
+		terminating := newPod(sts+"-3", corev1.ConditionTrue)
+		now := metav1.Now()
+		terminating.DeletionTimestamp = &now
+		pods := []corev1.Pod{
+			newPod(sts+"-0", corev1.ConditionFalse),
+			newPod(sts+"-1", corev1.ConditionTrue),
+			{ObjectMeta: metav1.ObjectMeta{Name: sts + "-2"}},
+			terminating,
+		}
+		addrs := utils.BootstrapFailoverPeers(pods, sts, sts, 9096, peerIDs)
+		Expect(addrs).To(Equal([]string{
+			"/dns4/" + sts + "-1." + sts + "/tcp/9096/p2p/" + peerIDs[1].String(),
+		}))
+	})
+
+	It("orders the peers by ordinal", func() {
+		pods := []corev1.Pod{
+			newPod(sts+"-2", corev1.ConditionTrue),
+			newPod(sts+"-0", corev1.ConditionTrue),
+			newPod(sts+"-1", corev1.ConditionTrue),
+			newPod("other-0", corev1.ConditionTrue),
+		}
+		addrs := utils.BootstrapFailoverPeers(pods, sts, sts, 9096, peerIDs)
+		Expect(addrs).To(HaveLen(3))
+		for i, addr := range addrs {
+			Expect(addr).To(HaveSuffix(peerIDs[int32(i)].String()))
+		}
+		reversed := []corev1.Pod{pods[3], pods[2], pods[1], pods[0]}
+		Expect(utils.BootstrapFailoverPeers(reversed, sts, sts, 9096, peerIDs)).To(Equal(addrs))
+	})
+
+	It("skips peers without a known identity", func() {
+		delete(peerIDs, 0)
+		pods := []corev1.Pod{newPod(sts+"-0", corev1.ConditionTrue)}
+		Expect(utils.BootstrapFailoverPeers(pods, sts, sts, 9096, peerIDs)).To(BeEmpty())
+	})
+})
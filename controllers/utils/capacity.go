@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// capacityReportScript Prints the repo and bandwidth stats of the IPFS API of each peer given as
+// argument, prefixed with the API address and the kind of stats, one peer and kind per line.
+const capacityReportScript = `set -e
+for api in "$@"; do
+  printf '%s repo %s\n' "$api" "$(ipfs --api "$api" repo stat --enc=json)"
+  printf '%s bw %s\n' "$api" "$(ipfs --api "$api" stats bw --enc=json)"
+done
+`
+
+// PeerCapacity Holds the repo and bandwidth stats of a single peer.
+type PeerCapacity struct {
+	Peer       string
+	RepoSize   uint64
+	StorageMax uint64
+	NumObjects uint64
+	TotalIn    int64
+	TotalOut   int64
+	RateIn     float64
+	RateOut    float64
+}
+
+// CapacitySummary Aggregates the stats of every peer of a capacity report.
+type CapacitySummary struct {
+	Peers      []PeerCapacity
+	RepoSize   uint64
+	StorageMax uint64
+	NumObjects uint64
+	TotalIn    int64
+	TotalOut   int64
+}
+
+// Utilization Returns the fraction of the total StorageMax used by the peers' repos.
+func (s CapacitySummary) Utilization() float64 {
+	if s.StorageMax == 0 {
+		return 0
+	}
+	return float64(s.RepoSize) / float64(s.StorageMax)
+}
+
+// CapacityReportJob Returns a Job which collects `ipfs repo stat` and `ipfs stats bw` from the
+// IPFS API of each of the given peers, for ParseCapacityReport to aggregate from its logs.
+func CapacityReportJob(name, namespace, image string, peerAPIAddrs []string) (*batchv1.Job, error) {
+	if len(peerAPIAddrs) == 0 {
+		return nil, fmt.Errorf("capacity report needs at least one peer api address")
+	}
+	var backoffLimit int32
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:    "capacity-report",
+							Image:   image,
+							Command: append([]string{"sh", "-c", capacityReportScript, "capacity-report"}, peerAPIAddrs...),
+						},
+					},
+				},
+			},
+		},
+	}
+	return job, nil
+}
+
+// ParseCapacityReport Aggregates the output of the capacity report Job into a summary of every
+// peer, sorted by peer. Lines which are not stats, such as shell traces, are skipped.
+func ParseCapacityReport(output string) (CapacitySummary, error) {
+	peers := make(map[string]*PeerCapacity)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), " ", 3)
+		if len(fields) != 3 || (fields[1] != "repo" && fields[1] != "bw") {
+			continue
+		}
+		p, ok := peers[fields[0]]
+		if !ok {
+			p = &PeerCapacity{Peer: fields[0]}
+			peers[fields[0]] = p
+		}
+		var err error
+		if fields[1] == "repo" {
+			err = json.Unmarshal([]byte(fields[2]), &struct {
+				RepoSize   *uint64
+				StorageMax *uint64
+				NumObjects *uint64
+			}{&p.RepoSize, &p.StorageMax, &p.NumObjects})
+		} else {
+			err = json.Unmarshal([]byte(fields[2]), &struct {
+				TotalIn  *int64
+				TotalOut *int64
+				RateIn   *float64
+				RateOut  *float64
+			}{&p.TotalIn, &p.TotalOut, &p.RateIn, &p.RateOut})
+		}
+		if err != nil {
+			return CapacitySummary{}, fmt.Errorf("invalid %s stats of peer %s: %w", fields[1], fields[0], err)
+		}
+	}
+	summary := CapacitySummary{Peers: make([]PeerCapacity, 0, len(peers))}
+	for _, p := range peers {
+		summary.Peers = append(summary.Peers, *p)
+		summary.RepoSize += p.RepoSize
+		summary.StorageMax += p.StorageMax
+		summary.NumObjects += p.NumObjects
+		summary.TotalIn += p.TotalIn
+		summary.TotalOut += p.TotalOut
+	}
+	sort.Slice(summary.Peers, func(i, j int) bool {
+		return summary.Peers[i].Peer < summary.Peers[j].Peer
+	})
+	return summary, nil
+}
@@ -0,0 +1,61 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Capacity report", func() {
+	const (
+		peer0 = "/dns4/ipfs-cluster-test-0.ipfs-cluster-test/tcp/5001"
+		peer1 = "/dns4/ipfs-cluster-test-1.ipfs-cluster-test/tcp/5001"
+	)
+
+	It("collects the stats of every peer", func() {
+		job, err := utils.CapacityReportJob("capacity", "test", "docker.io/ipfs/kubo:v0.16.0", []string{peer0, peer1})
+		Expect(err).NotTo(HaveOccurred())
+		command := job.Spec.Template.Spec.Containers[0].Command
+		Expect(command[2]).To(ContainSubstring("repo stat --enc=json"))
+		Expect(command[2]).To(ContainSubstring("stats bw --enc=json"))
+		Expect(command[len(command)-2:]).To(Equal([]string{peer0, peer1}))
+
+		_, err = utils.CapacityReportJob("capacity", "test", "docker.io/ipfs/kubo:v0.16.0", nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("aggregates the stats across peers", func() {
+		output := "" +
+			peer1 + ` repo {"RepoSize":300,"StorageMax":1000,"NumObjects":3,"RepoPath":"/data/ipfs","Version":"fs-repo@12"}
+` + peer1 + ` bw {"TotalIn":10,"TotalOut":20,"RateIn":1.5,"RateOut":2.5}
++ ipfs --api ` + peer0 + ` repo stat --enc=json
+` + peer0 + ` repo {"RepoSize":100,"StorageMax":1000,"NumObjects":1,"RepoPath":"/data/ipfs","Version":"fs-repo@12"}
+` + peer0 + ` bw {"TotalIn":5,"TotalOut":7,"RateIn":0,"RateOut":0.5}
+`
+		summary, err := utils.ParseCapacityReport(output)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(summary.Peers).To(Equal([]utils.PeerCapacity{
+			{Peer: peer0, RepoSize: 100, StorageMax: 1000, NumObjects: 1, TotalIn: 5, TotalOut: 7, RateOut: 0.5},
+			{Peer: peer1, RepoSize: 300, StorageMax: 1000, NumObjects: 3, TotalIn: 10, TotalOut: 20, RateIn: 1.5, RateOut: 2.5},
+		}))
+		Expect(summary.RepoSize).To(BeEquivalentTo(400))
+		Expect(summary.StorageMax).To(BeEquivalentTo(2000))
+		Expect(summary.NumObjects).To(BeEquivalentTo(4))
+		Expect(summary.TotalIn).To(BeEquivalentTo(15))
+		Expect(summary.TotalOut).To(BeEquivalentTo(27))
+		Expect(summary.Utilization()).To(BeNumerically("~", 0.2))
+	})
+
+	It("handles an empty report", func() {
+		summary, err := utils.ParseCapacityReport("")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(summary.Peers).To(BeEmpty())
+		Expect(summary.Utilization()).To(BeZero())
+	})
+
+	It("rejects malformed stats", func() {
+		_, err := utils.ParseCapacityReport(peer0 + ` repo {"RepoSize":"big"}`)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"path"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ContainerImportCAR Defines the name of the init container importing a bootstrap CAR file.
+const ContainerImportCAR = "import-car"
+
+// importCARScript Imports the CAR file given as first argument into the repo, pinning its roots,
+// and records the import so that restarts of a seeded node skip it.
+const importCARScript = `set -e
+marker="${IPFS_PATH}/.imported-$(basename "$1")"
+if [ -f "${marker}" ]; then
+	echo "$1 already imported"
+	exit 0
+fi
+ipfs dag import "$1"
+touch "${marker}"
+`
+
+// CARImportInitContainers Returns the init container which runs `ipfs dag import` on the CAR file
+// at carPath before the daemon starts, seeding the IPFS repo held at the root of dataVolume, which
+// is mounted at repoPath like in the ipfs container, for a fast cold start. The CAR file is read
+// from carVolume mounted read-only at its directory, or from the image when carVolume is empty.
+// No containers are returned when no CAR file is configured. The container must run after the
+// repo is initialized.
+func CARImportInitContainers(image, dataVolume, repoPath, carVolume, carPath string) []corev1.Container {
+	if carPath == "" {
+		return nil
+	}
+	container := corev1.Container{
+		Name:    ContainerImportCAR,
+		Image:   image,
+		Command: []string{"sh", "-c", importCARScript, ContainerImportCAR, carPath},
+		Env: []corev1.EnvVar{
+			{
+				Name:  "IPFS_PATH",
+				Value: repoPath,
+			},
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      dataVolume,
+				MountPath: repoPath,
+			},
+		},
+	}
+	if carVolume != "" {
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      carVolume,
+			MountPath: path.Dir(carPath),
+			ReadOnly:  true,
+		})
+	}
+	return []corev1.Container{container}
+}
@@ -0,0 +1,42 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("CAR import", func() {
+	const image = "docker.io/ipfs/kubo:v0.16.0"
+
+	It("imports the configured CAR file", func() {
+		containers := utils.CARImportInitContainers(image, "ipfs-storage", "/data/ipfs", "seed", "/seed/bootstrap.car")
+		Expect(containers).To(HaveLen(1))
+		container := containers[0]
+		Expect(container.Name).To(Equal(utils.ContainerImportCAR))
+		Expect(container.Command[len(container.Command)-1]).To(Equal("/seed/bootstrap.car"))
+		Expect(container.Command[2]).To(ContainSubstring(`ipfs dag import "$1"`))
+		Expect(container.Env).To(ContainElement(corev1.EnvVar{Name: "IPFS_PATH", Value: "/data/ipfs"}))
+		Expect(container.VolumeMounts).To(ContainElement(corev1.VolumeMount{
+			Name:      "ipfs-storage",
+			MountPath: "/data/ipfs",
+		}))
+		Expect(container.VolumeMounts).To(ContainElement(corev1.VolumeMount{
+			Name:      "seed",
+			MountPath: "/seed",
+			ReadOnly:  true,
+		}))
+	})
+
+	It("reads a CAR file baked into the image", func() {
+		containers := utils.CARImportInitContainers(image, "ipfs-storage", "/data/ipfs", "", "/seed/bootstrap.car")
+		Expect(containers).To(HaveLen(1))
+		Expect(containers[0].VolumeMounts).To(HaveLen(1))
+	})
+
+	It("is skipped when no CAR file is configured", func() {
+		Expect(utils.CARImportInitContainers(image, "ipfs-storage", "/data/ipfs", "seed", "")).To(BeEmpty())
+	})
+})
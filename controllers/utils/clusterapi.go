@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// clusterAPITimeout Bounds each request the operator makes to the IPFS Cluster REST API, so that
+// an unresponsive peer doesn't hold up the reconcile.
+const clusterAPITimeout = 10 * time.Second
+
+// ClusterAPIClient Queries the IPFS Cluster REST API of a peer, or of the cluster Service, on
+// behalf of the operator.
+type ClusterAPIClient struct {
+	HTTP     *http.Client
+	BaseURL  string
+	Username string
+	Password string
+}
+
+// ClusterPeerInfo Holds what a peer announces about itself on the /id endpoint of its REST API.
+type ClusterPeerInfo struct {
+	// ID is the peer ID of the IPFS Cluster peer.
+	ID string `json:"id"`
+	// IPFS describes the IPFS daemon the peer is connected to.
+	IPFS struct {
+		// ID is the peer ID of the IPFS daemon.
+		ID string `json:"id"`
+	} `json:"ipfs"`
+}
+
+// NewClusterAPIClient Returns a client for the REST API served at the given host and port.
+// Credentials are given as the comma-separated `user:password` pairs the REST API is configured
+// with, of which the first is used. When a TLS Secret is given the API is reached over https,
+// trusting only the exact certificate held by the Secret: the peers are reached by addresses
+// the certificate is usually not issued for, so its hostnames are not verified.
+func NewClusterAPIClient(
+	host string,
+	port int32,
+	credentials string,
+	tlsSecret *corev1.Secret,
+) (*ClusterAPIClient, error) {
+	addr := net.JoinHostPort(host, strconv.Itoa(int(port)))
+	c := &ClusterAPIClient{
+		HTTP:    &http.Client{Timeout: clusterAPITimeout},
+		BaseURL: "http://" + addr,
+	}
+	if credentials != "" {
+		pair := strings.SplitN(credentials, ",", 2)[0]
+		user, password, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, fmt.Errorf("cluster api credentials must be given as user:password pairs")
+		}
+		c.Username, c.Password = user, password
+	}
+	if tlsSecret == nil {
+		return c, nil
+	}
+	block, _ := pem.Decode(tlsSecret.Data[corev1.TLSCertKey])
+	if block == nil {
+		return nil, fmt.Errorf("tls secret %q holds no certificate", tlsSecret.Name)
+	}
+	expected, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse certificate of tls secret %q: %w", tlsSecret.Name, err)
+	}
+	c.BaseURL = "https://" + addr
+	c.HTTP.Transport = &http.Transport{
+		TLSClientConfig: &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: true, //nolint:gosec // the served certificate must equal the pinned one
+			VerifyConnection: func(state tls.ConnectionState) error {
+				if len(state.PeerCertificates) == 0 || !state.PeerCertificates[0].Equal(expected) {
+					return fmt.Errorf("cluster api does not serve the certificate of tls secret %q", tlsSecret.Name)
+				}
+				return nil
+			},
+		},
+	}
+	return c, nil
+}
+
+// get Returns the body of a GET request to the given path of the REST API.
+func (c *ClusterAPIClient) get(ctx context.Context, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+path, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("could not build cluster api request: %w", err)
+	}
+	if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not query cluster api: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read cluster api response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cluster api answered %s %s: %s", req.Method, path, bytes.TrimSpace(body))
+	}
+	return body, nil
+}
+
+// Pinset Returns the pinset held by the cluster state of the peer, one JSON-encoded pin per line
+// as streamed by the /allocations endpoint, the same form CountExportedPins reads.
+func (c *ClusterAPIClient) Pinset(ctx context.Context) (string, error) {
+	body, err := c.get(ctx, "/allocations?filter=all")
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// PeerInfo Returns what the peer serving the REST API announces about itself.
+func (c *ClusterAPIClient) PeerInfo(ctx context.Context) (ClusterPeerInfo, error) {
+	info := ClusterPeerInfo{}
+	body, err := c.get(ctx, "/id")
+	if err != nil {
+		return info, err
+	}
+	if err = json.Unmarshal(body, &info); err != nil {
+		return info, fmt.Errorf("could not decode peer info: %w", err)
+	}
+	return info, nil
+}
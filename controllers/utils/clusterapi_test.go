@@ -0,0 +1,96 @@
+package utils_test
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Cluster API client", func() {
+	const pinset = `{"cid":{"/":"bafyone"}}` + "\n" + `{"cid":{"/":"bafytwo"}}` + "\n"
+	ctx := context.TODO()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); ok && (user != "admin" || password != "secret") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.URL.Path == "/allocations" && r.URL.Query().Get("filter") == "all":
+			_, _ = w.Write([]byte(pinset))
+			return
+		case r.URL.Path == "/id":
+			_, _ = w.Write([]byte(`{"id":"12D3KooWCluster","ipfs":{"id":"12D3KooWIPFS"}}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	clientFor := func(server *httptest.Server, credentials string, tlsSecret *corev1.Secret) *utils.ClusterAPIClient {
+		host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+		Expect(err).NotTo(HaveOccurred())
+		portNumber, err := strconv.Atoi(port)
+		Expect(err).NotTo(HaveOccurred())
+		c, err := utils.NewClusterAPIClient(host, int32(portNumber), credentials, tlsSecret)
+		Expect(err).NotTo(HaveOccurred())
+		return c
+	}
+
+	It("streams the pinset", func() {
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		export, err := clientFor(server, "", nil).Pinset(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(utils.CountExportedPins(export)).To(Equal(2))
+	})
+
+	It("decodes the announced peer info", func() {
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		info, err := clientFor(server, "", nil).PeerInfo(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.ID).To(Equal("12D3KooWCluster"))
+		Expect(info.IPFS.ID).To(Equal("12D3KooWIPFS"))
+	})
+
+	It("authenticates with the first credentials", func() {
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		_, err := clientFor(server, "admin:secret,other:password", nil).Pinset(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = clientFor(server, "other:password,admin:secret", nil).Pinset(ctx)
+		Expect(err).To(MatchError(ContainSubstring("answered")))
+
+		_, err = utils.NewClusterAPIClient("localhost", 9094, "admin", nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("only trusts the certificate of the tls secret", func() {
+		secret := newTLSSecret("ipfs-cluster.example.com")
+		pair, err := tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+		Expect(err).NotTo(HaveOccurred())
+		server := httptest.NewUnstartedServer(handler)
+		server.TLS = &tls.Config{Certificates: []tls.Certificate{pair}, MinVersion: tls.VersionTLS12}
+		server.StartTLS()
+		defer server.Close()
+
+		_, err = clientFor(server, "", secret).Pinset(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = clientFor(server, "", newTLSSecret("ipfs-cluster.example.com")).Pinset(ctx)
+		Expect(err).To(HaveOccurred())
+		_, err = clientFor(server, "", nil).Pinset(ctx)
+		Expect(err).To(HaveOccurred())
+	})
+})
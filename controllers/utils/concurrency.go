@@ -0,0 +1,35 @@
+package utils
+
+import "math"
+
+const (
+	// defaultClientQPS Matches the client-go default used when the rest config leaves QPS unset.
+	defaultClientQPS = 5
+	// requestsPerReconcile Estimates the API requests issued per second by a busy reconcile
+	// worker, covering the tracked objects it creates or patches and the status updates.
+	requestsPerReconcile = 2
+	// clustersPerWorker Is the number of IpfsClusters a single worker is expected to keep up with.
+	clustersPerWorker = 10
+)
+
+// SuggestedMaxConcurrentReconciles Returns the number of reconcile workers to run for the
+// given number of IpfsClusters, adding one worker per clustersPerWorker clusters.
+// The result is capped so that the workers together stay within the client's QPS budget,
+// and is never below one. A non-positive qps uses the client-go default.
+func SuggestedMaxConcurrentReconciles(clusterCount int, qps float32) int {
+	if qps <= 0 {
+		qps = defaultClientQPS
+	}
+	qpsLimit := int(math.Floor(float64(qps) / requestsPerReconcile))
+	if qpsLimit < 1 {
+		qpsLimit = 1
+	}
+	workers := (clusterCount + clustersPerWorker - 1) / clustersPerWorker
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > qpsLimit {
+		workers = qpsLimit
+	}
+	return workers
+}
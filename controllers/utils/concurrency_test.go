@@ -0,0 +1,27 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("MaxConcurrentReconciles", func() {
+	It("runs a single worker for few clusters", func() {
+		Expect(utils.SuggestedMaxConcurrentReconciles(0, 100)).To(Equal(1))
+		Expect(utils.SuggestedMaxConcurrentReconciles(10, 100)).To(Equal(1))
+	})
+
+	It("scales with the number of clusters", func() {
+		Expect(utils.SuggestedMaxConcurrentReconciles(11, 100)).To(Equal(2))
+		Expect(utils.SuggestedMaxConcurrentReconciles(45, 100)).To(Equal(5))
+	})
+
+	It("caps at the QPS-derived limit", func() {
+		Expect(utils.SuggestedMaxConcurrentReconciles(1000, 20)).To(Equal(10))
+		// the client-go default of 5 QPS only supports two workers
+		Expect(utils.SuggestedMaxConcurrentReconciles(1000, 0)).To(Equal(2))
+		Expect(utils.SuggestedMaxConcurrentReconciles(1000, 1)).To(Equal(1))
+	})
+})
@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"fmt"
+
+	"github.com/redhat-et/ipfs-operator/controllers/scripts"
+)
+
+// ConsensusMigrationStep Is a step of the migration of a cluster from the raft to the crdt consensus.
+// The consensus state cannot be converted in place, so the pinset is exported under raft and imported
+// again once the peers run crdt.
+type ConsensusMigrationStep string
+
+const (
+	// ConsensusMigrationNotStarted Means no step of the migration has completed yet.
+	ConsensusMigrationNotStarted ConsensusMigrationStep = ""
+	// ConsensusMigrationExport Exports the pinset from the raft state.
+	ConsensusMigrationExport ConsensusMigrationStep = "ExportRaftState"
+	// ConsensusMigrationSwitch Switches the peers over to the crdt consensus.
+	ConsensusMigrationSwitch ConsensusMigrationStep = "SwitchConsensus"
+	// ConsensusMigrationImport Imports the exported pinset into the crdt state.
+	ConsensusMigrationImport ConsensusMigrationStep = "ImportCRDTState"
+)
+
+// consensusMigrationSteps Lists the steps of the migration in the order they must run in.
+var consensusMigrationSteps = []ConsensusMigrationStep{
+	ConsensusMigrationExport,
+	ConsensusMigrationSwitch,
+	ConsensusMigrationImport,
+}
+
+// NextConsensusMigrationStep Returns the step which follows the last completed one, and false once
+// the migration is complete.
+func NextConsensusMigrationStep(completed ConsensusMigrationStep) (ConsensusMigrationStep, bool, error) {
+	if completed == ConsensusMigrationNotStarted {
+		return consensusMigrationSteps[0], true, nil
+	}
+	for i, step := range consensusMigrationSteps {
+		if step != completed {
+			continue
+		}
+		if i == len(consensusMigrationSteps)-1 {
+			return "", false, nil
+		}
+		return consensusMigrationSteps[i+1], true, nil
+	}
+	return "", false, fmt.Errorf("unknown consensus migration step %q", completed)
+}
+
+// ValidateConsensusMigrationStep Ensures the given step may run after the last completed one, so that
+// no step is skipped or repeated.
+func ValidateConsensusMigrationStep(completed, step ConsensusMigrationStep) error {
+	next, ok, err := NextConsensusMigrationStep(completed)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("consensus migration is already complete")
+	}
+	if step != next {
+		return fmt.Errorf("consensus migration step %q must run before %q", next, step)
+	}
+	return nil
+}
+
+// ValidateConsensusChange Ensures the consensus of a cluster may change from current to desired, given
+// the last completed step of its migration. Switching in place would start the peers with an empty
+// crdt state, dropping the pinset, so raft may only be switched to crdt once its state was exported.
+// Migrating from crdt back to raft is not supported.
+func ValidateConsensusChange(current, desired string, completed ConsensusMigrationStep) error {
+	if current == desired {
+		return nil
+	}
+	if current != scripts.ClusterConsensusRaft || desired != scripts.ClusterConsensusCRDT {
+		return fmt.Errorf("cannot migrate the consensus from %q to %q", current, desired)
+	}
+	if err := ValidateConsensusMigrationStep(completed, ConsensusMigrationSwitch); err != nil {
+		return fmt.Errorf("cannot switch the consensus in place, the raft state must be exported first: %w", err)
+	}
+	return nil
+}
+
+// ConsensusMigrationScript Returns the shell script running the given step of the migration on a
+// peer of the given image, which exports the raft state to or imports the crdt state from the given
+// path. The switch itself is a change of the peer configuration, and has no script.
+func ConsensusMigrationScript(step ConsensusMigrationStep, clusterImage, path string) (string, error) {
+	switch step {
+	case ConsensusMigrationExport:
+		return StateExportScript(clusterImage, path)
+	case ConsensusMigrationSwitch:
+		return "", nil
+	case ConsensusMigrationImport:
+		return fmt.Sprintf("set -e\nipfs-cluster-service state import -f %q\n", path), nil
+	default:
+		return "", fmt.Errorf("unknown consensus migration step %q", step)
+	}
+}
@@ -0,0 +1,63 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/ipfs-operator/controllers/scripts"
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Consensus migration", func() {
+	It("orders the steps export, switch, import", func() {
+		var steps []utils.ConsensusMigrationStep
+		completed := utils.ConsensusMigrationNotStarted
+		for {
+			next, ok, err := utils.NextConsensusMigrationStep(completed)
+			Expect(err).NotTo(HaveOccurred())
+			if !ok {
+				break
+			}
+			Expect(utils.ValidateConsensusMigrationStep(completed, next)).To(Succeed())
+			steps = append(steps, next)
+			completed = next
+		}
+		Expect(steps).To(Equal([]utils.ConsensusMigrationStep{
+			utils.ConsensusMigrationExport,
+			utils.ConsensusMigrationSwitch,
+			utils.ConsensusMigrationImport,
+		}))
+	})
+
+	It("refuses to skip or repeat a step", func() {
+		Expect(utils.ValidateConsensusMigrationStep(
+			utils.ConsensusMigrationNotStarted, utils.ConsensusMigrationImport)).NotTo(Succeed())
+		Expect(utils.ValidateConsensusMigrationStep(
+			utils.ConsensusMigrationExport, utils.ConsensusMigrationExport)).NotTo(Succeed())
+		Expect(utils.ValidateConsensusMigrationStep(
+			utils.ConsensusMigrationImport, utils.ConsensusMigrationImport)).NotTo(Succeed())
+	})
+
+	It("blocks a direct in-place consensus switch", func() {
+		raft, crdt := scripts.ClusterConsensusRaft, scripts.ClusterConsensusCRDT
+		Expect(utils.ValidateConsensusChange(raft, crdt, utils.ConsensusMigrationNotStarted)).NotTo(Succeed())
+		Expect(utils.ValidateConsensusChange(raft, crdt, utils.ConsensusMigrationExport)).To(Succeed())
+		Expect(utils.ValidateConsensusChange(crdt, raft, utils.ConsensusMigrationExport)).NotTo(Succeed())
+		Expect(utils.ValidateConsensusChange(crdt, crdt, utils.ConsensusMigrationNotStarted)).To(Succeed())
+	})
+
+	It("renders the export and import scripts", func() {
+		export, err := utils.ConsensusMigrationScript(utils.ConsensusMigrationExport,
+			"docker.io/ipfs/ipfs-cluster:1.0.4", "/backup/state.json")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(export).To(ContainSubstring(`state export -f "/backup/state.json"`))
+
+		switchScript, err := utils.ConsensusMigrationScript(utils.ConsensusMigrationSwitch, "", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(switchScript).To(BeEmpty())
+
+		imported, err := utils.ConsensusMigrationScript(utils.ConsensusMigrationImport, "", "/backup/state.json")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(imported).To(ContainSubstring(`state import -f "/backup/state.json"`))
+	})
+})
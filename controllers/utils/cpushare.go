@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// DefaultMinContainerMilliCPU Is the CPU request no container is reduced below when fitting a pod
+// onto a small node, enough for the IPFS and IPFS Cluster daemons to keep their connections alive.
+const DefaultMinContainerMilliCPU = 50
+
+// FitCPURequests Reduces the CPU requests of the containers of the given pod proportionally so that
+// their total fits the CPU budget of a small node, e.g. the allocatable CPU of a 1-core node, which
+// the 250m floor of the IPFS container alongside its sidecars may otherwise exceed, leaving the pod
+// unschedulable. No request is reduced below hardMinimum, and containers requesting no CPU are left
+// as is. Requests which already fit are kept. An error is returned when the hard minimums alone
+// exceed the budget.
+func FitCPURequests(spec *corev1.PodSpec, budget, hardMinimum resource.Quantity) error {
+	budgetMilli, minMilli := budget.MilliValue(), hardMinimum.MilliValue()
+	requested := make(map[int]int64)
+	var total int64
+	for i := range spec.Containers {
+		if cpu, ok := spec.Containers[i].Resources.Requests[corev1.ResourceCPU]; ok && !cpu.IsZero() {
+			requested[i] = cpu.MilliValue()
+			total += cpu.MilliValue()
+		}
+	}
+	if total <= budgetMilli {
+		return nil
+	}
+	if int64(len(requested))*minMilli > budgetMilli {
+		return fmt.Errorf(
+			"cpu budget %s cannot fit %d containers requesting at least %s", budget.String(), len(requested),
+			hardMinimum.String(),
+		)
+	}
+	// pin the containers the proportional share would take below the hard minimum, then share
+	// what is left of the budget between the others, until no other container falls below it
+	fitted := make(map[int]int64, len(requested))
+	for {
+		remaining, shared := budgetMilli, int64(0)
+		for i, milli := range requested {
+			if _, pinned := fitted[i]; pinned {
+				remaining -= minMilli
+			} else {
+				shared += milli
+			}
+		}
+		pinnedMore := false
+		for i, milli := range requested {
+			if _, pinned := fitted[i]; !pinned && milli*remaining/shared < minMilli {
+				fitted[i] = minMilli
+				pinnedMore = true
+			}
+		}
+		if !pinnedMore {
+			for i, milli := range requested {
+				if _, pinned := fitted[i]; !pinned {
+					fitted[i] = milli * remaining / shared
+				}
+			}
+			break
+		}
+	}
+	for i, milli := range fitted {
+		spec.Containers[i].Resources.Requests[corev1.ResourceCPU] = *resource.NewMilliQuantity(milli, resource.DecimalSI)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("CPU fair share", func() {
+	hardMinimum := *resource.NewMilliQuantity(utils.DefaultMinContainerMilliCPU, resource.DecimalSI)
+
+	newSpec := func(requests ...string) *corev1.PodSpec {
+		spec := &corev1.PodSpec{}
+		for _, request := range requests {
+			container := corev1.Container{}
+			if request != "" {
+				container.Resources.Requests = corev1.ResourceList{
+					corev1.ResourceCPU: resource.MustParse(request),
+				}
+			}
+			spec.Containers = append(spec.Containers, container)
+		}
+		return spec
+	}
+	cpuOf := func(spec *corev1.PodSpec, i int) int64 {
+		cpu := spec.Containers[i].Resources.Requests[corev1.ResourceCPU]
+		return cpu.MilliValue()
+	}
+	totalOf := func(spec *corev1.PodSpec) int64 {
+		var total int64
+		for i := range spec.Containers {
+			total += cpuOf(spec, i)
+		}
+		return total
+	}
+
+	It("keeps requests which already fit", func() {
+		spec := newSpec("250m", "100m")
+		Expect(utils.FitCPURequests(spec, resource.MustParse("1"), hardMinimum)).To(Succeed())
+		Expect(cpuOf(spec, 0)).To(BeEquivalentTo(250))
+		Expect(cpuOf(spec, 1)).To(BeEquivalentTo(100))
+	})
+
+	It("shares the budget proportionally", func() {
+		spec := newSpec("500m", "250m", "250m")
+		Expect(utils.FitCPURequests(spec, resource.MustParse("800m"), hardMinimum)).To(Succeed())
+		Expect(totalOf(spec)).To(BeNumerically("<=", 800))
+		Expect(cpuOf(spec, 0)).To(BeEquivalentTo(400))
+		Expect(cpuOf(spec, 1)).To(BeEquivalentTo(200))
+		Expect(cpuOf(spec, 2)).To(BeEquivalentTo(200))
+	})
+
+	It("keeps every container above its hard minimum", func() {
+		spec := newSpec("900m", "60m", "", "250m")
+		Expect(utils.FitCPURequests(spec, resource.MustParse("300m"), hardMinimum)).To(Succeed())
+		Expect(totalOf(spec)).To(BeNumerically("<=", 300))
+		for _, i := range []int{0, 1, 3} {
+			Expect(cpuOf(spec, i)).To(BeNumerically(">=", utils.DefaultMinContainerMilliCPU))
+		}
+		Expect(spec.Containers[2].Resources.Requests).To(BeEmpty())
+	})
+
+	It("fails when the hard minimums exceed the budget", func() {
+		spec := newSpec("250m", "250m", "250m")
+		Expect(utils.FitCPURequests(spec, resource.MustParse("100m"), hardMinimum)).NotTo(Succeed())
+	})
+})
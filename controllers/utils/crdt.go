@@ -0,0 +1,13 @@
+package utils
+
+import (
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+)
+
+// CRDTClusterName Returns the CRDT cluster name of the given IpfsCluster, derived from its
+// namespace and name, which cannot contain the separator, so that distinct IpfsClusters never
+// share a pubsub topic and cross-contaminate their pinsets, while the name of one IpfsCluster
+// never changes.
+func CRDTClusterName(m *clusterv1alpha1.IpfsCluster) string {
+	return "ipfs-cluster/" + m.Namespace + "/" + m.Name
+}
@@ -0,0 +1,39 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("CRDT cluster names", func() {
+	newCluster := func(namespace, name string) *clusterv1alpha1.IpfsCluster {
+		return &clusterv1alpha1.IpfsCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		}
+	}
+
+	It("derives distinct names for distinct clusters", func() {
+		names := map[string]bool{}
+		for _, m := range []*clusterv1alpha1.IpfsCluster{
+			newCluster("a-b", "c"),
+			newCluster("a", "b-c"),
+			newCluster("a", "c"),
+			newCluster("b", "c"),
+		} {
+			name := utils.CRDTClusterName(m)
+			Expect(names).NotTo(HaveKey(name))
+			names[name] = true
+		}
+	})
+
+	It("keeps the name of a cluster stable", func() {
+		m := newCluster("test", "ipfs")
+		Expect(utils.CRDTClusterName(m)).To(Equal("ipfs-cluster/test/ipfs"))
+		m.Spec.Replicas = 5
+		Expect(utils.CRDTClusterName(m.DeepCopy())).To(Equal("ipfs-cluster/test/ipfs"))
+	})
+})
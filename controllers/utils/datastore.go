@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"fmt"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+)
+
+// CheckDatastoreMigration Returns an error if the desired datastore backend differs from
+// the backend the IPFS repos were initialized with, since this cannot be changed in place.
+// Repos which were initialized before the backend was recorded, as told by initialized, use
+// the default flatfs backend. The change is allowed when the repos were not initialized yet,
+// or when a migration was explicitly requested.
+func CheckDatastoreMigration(
+	recorded clusterv1alpha1.DatastoreBackend,
+	desired clusterv1alpha1.DatastoreBackend,
+	initialized bool,
+	allowMigration bool,
+) error {
+	if recorded == "" {
+		if !initialized {
+			return nil
+		}
+		recorded = clusterv1alpha1.DatastoreBackendFlatfs
+	}
+	if recorded == desired || allowMigration {
+		return nil
+	}
+	return fmt.Errorf(
+		"datastore backend cannot be changed from %q to %q without a migration, set allowMigration to proceed",
+		recorded, desired,
+	)
+}
@@ -0,0 +1,59 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Datastore migration", func() {
+	When("the datastore backend changes", func() {
+		It("blocks the change", func() {
+			err := utils.CheckDatastoreMigration(
+				clusterv1alpha1.DatastoreBackendFlatfs,
+				clusterv1alpha1.DatastoreBackendBadger,
+				true,
+				false,
+			)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("proceeds when a migration is allowed", func() {
+			err := utils.CheckDatastoreMigration(
+				clusterv1alpha1.DatastoreBackendFlatfs,
+				clusterv1alpha1.DatastoreBackendBadger,
+				true,
+				true,
+			)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	When("the datastore backend is unchanged", func() {
+		It("proceeds", func() {
+			err := utils.CheckDatastoreMigration(
+				clusterv1alpha1.DatastoreBackendFlatfs,
+				clusterv1alpha1.DatastoreBackendFlatfs,
+				true,
+				false,
+			)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	When("no datastore backend has been recorded", func() {
+		It("proceeds for repos which are not initialized yet", func() {
+			err := utils.CheckDatastoreMigration("", clusterv1alpha1.DatastoreBackendBadger, false, false)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("treats initialized repos as flatfs", func() {
+			err := utils.CheckDatastoreMigration("", clusterv1alpha1.DatastoreBackendBadger, true, false)
+			Expect(err).To(MatchError(ContainSubstring(`from "flatfs" to "badger"`)))
+			err = utils.CheckDatastoreMigration("", clusterv1alpha1.DatastoreBackendFlatfs, true, false)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+})
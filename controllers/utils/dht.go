@@ -0,0 +1,112 @@
+package utils
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// dhtServerTiers Sizes DHT servers by the number of peers they are expected to track, smallest
+// first. The routing table, provider records and connections grow with the peer count, so
+// memory and CPU scale with it whereas storage barely matters.
+var dhtServerTiers = []struct {
+	maxPeers int
+	milliCPU int64
+	memoryGB int64
+}{
+	{maxPeers: 1000, milliCPU: 1000, memoryGB: 2},
+	{maxPeers: 5000, milliCPU: 2000, memoryGB: 4},
+	{maxPeers: 20000, milliCPU: 4000, memoryGB: 8},
+}
+
+// dhtServerMaxTier Sizes DHT servers expected to track more peers than the largest tier.
+var dhtServerMaxTier = struct {
+	milliCPU int64
+	memoryGB int64
+}{milliCPU: 8000, memoryGB: 16}
+
+// DHTServerResources Returns the resource requirements of an IPFS container dedicated to serving
+// the DHT for the given number of expected peers. Memory is requested up to its limit, since
+// a DHT server running out of it drops its routing table, while CPU may burst to twice the request.
+func DHTServerResources(expectedPeers int) corev1.ResourceRequirements {
+	milliCPU, memoryGB := dhtServerMaxTier.milliCPU, dhtServerMaxTier.memoryGB
+	for _, tier := range dhtServerTiers {
+		if expectedPeers <= tier.maxPeers {
+			milliCPU, memoryGB = tier.milliCPU, tier.memoryGB
+			break
+		}
+	}
+	memory := resource.NewScaledQuantity(memoryGB, resource.Giga)
+	return corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    *resource.NewMilliQuantity(milliCPU, resource.DecimalSI),
+			corev1.ResourceMemory: memory.DeepCopy(),
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceCPU:    *resource.NewMilliQuantity(2*milliCPU, resource.DecimalSI),
+			corev1.ResourceMemory: memory.DeepCopy(),
+		},
+	}
+}
+
+// JobResources Returns the resource requirements of the IPFS container of a one-shot pod using
+// the job profile, which only needs to start a node and add content through it.
+func JobResources() corev1.ResourceRequirements {
+	memory := IPFSMemoryFloor(RoutingTypeDHTClient)
+	return corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    *resource.NewMilliQuantity(100, resource.DecimalSI),
+			corev1.ResourceMemory: memory.DeepCopy(),
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceCPU:    *resource.NewMilliQuantity(500, resource.DecimalSI),
+			corev1.ResourceMemory: memory.DeepCopy(),
+		},
+	}
+}
+
+// IPFSResourcesForRole Returns the resource requirements of the IPFS container of a node with
+// the given role. DHT servers are sized by the expected number of peers and jobs get minimal
+// resources, whereas every other role is sized by its storage, with at least the minimum viable
+// memory of a node serving the DHT, including the headroom of the accelerated DHT client if enabled.
+func IPFSResourcesForRole(
+	role EvictionRole,
+	ipfsStorageBytes int64,
+	expectedPeers int,
+	acceleratedDHTClient bool,
+) corev1.ResourceRequirements {
+	switch role {
+	case EvictionRoleDHTServer:
+		return DHTServerResources(expectedPeers)
+	case EvictionRoleJob:
+		return JobResources()
+	}
+	resources := IPFSContainerResources(ipfsStorageBytes)
+	EnsureIPFSRoutingMemoryFloor(&resources, RoutingTypeDHT, acceleratedDHTClient)
+	return resources
+}
+
+const (
+	// minDHTServers Is the fewest DHT servers a cluster should run, so that its records stay
+	// reachable through a restart or the loss of a node.
+	minDHTServers = 3
+	// dhtServerFraction Is the share of the peers of large clusters which should serve the DHT.
+	// The others only query it, saving the memory and connections of a DHT server.
+	dhtServerFraction = 4
+)
+
+// RecommendDHTRoles Returns how many of the given number of peers should run as DHT servers and how
+// many as dhtclients: a quarter of the peers, rounded up, serve the DHT, but never fewer than
+// minDHTServers. Clusters of minDHTServers peers or fewer run only DHT servers.
+func RecommendDHTRoles(totalPeers int) (servers, clients int) {
+	if totalPeers <= 0 {
+		return 0, 0
+	}
+	servers = (totalPeers + dhtServerFraction - 1) / dhtServerFraction
+	if servers < minDHTServers {
+		servers = minDHTServers
+	}
+	if servers > totalPeers {
+		servers = totalPeers
+	}
+	return servers, totalPeers - servers
+}
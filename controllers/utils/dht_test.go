@@ -0,0 +1,83 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("DHT server resources", func() {
+	memoryOf := func(peers int) resource.Quantity {
+		return utils.DHTServerResources(peers).Requests[corev1.ResourceMemory]
+	}
+	cpuOf := func(peers int) resource.Quantity {
+		return utils.DHTServerResources(peers).Requests[corev1.ResourceCPU]
+	}
+
+	It("scales with the routing table size", func() {
+		sizes := []int{500, 1000, 5000, 20000, 100000}
+		tiers := []string{"2G", "2G", "4G", "8G", "16G"}
+		for i, peers := range sizes {
+			memory := memoryOf(peers)
+			Expect(memory.Cmp(resource.MustParse(tiers[i]))).To(Equal(0), "peers: %d", peers)
+		}
+		small, large := cpuOf(1000), cpuOf(20000)
+		Expect(large.Cmp(small)).To(Equal(1))
+	})
+
+	It("never starts below the memory floor of a DHT server", func() {
+		memory := memoryOf(0)
+		floor := utils.IPFSMemoryFloor(utils.RoutingTypeDHTServer)
+		Expect(memory.Cmp(floor)).To(BeNumerically(">=", 0))
+	})
+
+	It("requests the memory limit and lets CPU burst", func() {
+		resources := utils.DHTServerResources(5000)
+		memoryLimit := resources.Limits[corev1.ResourceMemory]
+		Expect(memoryLimit.Cmp(resources.Requests[corev1.ResourceMemory])).To(Equal(0))
+		cpuLimit, cpuRequest := resources.Limits[corev1.ResourceCPU], cpuOf(5000)
+		Expect(cpuLimit.MilliValue()).To(Equal(2 * cpuRequest.MilliValue()))
+	})
+
+	It("sizes storage peers by their storage", func() {
+		peer := utils.IPFSResourcesForRole(utils.EvictionRolePeer, 1<<40, 100000, true)
+		Expect(peer).NotTo(Equal(utils.DHTServerResources(100000)))
+		Expect(utils.IPFSResourcesForRole(utils.EvictionRoleDHTServer, 1<<40, 100000, true)).
+			To(Equal(utils.DHTServerResources(100000)))
+	})
+
+	It("gives jobs minimal resources", func() {
+		job := utils.IPFSResourcesForRole(utils.EvictionRoleJob, 1<<40, 100000, true)
+		Expect(job).To(Equal(utils.JobResources()))
+		peer := utils.IPFSResourcesForRole(utils.EvictionRolePeer, 1<<40, 100000, true)
+		jobMemory, peerMemory := job.Requests[corev1.ResourceMemory], peer.Requests[corev1.ResourceMemory]
+		Expect(jobMemory.Cmp(peerMemory)).To(Equal(-1))
+	})
+})
+
+var _ = Describe("DHT role split", func() {
+	DescribeTable("recommends servers and clients",
+		func(total, servers, clients int) {
+			s, c := utils.RecommendDHTRoles(total)
+			Expect(s).To(Equal(servers))
+			Expect(c).To(Equal(clients))
+		},
+		Entry("no peers", 0, 0, 0),
+		Entry("a single peer", 1, 1, 0),
+		Entry("a minimal cluster", 3, 3, 0),
+		Entry("a small cluster at the floor", 8, 3, 5),
+		Entry("a cluster above the floor", 13, 4, 9),
+		Entry("a large cluster", 100, 25, 75),
+	)
+
+	It("never recommends fewer servers than the floor", func() {
+		for total := 3; total <= 50; total++ {
+			servers, clients := utils.RecommendDHTRoles(total)
+			Expect(servers).To(BeNumerically(">=", 3))
+			Expect(servers + clients).To(Equal(total))
+		}
+	})
+})
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"sort"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// DrainPlan Determines which pins held by the peer being removed need to be re-replicated
+// onto the remaining peers so they keep at least minReplication copies. The returned pins
+// are sorted, and safe reports whether the peer can be removed without re-replicating anything.
+// A minReplication below 1, such as the "replicate everywhere" value of -1, requires at least
+// one copy to survive.
+func DrainPlan(
+	pins map[cid.Cid][]peer.ID,
+	removing peer.ID,
+	remaining []peer.ID,
+	minReplication int,
+) (rereplicate []cid.Cid, safe bool) {
+	if minReplication < 1 {
+		minReplication = 1
+	}
+	isRemaining := make(map[peer.ID]bool, len(remaining))
+	for _, p := range remaining {
+		if p != removing {
+			isRemaining[p] = true
+		}
+	}
+
+	rereplicate = make([]cid.Cid, 0)
+	for c, allocations := range pins {
+		heldByRemoving := false
+		replicas := 0
+		for _, p := range allocations {
+			switch {
+			case p == removing:
+				heldByRemoving = true
+			case isRemaining[p]:
+				replicas++
+			}
+		}
+		if heldByRemoving && replicas < minReplication {
+			rereplicate = append(rereplicate, c)
+		}
+	}
+	sort.Slice(rereplicate, func(i, j int) bool {
+		return rereplicate[i].KeyString() < rereplicate[j].KeyString()
+	})
+	return rereplicate, len(rereplicate) == 0
+}
@@ -0,0 +1,61 @@
+package utils_test
+
+import (
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p/core/peer"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Drain plan", func() {
+	var peerA, peerB, peerC peer.ID
+	var pinX, pinY cid.Cid
+
+	BeforeEach(func() {
+		var err error
+		for _, p := range []*peer.ID{&peerA, &peerB, &peerC} {
+			_, *p, err = utils.NewKey()
+			Expect(err).NotTo(HaveOccurred())
+		}
+		pinX, err = cid.Decode("QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG")
+		Expect(err).NotTo(HaveOccurred())
+		pinY, err = cid.Decode("bafybeigdyrzt5sfp7udm7hu76uh7y26nf3efuylqabf3oclgtqy55fbzdi")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("is safe when every pin keeps enough replicas", func() {
+		pins := map[cid.Cid][]peer.ID{
+			pinX: {peerA, peerB, peerC},
+			pinY: {peerB, peerC},
+		}
+		rereplicate, safe := utils.DrainPlan(pins, peerA, []peer.ID{peerB, peerC}, 2)
+		Expect(safe).To(BeTrue())
+		Expect(rereplicate).To(BeEmpty())
+	})
+
+	It("requires re-replication of pins uniquely held by the removed peer", func() {
+		pins := map[cid.Cid][]peer.ID{
+			pinX: {peerA},
+			pinY: {peerA, peerB},
+		}
+		rereplicate, safe := utils.DrainPlan(pins, peerA, []peer.ID{peerB, peerC}, 1)
+		Expect(safe).To(BeFalse())
+		Expect(rereplicate).To(ConsistOf(pinX))
+
+		rereplicate, safe = utils.DrainPlan(pins, peerA, []peer.ID{peerB, peerC}, 2)
+		Expect(safe).To(BeFalse())
+		Expect(rereplicate).To(ConsistOf(pinX, pinY))
+	})
+
+	It("requires at least one surviving copy when replicating everywhere", func() {
+		pins := map[cid.Cid][]peer.ID{
+			pinX: {peerA},
+			pinY: {peerA, peerB, peerC},
+		}
+		rereplicate, safe := utils.DrainPlan(pins, peerA, []peer.ID{peerB, peerC}, -1)
+		Expect(safe).To(BeFalse())
+		Expect(rereplicate).To(ConsistOf(pinX))
+	})
+})
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// EmptyDirUsage Returns the total size limit of the disk-backed emptyDir volumes of the given
+// pod spec, such as ephemeral repos and the export scratch space, along with the names of those
+// without a size limit, which may grow up to the node's whole ephemeral storage.
+// Memory-backed emptyDirs count against the memory limit instead and are skipped.
+func EmptyDirUsage(spec *corev1.PodSpec) (resource.Quantity, []string) {
+	total := resource.Quantity{}
+	var unbounded []string
+	for _, volume := range spec.Volumes {
+		emptyDir := volume.EmptyDir
+		if emptyDir == nil || emptyDir.Medium == corev1.StorageMediumMemory {
+			continue
+		}
+		if emptyDir.SizeLimit == nil {
+			unbounded = append(unbounded, volume.Name)
+			continue
+		}
+		total.Add(*emptyDir.SizeLimit)
+	}
+	return total, unbounded
+}
+
+// ValidateEphemeralStorageBudget Ensures the emptyDir volumes of the given pod spec fit into the
+// ephemeral storage budget of a node. Pods exceeding it are evicted once their volumes fill up
+// and get rescheduled onto another node with the same budget, looping through evictions.
+func ValidateEphemeralStorageBudget(spec *corev1.PodSpec, budget resource.Quantity) error {
+	total, unbounded := EmptyDirUsage(spec)
+	if len(unbounded) > 0 {
+		return fmt.Errorf("emptyDir volumes %v have no size limit and may exceed the ephemeral storage budget of %s",
+			unbounded, budget.String())
+	}
+	if total.Cmp(budget) > 0 {
+		return fmt.Errorf("emptyDir volumes need %s, exceeding the ephemeral storage budget of %s",
+			total.String(), budget.String())
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Ephemeral storage budget", func() {
+	emptyDir := func(name string, size string) corev1.Volume {
+		volume := corev1.Volume{
+			Name:         name,
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		}
+		if size != "" {
+			limit := resource.MustParse(size)
+			volume.EmptyDir.SizeLimit = &limit
+		}
+		return volume
+	}
+
+	var spec *corev1.PodSpec
+
+	BeforeEach(func() {
+		scratch, _ := utils.ExportScratchVolume(resource.MustParse("2Gi"))
+		spec = &corev1.PodSpec{
+			Volumes: []corev1.Volume{emptyDir("ipfs-repo", "20Gi"), scratch},
+		}
+	})
+
+	It("accepts a repo and scratch space within budget", func() {
+		Expect(utils.ValidateEphemeralStorageBudget(spec, resource.MustParse("50Gi"))).To(Succeed())
+		total, unbounded := utils.EmptyDirUsage(spec)
+		Expect(total.Cmp(resource.MustParse("22Gi"))).To(Equal(0))
+		Expect(unbounded).To(BeEmpty())
+	})
+
+	It("reports a repo and scratch space over budget", func() {
+		err := utils.ValidateEphemeralStorageBudget(spec, resource.MustParse("21Gi"))
+		Expect(err).To(MatchError(ContainSubstring("exceeding")))
+	})
+
+	It("reports emptyDirs without a size limit", func() {
+		spec.Volumes = append(spec.Volumes, emptyDir("cache", ""))
+		err := utils.ValidateEphemeralStorageBudget(spec, resource.MustParse("50Gi"))
+		Expect(err).To(MatchError(ContainSubstring("cache")))
+	})
+
+	It("skips memory-backed emptyDirs", func() {
+		memory := emptyDir("shm", "")
+		memory.EmptyDir.Medium = corev1.StorageMediumMemory
+		spec.Volumes = append(spec.Volumes, memory)
+		Expect(utils.ValidateEphemeralStorageBudget(spec, resource.MustParse("50Gi"))).To(Succeed())
+	})
+})
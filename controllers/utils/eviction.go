@@ -0,0 +1,122 @@
+package utils
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+)
+
+// EvictionRole Describes how important a pod is to keep when a node comes under pressure.
+type EvictionRole string
+
+const (
+	// EvictionRolePeer Marks a storage peer which should be evicted last.
+	EvictionRolePeer EvictionRole = "peer"
+	// EvictionRoleGateway Marks a stateless gateway which should be evicted
+	// before storage peers.
+	EvictionRoleGateway EvictionRole = "gateway"
+	// EvictionRoleDHTServer Marks a node dedicated to serving the DHT, which holds no
+	// pinned content and should be evicted before storage peers.
+	EvictionRoleDHTServer EvictionRole = "dht-server"
+	// EvictionRoleJob Marks a one-shot pod adding content before exiting.
+	EvictionRoleJob EvictionRole = "job"
+
+	// AnnotationEvictionRole Records the eviction role applied to a pod.
+	AnnotationEvictionRole = "cluster.ipfs.io/eviction-role"
+)
+
+// ClusterEvictionRole Returns the eviction role of the IPFS nodes of the given cluster.
+func ClusterEvictionRole(m *clusterv1alpha1.IpfsCluster) EvictionRole {
+	if m.Spec.Role == clusterv1alpha1.NodeRoleDHTServer {
+		return EvictionRoleDHTServer
+	}
+	return EvictionRolePeer
+}
+
+// ApplyEvictionOrdering Adjusts the QoS class and priority of the given pod template so that
+// the kubelet evicts gateways, DHT servers and jobs before storage peers under node pressure. The
+// resources of each container are adjusted by EvictionOrderedResources, and the pod is annotated
+// by AnnotateEvictionRole.
+func ApplyEvictionOrdering(tmpl *corev1.PodTemplateSpec, role EvictionRole, priorityClassName string) {
+	for i := range tmpl.Spec.Containers {
+		tmpl.Spec.Containers[i].Resources = EvictionOrderedResources(tmpl.Spec.Containers[i].Resources, role)
+	}
+	AnnotateEvictionRole(tmpl, role, priorityClassName)
+}
+
+// EvictionOrderedResources Returns the given resource requirements adjusted so that gateways, DHT
+// servers and jobs are evicted before storage peers. The others are kept Burstable by requesting
+// less than their limits, unless they already request less than one of them. The requirements of
+// peers are returned unchanged, since peers are protected by their PriorityClass and raising their
+// requests to their limits would multiply the resources they hold on to.
+func EvictionOrderedResources(resources corev1.ResourceRequirements, role EvictionRole) corev1.ResourceRequirements {
+	ordered := *resources.DeepCopy()
+	switch role {
+	case EvictionRoleGateway, EvictionRoleDHTServer, EvictionRoleJob:
+		if requestsBelowLimits(ordered) {
+			break
+		}
+		for name, limit := range ordered.Limits {
+			if ordered.Requests == nil {
+				ordered.Requests = corev1.ResourceList{}
+			}
+			ordered.Requests[name] = *resource.NewMilliQuantity(limit.MilliValue()/2, limit.Format)
+		}
+	}
+	return ordered
+}
+
+// AnnotateEvictionRole Records the given eviction role on the pod template and sets the given
+// PriorityClass, if any, without touching the resources of its containers.
+func AnnotateEvictionRole(tmpl *corev1.PodTemplateSpec, role EvictionRole, priorityClassName string) {
+	if priorityClassName != "" {
+		tmpl.Spec.PriorityClassName = priorityClassName
+	}
+	if tmpl.Annotations == nil {
+		tmpl.Annotations = make(map[string]string, 1)
+	}
+	tmpl.Annotations[AnnotationEvictionRole] = string(role)
+}
+
+// requestsBelowLimits Returns whether the given requirements request less than one of their limits.
+func requestsBelowLimits(resources corev1.ResourceRequirements) bool {
+	for name, limit := range resources.Limits {
+		if request, ok := resources.Requests[name]; ok && request.Cmp(limit) < 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// PodQOSClass Returns the QoS class Kubernetes assigns to a pod with the given spec.
+func PodQOSClass(spec *corev1.PodSpec) corev1.PodQOSClass {
+	hasResources := false
+	guaranteed := true
+	containers := append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...)
+	for _, container := range containers {
+		requests := container.Resources.Requests
+		limits := container.Resources.Limits
+		if len(requests) > 0 || len(limits) > 0 {
+			hasResources = true
+		}
+		for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			limit, hasLimit := limits[name]
+			if !hasLimit {
+				guaranteed = false
+				continue
+			}
+			if request, hasRequest := requests[name]; hasRequest && request.Cmp(limit) != 0 {
+				guaranteed = false
+			}
+		}
+	}
+	switch {
+	case !hasResources:
+		return corev1.PodQOSBestEffort
+	case guaranteed:
+		return corev1.PodQOSGuaranteed
+	default:
+		return corev1.PodQOSBurstable
+	}
+}
@@ -0,0 +1,95 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Eviction ordering", func() {
+	var newTemplate func() *corev1.PodTemplateSpec
+
+	BeforeEach(func() {
+		newTemplate = func() *corev1.PodTemplateSpec {
+			tmpl := &corev1.PodTemplateSpec{}
+			tmpl.Spec.Containers = []corev1.Container{{
+				Name:      "ipfs",
+				Resources: utils.IPFSContainerResources(1 << 40),
+			}}
+			return tmpl
+		}
+	})
+
+	It("evicts gateways before peers", func() {
+		peer := newTemplate()
+		gateway := newTemplate()
+		resources := &gateway.Spec.Containers[0].Resources
+		for name, limit := range resources.Limits {
+			resources.Requests[name] = limit.DeepCopy()
+		}
+		utils.ApplyEvictionOrdering(peer, utils.EvictionRolePeer, "ipfs-peer")
+		utils.ApplyEvictionOrdering(gateway, utils.EvictionRoleGateway, "ipfs-gateway")
+
+		gatewayResources := gateway.Spec.Containers[0].Resources
+		gatewayMemory := gatewayResources.Requests[corev1.ResourceMemory]
+		Expect(gatewayMemory.Cmp(gatewayResources.Limits[corev1.ResourceMemory])).To(Equal(-1))
+		Expect(utils.PodQOSClass(&gateway.Spec)).To(Equal(corev1.PodQOSBurstable))
+
+		Expect(peer.Spec.PriorityClassName).To(Equal("ipfs-peer"))
+		Expect(gateway.Spec.PriorityClassName).To(Equal("ipfs-gateway"))
+		Expect(gateway.Annotations).To(HaveKeyWithValue(utils.AnnotationEvictionRole, string(utils.EvictionRoleGateway)))
+	})
+
+	It("keeps the resources of peers", func() {
+		peer := newTemplate()
+		want := peer.Spec.Containers[0].Resources.DeepCopy()
+		utils.ApplyEvictionOrdering(peer, utils.EvictionRolePeer, "")
+		Expect(peer.Spec.Containers[0].Resources).To(Equal(*want))
+		Expect(peer.Annotations).To(HaveKeyWithValue(utils.AnnotationEvictionRole, string(utils.EvictionRolePeer)))
+	})
+
+	It("keeps user-set resources when only annotating the role", func() {
+		tmpl := newTemplate()
+		resources := &tmpl.Spec.Containers[0].Resources
+		resources.Requests[corev1.ResourceMemory] = resources.Limits[corev1.ResourceMemory].DeepCopy()
+		want := resources.DeepCopy()
+		utils.AnnotateEvictionRole(tmpl, utils.EvictionRoleDHTServer, "ipfs-dht")
+		Expect(tmpl.Spec.Containers[0].Resources).To(Equal(*want))
+		Expect(tmpl.Spec.PriorityClassName).To(Equal("ipfs-dht"))
+		Expect(tmpl.Annotations).To(HaveKeyWithValue(utils.AnnotationEvictionRole, string(utils.EvictionRoleDHTServer)))
+	})
+
+	It("keeps gateways burstable when they request their limits", func() {
+		gateway := newTemplate()
+		resources := &gateway.Spec.Containers[0].Resources
+		for name, limit := range resources.Limits {
+			resources.Requests[name] = limit.DeepCopy()
+		}
+		Expect(utils.PodQOSClass(&gateway.Spec)).To(Equal(corev1.PodQOSGuaranteed))
+
+		utils.ApplyEvictionOrdering(gateway, utils.EvictionRoleGateway, "")
+		Expect(utils.PodQOSClass(&gateway.Spec)).To(Equal(corev1.PodQOSBurstable))
+		Expect(gateway.Spec.PriorityClassName).To(BeEmpty())
+	})
+
+	It("keeps the memory request of burstable DHT servers", func() {
+		m := &clusterv1alpha1.IpfsCluster{}
+		m.Spec.Role = clusterv1alpha1.NodeRoleDHTServer
+		role := utils.ClusterEvictionRole(m)
+		Expect(role).To(Equal(utils.EvictionRoleDHTServer))
+
+		dhtServer := newTemplate()
+		dhtServer.Spec.Containers[0].Resources = utils.IPFSResourcesForRole(role, 1<<40, 2000, true)
+		want := dhtServer.Spec.Containers[0].Resources.DeepCopy()
+		utils.ApplyEvictionOrdering(dhtServer, role, "")
+		Expect(utils.PodQOSClass(&dhtServer.Spec)).To(Equal(corev1.PodQOSBurstable))
+		Expect(dhtServer.Spec.Containers[0].Resources).To(Equal(*want))
+	})
+
+	It("classifies pods without resources as best effort", func() {
+		Expect(utils.PodQOSClass(&corev1.PodSpec{Containers: []corev1.Container{{}}})).To(Equal(corev1.PodQOSBestEffort))
+	})
+})
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+)
+
+// IsExtendedResourceName Returns true if the given resource is an extended resource, e.g. a device
+// advertised by a device plugin such as `nvidia.com/gpu`, rather than one native to Kubernetes.
+func IsExtendedResourceName(name corev1.ResourceName) bool {
+	n := string(name)
+	if !strings.Contains(n, "/") || strings.HasPrefix(n, corev1.ResourceDefaultNamespacePrefix) {
+		return false
+	}
+	return !strings.HasPrefix(n, corev1.DefaultResourceRequestsPrefix)
+}
+
+// ValidateExtendedResources Ensures that the extended resources requested by the given requirements
+// fit the given available amounts, e.g. the most any single node provides. A pod requesting an
+// extended resource no node provides enough of stays Pending forever. Since extended resources
+// cannot be overcommitted, a limit without a request counts as the request.
+func ValidateExtendedResources(resources *corev1.ResourceRequirements, available corev1.ResourceList) error {
+	if resources == nil {
+		return nil
+	}
+	requested := make(corev1.ResourceList)
+	for name, quantity := range resources.Limits {
+		if IsExtendedResourceName(name) {
+			requested[name] = quantity
+		}
+	}
+	for name, quantity := range resources.Requests {
+		if IsExtendedResourceName(name) {
+			requested[name] = quantity
+		}
+	}
+	unsatisfiable := make([]string, 0)
+	for name, quantity := range requested {
+		if have, ok := available[name]; !ok || quantity.Cmp(have) > 0 {
+			unsatisfiable = append(unsatisfiable, fmt.Sprintf("%s (requested %s, available %s)",
+				name, quantity.String(), have.String()))
+		}
+	}
+	if len(unsatisfiable) > 0 {
+		sort.Strings(unsatisfiable)
+		return fmt.Errorf("no node provides the requested extended resources: %s", strings.Join(unsatisfiable, ", "))
+	}
+	return nil
+}
+
+// ExtendedResourcesCondition Returns the condition reporting whether the extended resources requested
+// by the given requirements are available, as validated by ValidateExtendedResources.
+func ExtendedResourcesCondition(
+	resources *corev1.ResourceRequirements,
+	available corev1.ResourceList,
+) metav1.Condition {
+	if err := ValidateExtendedResources(resources, available); err != nil {
+		return metav1.Condition{
+			Type:    clusterv1alpha1.ConditionExtendedResourcesSchedulable,
+			Status:  metav1.ConditionFalse,
+			Reason:  clusterv1alpha1.ExtendedResourcesReasonUnschedulable,
+			Message: err.Error(),
+		}
+	}
+	return metav1.Condition{
+		Type:    clusterv1alpha1.ConditionExtendedResourcesSchedulable,
+		Status:  metav1.ConditionTrue,
+		Reason:  clusterv1alpha1.ExtendedResourcesReasonSatisfiable,
+		Message: "every requested extended resource is available",
+	}
+}
@@ -0,0 +1,58 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Extended resource requests", func() {
+	const gpu corev1.ResourceName = "nvidia.com/gpu"
+	available := corev1.ResourceList{
+		gpu: resource.MustParse("2"),
+	}
+
+	It("tells extended resources apart from native ones", func() {
+		Expect(utils.IsExtendedResourceName(gpu)).To(BeTrue())
+		Expect(utils.IsExtendedResourceName(corev1.ResourceCPU)).To(BeFalse())
+		Expect(utils.IsExtendedResourceName("hugepages-2Mi")).To(BeFalse())
+		Expect(utils.IsExtendedResourceName("kubernetes.io/batch-cpu")).To(BeFalse())
+	})
+
+	It("accepts a satisfiable request", func() {
+		resources := &corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("64Gi"),
+				gpu:                   resource.MustParse("1"),
+			},
+		}
+		Expect(utils.ValidateExtendedResources(resources, available)).To(Succeed())
+		Expect(utils.ValidateExtendedResources(nil, nil)).To(Succeed())
+		cond := utils.ExtendedResourcesCondition(resources, available)
+		Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+		Expect(cond.Reason).To(Equal(clusterv1alpha1.ExtendedResourcesReasonSatisfiable))
+	})
+
+	It("reports an unsatisfiable request as unschedulable", func() {
+		resources := &corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{
+				gpu:                  resource.MustParse("4"),
+				"example.com/device": resource.MustParse("1"),
+			},
+		}
+		err := utils.ValidateExtendedResources(resources, available)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("nvidia.com/gpu"))
+		Expect(err.Error()).To(ContainSubstring("example.com/device"))
+
+		cond := utils.ExtendedResourcesCondition(resources, available)
+		Expect(cond.Type).To(Equal(clusterv1alpha1.ConditionExtendedResourcesSchedulable))
+		Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+		Expect(cond.Reason).To(Equal(clusterv1alpha1.ExtendedResourcesReasonUnschedulable))
+	})
+})
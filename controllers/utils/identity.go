@@ -0,0 +1,279 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+)
+
+// SharedIdentitySecretOwners Lists the IpfsClusters in the same namespace and returns the names
+// of all clusters other than the given one which use the same identity Secret.
+// Sharing an identity Secret produces peers with identical IDs, corrupting both clusters.
+func SharedIdentitySecretOwners(
+	ctx context.Context,
+	c client.Client,
+	m *clusterv1alpha1.IpfsCluster,
+) ([]string, error) {
+	clusters := &clusterv1alpha1.IpfsClusterList{}
+	if err := c.List(ctx, clusters, client.InNamespace(m.Namespace)); err != nil {
+		return nil, fmt.Errorf("could not list ipfs clusters: %w", err)
+	}
+	secretName := m.IdentitySecretName()
+	owners := make([]string, 0)
+	for i := range clusters.Items {
+		other := &clusters.Items[i]
+		if other.Name == m.Name {
+			continue
+		}
+		if other.IdentitySecretName() == secretName {
+			owners = append(owners, other.Name)
+		}
+	}
+	return owners, nil
+}
+
+// ValidateIdentitySecret Returns an error if the identity Secret of the given IpfsCluster
+// is also used by another IpfsCluster.
+func ValidateIdentitySecret(ctx context.Context, c client.Client, m *clusterv1alpha1.IpfsCluster) error {
+	owners, err := SharedIdentitySecretOwners(ctx, c, m)
+	if err != nil {
+		return err
+	}
+	if len(owners) > 0 {
+		return fmt.Errorf("identity secret %q is already used by: %v", m.IdentitySecretName(), owners)
+	}
+	return nil
+}
+
+// secretValue Returns the value stored under the given key of the Secret, preferring
+// StringData since it has not been merged into Data before the Secret is written.
+func secretValue(secret *corev1.Secret, key string) (string, bool) {
+	if v, ok := secret.StringData[key]; ok {
+		return v, true
+	}
+	v, ok := secret.Data[key]
+	return string(v), ok
+}
+
+// PeerIDToCIDv1 Returns the given peer ID as a base32 CIDv1 with the libp2p-key codec, e.g.
+// `bafzaa...` rather than `12D3KooW...`, the form newer go-ipfs and libp2p tooling
+// display peer IDs in. Both forms decode to the same peer ID, but the generated configs,
+// Secrets and multiaddrs consistently use the base58btc form returned by peer.ID.String,
+// which every IPFS and IPFS Cluster version reads; this form is only meant for display.
+func PeerIDToCIDv1(id peer.ID) string {
+	return peer.ToCid(id).String()
+}
+
+// EnsureOrdinalIdentity Returns the identity stored in the Secret under the given peer ID
+// and private key entries, so that a peer removed by a scale-down regains its original
+// identity when it is added back. A new identity is only generated and stored if the Secret
+// holds neither entry, in which case generated is true. An identity missing only one of its
+// entries is reported as an error rather than being replaced. A peer ID stored in another
+// encoding, such as a CIDv1, is rewritten into the base58btc form.
+func EnsureOrdinalIdentity(
+	secret *corev1.Secret,
+	peerIDKey, privateKeyKey string,
+) (peerID peer.ID, generated bool, err error) {
+	existingID, hasID := secretValue(secret, peerIDKey)
+	_, hasKey := secretValue(secret, privateKeyKey)
+	if hasID && hasKey {
+		if peerID, err = peer.Decode(existingID); err != nil {
+			return "", false, fmt.Errorf("invalid peer ID stored under %q: %w", peerIDKey, err)
+		}
+		// keep the Secret in the base58btc form the configs are rendered with
+		if existingID != peerID.String() {
+			if secret.StringData == nil {
+				secret.StringData = make(map[string]string, 1)
+			}
+			secret.StringData[peerIDKey] = peerID.String()
+		}
+		return peerID, false, nil
+	}
+	if hasID || hasKey {
+		return "", false, fmt.Errorf("incomplete identity: %q and %q must both be present", peerIDKey, privateKeyKey)
+	}
+	peerID, privKey, err := GenerateIdentity()
+	if err != nil {
+		return "", false, err
+	}
+	if secret.StringData == nil {
+		secret.StringData = make(map[string]string, 2)
+	}
+	secret.StringData[peerIDKey] = peerID.String()
+	secret.StringData[privateKeyKey] = privKey
+	return peerID, true, nil
+}
+
+const (
+	// LegacyIdentityKeyPeerID Is the key the peer ID is stored under in a legacy per-ordinal Secret.
+	LegacyIdentityKeyPeerID = "peerID"
+	// LegacyIdentityKeyPrivateKey Is the key the private key is stored under in a legacy per-ordinal Secret.
+	LegacyIdentityKeyPrivateKey = "privateKey"
+)
+
+// IdentitySource Describes which Secret an identity was read from.
+type IdentitySource string
+
+const (
+	// IdentitySourceNone Means neither Secret holds the identity.
+	IdentitySourceNone IdentitySource = ""
+	// IdentitySourceConsolidated Means the identity was read from the consolidated Secret.
+	IdentitySourceConsolidated IdentitySource = "consolidated"
+	// IdentitySourceLegacy Means the identity was read from the legacy per-ordinal Secret.
+	IdentitySourceLegacy IdentitySource = "legacy"
+)
+
+// LegacyIdentitySecretName Returns the name of the legacy Secret which held the identity of
+// the given ordinal alone, before identities were consolidated into a single Secret.
+func LegacyIdentitySecretName(consolidatedName string, ordinal int32) string {
+	return fmt.Sprintf("%s-%d", consolidatedName, ordinal)
+}
+
+// ReadOrdinalIdentity Returns the identity of an ordinal during the migration to the consolidated
+// Secret, preferring the entries stored under the given keys of the consolidated Secret and falling
+// back to the legacy per-ordinal Secret, so that no peer loses its key while both are in use.
+// Either Secret may be nil. An identity missing one of its entries is reported as an error.
+func ReadOrdinalIdentity(
+	consolidated, legacy *corev1.Secret,
+	peerIDKey, privateKeyKey string,
+) (peerID, privateKey string, source IdentitySource, err error) {
+	read := func(secret *corev1.Secret, idKey, keyKey string) (string, string, bool, error) {
+		if secret == nil {
+			return "", "", false, nil
+		}
+		id, hasID := secretValue(secret, idKey)
+		key, hasKey := secretValue(secret, keyKey)
+		if hasID != hasKey {
+			return "", "", false, fmt.Errorf(
+				"incomplete identity in secret %q: %q and %q must both be present", secret.Name, idKey, keyKey,
+			)
+		}
+		return id, key, hasID, nil
+	}
+	peerID, privateKey, found, err := read(consolidated, peerIDKey, privateKeyKey)
+	if err != nil || found {
+		return peerID, privateKey, IdentitySourceConsolidated, err
+	}
+	peerID, privateKey, found, err = read(legacy, LegacyIdentityKeyPeerID, LegacyIdentityKeyPrivateKey)
+	if err != nil || !found {
+		return "", "", IdentitySourceNone, err
+	}
+	return peerID, privateKey, IdentitySourceLegacy, nil
+}
+
+// MigrateLegacyIdentity Copies the identity held by the legacy per-ordinal Secret into the given
+// keys of the consolidated Secret, unless the consolidated Secret already holds one, and returns
+// whether it was copied. The legacy Secret is left untouched so that it can still be read until
+// every peer runs from the consolidated Secret.
+func MigrateLegacyIdentity(consolidated, legacy *corev1.Secret, peerIDKey, privateKeyKey string) (bool, error) {
+	peerID, privateKey, source, err := ReadOrdinalIdentity(consolidated, legacy, peerIDKey, privateKeyKey)
+	if err != nil || source != IdentitySourceLegacy {
+		return false, err
+	}
+	if _, err = peer.Decode(peerID); err != nil {
+		return false, fmt.Errorf("invalid peer ID stored in secret %q: %w", legacy.Name, err)
+	}
+	if consolidated.StringData == nil {
+		consolidated.StringData = make(map[string]string, 2)
+	}
+	consolidated.StringData[peerIDKey] = peerID
+	consolidated.StringData[privateKeyKey] = privateKey
+	return true, nil
+}
+
+// IdentitySlots Returns how many ordinals, counting up from 0, hold a complete identity in the
+// Secret under the given peer ID and private key prefixes, e.g. `peerID-0` and `privateKey-0`.
+// A peer beyond these slots would start without an identity.
+func IdentitySlots(secret *corev1.Secret, peerIDPrefix, privateKeyPrefix string) int32 {
+	var slots int32
+	for {
+		ordinal := strconv.Itoa(int(slots))
+		_, hasID := secretValue(secret, peerIDPrefix+ordinal)
+		_, hasKey := secretValue(secret, privateKeyPrefix+ordinal)
+		if !hasID || !hasKey {
+			return slots
+		}
+		slots++
+	}
+}
+
+// ClampMaxReplicasToIdentities Returns the maxReplicas the autoscaler may scale the peers up to,
+// given the identities held by the Secret. When generate is set, the identities of the ordinals
+// up to maxReplicas are generated on demand and stored in the Secret, leaving maxReplicas as is.
+// Otherwise maxReplicas is clamped to the available identity slots.
+func ClampMaxReplicasToIdentities(
+	secret *corev1.Secret,
+	maxReplicas int32,
+	peerIDPrefix, privateKeyPrefix string,
+	generate bool,
+) (int32, error) {
+	if maxReplicas < 0 {
+		return 0, fmt.Errorf("max replicas cannot be negative, got %d", maxReplicas)
+	}
+	if !generate {
+		if slots := IdentitySlots(secret, peerIDPrefix, privateKeyPrefix); slots < maxReplicas {
+			return slots, nil
+		}
+		return maxReplicas, nil
+	}
+	for i := int32(0); i < maxReplicas; i++ {
+		ordinal := strconv.Itoa(int(i))
+		if _, _, err := EnsureOrdinalIdentity(secret, peerIDPrefix+ordinal, privateKeyPrefix+ordinal); err != nil {
+			return 0, fmt.Errorf("could not ensure identity for replica %d: %w", i, err)
+		}
+	}
+	return maxReplicas, nil
+}
+
+// PeerIdentityMismatches Returns the ordinals, in ascending order, of the peers announcing a peer
+// ID other than the one stored for them in the Secret, given the peer ID announced by each ordinal.
+// Since a repo is only initialized once, a peer whose PVC was left over from a previous identity
+// keeps announcing that identity until the PVC is removed. Ordinals without a stored peer ID are
+// left out, and announced IDs are compared regardless of their encoding.
+func PeerIdentityMismatches(secret *corev1.Secret, peerIDPrefix string, announced map[int32]string) ([]int32, error) {
+	mismatched := make([]int32, 0)
+	for ordinal, live := range announced {
+		stored, ok := secretValue(secret, peerIDPrefix+strconv.Itoa(int(ordinal)))
+		if !ok {
+			continue
+		}
+		expected, err := peer.Decode(stored)
+		if err != nil {
+			return nil, fmt.Errorf("invalid peer ID stored for replica %d: %w", ordinal, err)
+		}
+		actual, err := peer.Decode(live)
+		if err != nil || actual != expected {
+			mismatched = append(mismatched, ordinal)
+		}
+	}
+	sort.Slice(mismatched, func(i, j int) bool { return mismatched[i] < mismatched[j] })
+	return mismatched, nil
+}
+
+// PeerIdentityMismatchCondition Returns the condition reporting the peers which announce a peer
+// ID other than their expected one, so that their PVCs can be remediated.
+func PeerIdentityMismatchCondition(mismatched []int32) metav1.Condition {
+	if len(mismatched) == 0 {
+		return metav1.Condition{
+			Type:    clusterv1alpha1.ConditionPeerIdentityMismatch,
+			Status:  metav1.ConditionFalse,
+			Reason:  clusterv1alpha1.PeerIdentityReasonMatched,
+			Message: "every peer announces its expected peer ID",
+		}
+	}
+	return metav1.Condition{
+		Type:   clusterv1alpha1.ConditionPeerIdentityMismatch,
+		Status: metav1.ConditionTrue,
+		Reason: clusterv1alpha1.PeerIdentityReasonMismatched,
+		Message: fmt.Sprintf("replicas %v announce a peer ID other than their identity secret, "+
+			"their repos were initialized with another identity and their PVCs must be removed", mismatched),
+	}
+}
@@ -0,0 +1,320 @@
+package utils_test
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Identity secret validation", func() {
+	var ctx context.Context
+	var scheme *runtime.Scheme
+
+	newCluster := func(name, secretName string) *clusterv1alpha1.IpfsCluster {
+		m := &clusterv1alpha1.IpfsCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "test",
+			},
+		}
+		if secretName != "" {
+			m.Spec.IdentitySecretRef = &corev1.LocalObjectReference{Name: secretName}
+		}
+		return m
+	}
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		scheme = runtime.NewScheme()
+		Expect(clusterv1alpha1.AddToScheme(scheme)).To(Succeed())
+	})
+
+	It("reports a conflicting cluster", func() {
+		mine := newCluster("mine", "shared-identity")
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			mine,
+			newCluster("theirs", "shared-identity"),
+			newCluster("unrelated", ""),
+		).Build()
+
+		owners, err := utils.SharedIdentitySecretOwners(ctx, c, mine)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(owners).To(Equal([]string{"theirs"}))
+		Expect(utils.ValidateIdentitySecret(ctx, c, mine)).NotTo(Succeed())
+	})
+
+	It("detects a reference to another cluster's generated secret", func() {
+		mine := newCluster("mine", "ipfs-cluster-theirs")
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(mine, newCluster("theirs", "")).Build()
+		Expect(utils.ValidateIdentitySecret(ctx, c, mine)).NotTo(Succeed())
+	})
+
+	It("accepts clusters with their own secrets", func() {
+		mine := newCluster("mine", "")
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			mine,
+			newCluster("theirs", ""),
+			newCluster("other", "other-identity"),
+		).Build()
+
+		owners, err := utils.SharedIdentitySecretOwners(ctx, c, mine)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(owners).To(BeEmpty())
+		Expect(utils.ValidateIdentitySecret(ctx, c, mine)).To(Succeed())
+	})
+})
+
+var _ = Describe("Ordinal identities", func() {
+	It("reuses the identity of a previously seen ordinal", func() {
+		peerID, privKey, err := utils.GenerateIdentity()
+		Expect(err).NotTo(HaveOccurred())
+		secret := &corev1.Secret{
+			Data: map[string][]byte{
+				"peerID-2":     []byte(peerID.String()),
+				"privateKey-2": []byte(privKey),
+			},
+		}
+		id, generated, err := utils.EnsureOrdinalIdentity(secret, "peerID-2", "privateKey-2")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(generated).To(BeFalse())
+		Expect(id).To(Equal(peerID))
+		Expect(secret.StringData).To(BeEmpty())
+	})
+
+	It("generates an identity for a never-seen ordinal", func() {
+		secret := &corev1.Secret{}
+		id, generated, err := utils.EnsureOrdinalIdentity(secret, "peerID-3", "privateKey-3")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(generated).To(BeTrue())
+		Expect(secret.StringData["peerID-3"]).To(Equal(id.String()))
+		Expect(secret.StringData["privateKey-3"]).NotTo(BeEmpty())
+
+		// the generated identity is stable on subsequent calls
+		again, generated, err := utils.EnsureOrdinalIdentity(secret, "peerID-3", "privateKey-3")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(generated).To(BeFalse())
+		Expect(again).To(Equal(id))
+	})
+
+	It("refuses to replace an incomplete identity", func() {
+		secret := &corev1.Secret{
+			Data: map[string][]byte{
+				"privateKey-1": []byte("key"),
+			},
+		}
+		_, _, err := utils.EnsureOrdinalIdentity(secret, "peerID-1", "privateKey-1")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Peer ID encodings", func() {
+	It("round-trips the base58btc and CIDv1 forms of a peer ID", func() {
+		peerID, _, err := utils.GenerateIdentity()
+		Expect(err).NotTo(HaveOccurred())
+
+		cidV1 := utils.PeerIDToCIDv1(peerID)
+		Expect(cidV1).To(HavePrefix("b"))
+		Expect(cidV1).NotTo(Equal(peerID.String()))
+
+		fromCID, err := peer.Decode(cidV1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fromCID).To(Equal(peerID))
+		fromBase58, err := peer.Decode(peerID.String())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(utils.PeerIDToCIDv1(fromBase58)).To(Equal(cidV1))
+	})
+
+	It("stores peer IDs in the base58btc form", func() {
+		peerID, privKey, err := utils.GenerateIdentity()
+		Expect(err).NotTo(HaveOccurred())
+		secret := &corev1.Secret{
+			Data: map[string][]byte{
+				"peerID-0":     []byte(utils.PeerIDToCIDv1(peerID)),
+				"privateKey-0": []byte(privKey),
+			},
+		}
+		id, generated, err := utils.EnsureOrdinalIdentity(secret, "peerID-0", "privateKey-0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(generated).To(BeFalse())
+		Expect(id).To(Equal(peerID))
+		Expect(secret.StringData["peerID-0"]).To(Equal(peerID.String()))
+	})
+})
+
+var _ = Describe("Identity format migration", func() {
+	var legacyID, consolidatedID peer.ID
+	var legacyKey, consolidatedKey string
+	var legacy, consolidated *corev1.Secret
+
+	BeforeEach(func() {
+		var err error
+		legacyID, legacyKey, err = utils.GenerateIdentity()
+		Expect(err).NotTo(HaveOccurred())
+		consolidatedID, consolidatedKey, err = utils.GenerateIdentity()
+		Expect(err).NotTo(HaveOccurred())
+		legacy = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: utils.LegacyIdentitySecretName("ipfs-cluster-test", 1)},
+			Data: map[string][]byte{
+				utils.LegacyIdentityKeyPeerID:     []byte(legacyID.String()),
+				utils.LegacyIdentityKeyPrivateKey: []byte(legacyKey),
+			},
+		}
+		consolidated = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "ipfs-cluster-test"},
+			Data:       map[string][]byte{},
+		}
+	})
+
+	It("names the legacy secret after the ordinal", func() {
+		Expect(legacy.Name).To(Equal("ipfs-cluster-test-1"))
+	})
+
+	It("prefers the consolidated secret", func() {
+		consolidated.Data["peerID-1"] = []byte(consolidatedID.String())
+		consolidated.Data["privateKey-1"] = []byte(consolidatedKey)
+		id, key, source, err := utils.ReadOrdinalIdentity(consolidated, legacy, "peerID-1", "privateKey-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(source).To(Equal(utils.IdentitySourceConsolidated))
+		Expect(id).To(Equal(consolidatedID.String()))
+		Expect(key).To(Equal(consolidatedKey))
+	})
+
+	It("falls back to the legacy secret", func() {
+		id, key, source, err := utils.ReadOrdinalIdentity(consolidated, legacy, "peerID-1", "privateKey-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(source).To(Equal(utils.IdentitySourceLegacy))
+		Expect(id).To(Equal(legacyID.String()))
+		Expect(key).To(Equal(legacyKey))
+
+		_, _, source, err = utils.ReadOrdinalIdentity(consolidated, nil, "peerID-1", "privateKey-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(source).To(Equal(utils.IdentitySourceNone))
+	})
+
+	It("reports incomplete identities", func() {
+		delete(legacy.Data, utils.LegacyIdentityKeyPrivateKey)
+		_, _, _, err := utils.ReadOrdinalIdentity(consolidated, legacy, "peerID-1", "privateKey-1")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("copies the legacy identity into the consolidated secret", func() {
+		copied, err := utils.MigrateLegacyIdentity(consolidated, legacy, "peerID-1", "privateKey-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(copied).To(BeTrue())
+		Expect(consolidated.StringData["peerID-1"]).To(Equal(legacyID.String()))
+		Expect(consolidated.StringData["privateKey-1"]).To(Equal(legacyKey))
+		Expect(legacy.Data).To(HaveLen(2))
+
+		// the migrated identity is kept, rather than regenerated
+		id, generated, err := utils.EnsureOrdinalIdentity(consolidated, "peerID-1", "privateKey-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(generated).To(BeFalse())
+		Expect(id).To(Equal(legacyID))
+
+		copied, err = utils.MigrateLegacyIdentity(consolidated, legacy, "peerID-1", "privateKey-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(copied).To(BeFalse())
+	})
+})
+
+var _ = Describe("Autoscaler identity slots", func() {
+	newSecret := func(ordinals int) *corev1.Secret {
+		secret := &corev1.Secret{Data: map[string][]byte{}}
+		for i := 0; i < ordinals; i++ {
+			peerID, privKey, err := utils.GenerateIdentity()
+			Expect(err).NotTo(HaveOccurred())
+			secret.Data["peerID-"+strconv.Itoa(i)] = []byte(peerID.String())
+			secret.Data["privateKey-"+strconv.Itoa(i)] = []byte(privKey)
+		}
+		return secret
+	}
+
+	It("clamps maxReplicas to the available identities", func() {
+		secret := newSecret(3)
+		Expect(utils.IdentitySlots(secret, "peerID-", "privateKey-")).To(BeEquivalentTo(3))
+		maxReplicas, err := utils.ClampMaxReplicasToIdentities(secret, 5, "peerID-", "privateKey-", false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(maxReplicas).To(BeEquivalentTo(3))
+		Expect(secret.StringData).To(BeEmpty())
+
+		maxReplicas, err = utils.ClampMaxReplicasToIdentities(secret, 2, "peerID-", "privateKey-", false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(maxReplicas).To(BeEquivalentTo(2))
+	})
+
+	It("generates the missing identities on demand", func() {
+		secret := newSecret(2)
+		maxReplicas, err := utils.ClampMaxReplicasToIdentities(secret, 4, "peerID-", "privateKey-", true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(maxReplicas).To(BeEquivalentTo(4))
+		Expect(secret.StringData).To(HaveKey("peerID-2"))
+		Expect(secret.StringData).To(HaveKey("privateKey-3"))
+		Expect(secret.StringData).NotTo(HaveKey("peerID-1"))
+		Expect(utils.IdentitySlots(secret, "peerID-", "privateKey-")).To(BeEquivalentTo(4))
+	})
+
+	It("rejects a negative maxReplicas", func() {
+		_, err := utils.ClampMaxReplicasToIdentities(newSecret(1), -1, "peerID-", "privateKey-", false)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Announced peer identities", func() {
+	var (
+		secret   *corev1.Secret
+		expected []peer.ID
+	)
+
+	BeforeEach(func() {
+		secret = &corev1.Secret{Data: map[string][]byte{}}
+		expected = nil
+		for i := 0; i < 3; i++ {
+			peerID, privKey, err := utils.GenerateIdentity()
+			Expect(err).NotTo(HaveOccurred())
+			secret.Data["peerID-"+strconv.Itoa(i)] = []byte(peerID.String())
+			secret.Data["privateKey-"+strconv.Itoa(i)] = []byte(privKey)
+			expected = append(expected, peerID)
+		}
+	})
+
+	It("accepts peers announcing their expected IDs", func() {
+		mismatched, err := utils.PeerIdentityMismatches(secret, "peerID-", map[int32]string{
+			0: expected[0].String(),
+			1: utils.PeerIDToCIDv1(expected[1]),
+			2: expected[2].String(),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mismatched).To(BeEmpty())
+		cond := utils.PeerIdentityMismatchCondition(mismatched)
+		Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+		Expect(cond.Reason).To(Equal(clusterv1alpha1.PeerIdentityReasonMatched))
+	})
+
+	It("flags peers announcing a stale identity", func() {
+		stale, _, err := utils.GenerateIdentity()
+		Expect(err).NotTo(HaveOccurred())
+		mismatched, err := utils.PeerIdentityMismatches(secret, "peerID-", map[int32]string{
+			0: expected[0].String(),
+			1: stale.String(),
+			2: "not-a-peer-id",
+			5: stale.String(),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mismatched).To(Equal([]int32{1, 2}))
+		cond := utils.PeerIdentityMismatchCondition(mismatched)
+		Expect(cond.Type).To(Equal(clusterv1alpha1.ConditionPeerIdentityMismatch))
+		Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+		Expect(cond.Reason).To(Equal(clusterv1alpha1.PeerIdentityReasonMismatched))
+		Expect(cond.Message).To(ContainSubstring("[1 2]"))
+	})
+})
@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+	"github.com/redhat-et/ipfs-operator/controllers/scripts"
+)
+
+// Routing types supported by go-ipfs.
+const (
+	// RoutingTypeDHT Runs the DHT as a server once the node is publicly reachable.
+	// This is the go-ipfs default.
+	RoutingTypeDHT = "dht"
+	// RoutingTypeDHTServer Always runs the DHT as a server.
+	RoutingTypeDHTServer = "dhtserver"
+	// RoutingTypeDHTClient Only queries the DHT without serving records to other peers.
+	RoutingTypeDHTClient = "dhtclient"
+)
+
+var (
+	// ipfsMemoryFloorClient Is the minimum memory a go-ipfs node needs to start.
+	ipfsMemoryFloorClient = resource.NewScaledQuantity(1, resource.Giga)
+	// ipfsMemoryFloorServer Is the minimum memory of a go-ipfs node serving the DHT,
+	// which tracks far more connections and provider records on busy networks.
+	ipfsMemoryFloorServer = resource.NewScaledQuantity(2, resource.Giga)
+)
+
+// IPFSMemoryFloor Returns the minimum viable memory for a go-ipfs node using the given routing type.
+func IPFSMemoryFloor(routingType string) resource.Quantity {
+	switch routingType {
+	case RoutingTypeDHTClient:
+		return ipfsMemoryFloorClient.DeepCopy()
+	default:
+		return ipfsMemoryFloorServer.DeepCopy()
+	}
+}
+
+// acceleratedDHTClientHeadroom Is the extra memory of a go-ipfs node running the accelerated DHT
+// client, which keeps a routing table of the whole DHT rather than of its neighbourhood.
+var acceleratedDHTClientHeadroom = resource.NewScaledQuantity(2, resource.Giga)
+
+// IPFSRoutingMemoryFloor Returns the minimum viable memory for a go-ipfs node using the given routing
+// type, including the headroom of the accelerated DHT client when it is enabled.
+func IPFSRoutingMemoryFloor(routingType string, acceleratedDHTClient bool) resource.Quantity {
+	floor := IPFSMemoryFloor(routingType)
+	if acceleratedDHTClient {
+		floor.Add(*acceleratedDHTClientHeadroom)
+	}
+	return floor
+}
+
+// EnsureIPFSMemoryFloor Raises the memory request of the given resource requirements to
+// the minimum viable memory for the routing type, and the memory limit to at least
+// the request. Returns true if the requirements were changed.
+func EnsureIPFSMemoryFloor(resources *corev1.ResourceRequirements, routingType string) bool {
+	return ensureMemoryFloor(resources, IPFSMemoryFloor(routingType))
+}
+
+// EnsureIPFSRoutingMemoryFloor Is EnsureIPFSMemoryFloor for a node which may run the accelerated DHT
+// client, whose fuller routing table gets the node OOM-killed unless its memory is raised along.
+func EnsureIPFSRoutingMemoryFloor(
+	resources *corev1.ResourceRequirements,
+	routingType string,
+	acceleratedDHTClient bool,
+) bool {
+	return ensureMemoryFloor(resources, IPFSRoutingMemoryFloor(routingType, acceleratedDHTClient))
+}
+
+// ensureMemoryFloor Raises the memory request of the given resource requirements to the given floor,
+// and the memory limit to at least the request. Returns true if the requirements were changed.
+func ensureMemoryFloor(resources *corev1.ResourceRequirements, floor resource.Quantity) bool {
+	changed := false
+	if request, ok := resources.Requests[corev1.ResourceMemory]; !ok || request.Cmp(floor) < 0 {
+		if resources.Requests == nil {
+			resources.Requests = corev1.ResourceList{}
+		}
+		resources.Requests[corev1.ResourceMemory] = floor
+		changed = true
+	}
+	request := resources.Requests[corev1.ResourceMemory]
+	if limit, ok := resources.Limits[corev1.ResourceMemory]; ok && limit.Cmp(request) < 0 {
+		resources.Limits[corev1.ResourceMemory] = request.DeepCopy()
+		changed = true
+	}
+	return changed
+}
+
+// EnvGOGC Sets the garbage collection target percentage of the Go runtime.
+const EnvGOGC = "GOGC"
+
+// gogcSizings Lists the GOGC used below each memory request, smallest first.
+// Requests at or above the largest threshold keep the Go default of 100.
+var gogcSizings = []struct {
+	below *resource.Quantity
+	gogc  int
+}{
+	{below: resource.NewScaledQuantity(1, resource.Giga), gogc: 25},
+	{below: resource.NewScaledQuantity(2, resource.Giga), gogc: 50},
+}
+
+// IPFSGOGC Returns the GOGC to run go-ipfs with for the given memory request, and
+// whether it differs from the Go default. By default the heap may double between
+// collections, which on tightly sized pods gets the container killed before the
+// collector runs, so smaller requests collect more often at the cost of CPU.
+func IPFSGOGC(memoryRequest resource.Quantity) (int, bool) {
+	for _, sizing := range gogcSizings {
+		if memoryRequest.Cmp(*sizing.below) < 0 {
+			return sizing.gogc, true
+		}
+	}
+	return 0, false
+}
+
+// IPFSGOGCEnvs Returns the environment variables lowering GOGC of the go-ipfs container
+// for the memory request of the given resource requirements, if it is memory constrained.
+// Requirements without a memory request are left to the Go default.
+func IPFSGOGCEnvs(resources corev1.ResourceRequirements) []corev1.EnvVar {
+	request, ok := resources.Requests[corev1.ResourceMemory]
+	if !ok {
+		return nil
+	}
+	gogc, ok := IPFSGOGC(request)
+	if !ok {
+		return nil
+	}
+	return []corev1.EnvVar{
+		{
+			Name:  EnvGOGC,
+			Value: strconv.Itoa(gogc),
+		},
+	}
+}
+
+const (
+	// blockstoreCacheEntriesPerGi Is the number of cache entries given to each GiB of memory.
+	blockstoreCacheEntriesPerGi = 256 << 10
+	// maxBlockstoreCacheSize Bounds the cache so it can't crowd out the rest of the heap.
+	maxBlockstoreCacheSize = 4 << 20
+)
+
+// IPFSBlockstoreCacheSize Returns the number of entries of the blockstore ARC cache for the
+// memory request of the given resource requirements. The default is sized for desktops and
+// thrashes on read-heavy gateways, so the cache grows with the memory request, never
+// dropping below the default. Requirements without a memory request keep the default.
+// Kubo v0.16 can't be given another size than its default, so a memory request calling for
+// a larger cache returns that size along with ErrUnsupportedOption.
+func IPFSBlockstoreCacheSize(resources corev1.ResourceRequirements) (int, error) {
+	request, ok := resources.Requests[corev1.ResourceMemory]
+	if !ok {
+		return scripts.DefaultBlockstoreCacheSize, nil
+	}
+	size := int(request.Value() / (1 << 30) * blockstoreCacheEntriesPerGi)
+	switch {
+	case size < scripts.DefaultBlockstoreCacheSize:
+		size = scripts.DefaultBlockstoreCacheSize
+	case size > maxBlockstoreCacheSize:
+		size = maxBlockstoreCacheSize
+	}
+	if size != scripts.DefaultBlockstoreCacheSize {
+		return size, fmt.Errorf("blockstore cache of %d entries: %w", size, scripts.ErrUnsupportedOption)
+	}
+	return size, nil
+}
+
+// ResourceMgrDisabledCondition Returns the condition warning that the libp2p resource manager of
+// the IPFS nodes is disabled, leaving them unprotected against peers exhausting their resources.
+// Kubo v0.16 leaves the resource manager off by default, so it is only reported as enabled for nodes
+// whose config enables it explicitly.
+func ResourceMgrDisabledCondition(disabled bool) metav1.Condition {
+	if !disabled {
+		return metav1.Condition{
+			Type:    clusterv1alpha1.ConditionResourceManagerDisabled,
+			Status:  metav1.ConditionFalse,
+			Reason:  clusterv1alpha1.ResourceManagerReasonEnabled,
+			Message: "the resource manager is explicitly enabled in the config of the IPFS nodes",
+		}
+	}
+	return metav1.Condition{
+		Type:   clusterv1alpha1.ConditionResourceManagerDisabled,
+		Status: metav1.ConditionTrue,
+		Reason: clusterv1alpha1.ResourceManagerReasonDebugging,
+		Message: "the resource manager is disabled for debugging, which is unsafe in production: " +
+			"any peer can exhaust the memory and file descriptors of the IPFS nodes",
+	}
+}
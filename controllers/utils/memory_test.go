@@ -0,0 +1,149 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+	"github.com/redhat-et/ipfs-operator/controllers/scripts"
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("IPFS memory floor", func() {
+	It("is higher with dht server routing than with dhtclient", func() {
+		client := utils.IPFSMemoryFloor(utils.RoutingTypeDHTClient)
+		server := utils.IPFSMemoryFloor(utils.RoutingTypeDHTServer)
+		Expect(server.Cmp(client)).To(Equal(1))
+		auto := utils.IPFSMemoryFloor(utils.RoutingTypeDHT)
+		Expect(auto.Cmp(server)).To(Equal(0))
+	})
+
+	It("raises the request computed for small repos", func() {
+		resources := utils.IPFSContainerResources(1 << 30)
+		Expect(utils.EnsureIPFSMemoryFloor(&resources, utils.RoutingTypeDHTServer)).To(BeTrue())
+		floor := utils.IPFSMemoryFloor(utils.RoutingTypeDHTServer)
+		request := resources.Requests[corev1.ResourceMemory]
+		limit := resources.Limits[corev1.ResourceMemory]
+		Expect(request.Cmp(floor)).To(Equal(0))
+		Expect(limit.Cmp(request)).To(BeNumerically(">=", 0))
+	})
+
+	It("leaves a dhtclient request above the floor alone", func() {
+		resources := utils.IPFSContainerResources(1 << 30)
+		Expect(utils.EnsureIPFSMemoryFloor(&resources, utils.RoutingTypeDHTClient)).To(BeFalse())
+	})
+
+	It("raises a limit below the floor", func() {
+		resources := corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("512M"),
+			},
+		}
+		Expect(utils.EnsureIPFSMemoryFloor(&resources, utils.RoutingTypeDHTClient)).To(BeTrue())
+		limit := resources.Limits[corev1.ResourceMemory]
+		Expect(limit.Cmp(utils.IPFSMemoryFloor(utils.RoutingTypeDHTClient))).To(Equal(0))
+	})
+})
+
+var _ = Describe("IPFS GOGC", func() {
+	withMemoryRequest := func(memory string) corev1.ResourceRequirements {
+		return corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse(memory)},
+		}
+	}
+
+	It("is lower for small memory requests", func() {
+		small := utils.IPFSGOGCEnvs(withMemoryRequest("512Mi"))
+		Expect(small).To(ConsistOf(corev1.EnvVar{Name: utils.EnvGOGC, Value: "25"}))
+		medium := utils.IPFSGOGCEnvs(withMemoryRequest("1536Mi"))
+		Expect(medium).To(ConsistOf(corev1.EnvVar{Name: utils.EnvGOGC, Value: "50"}))
+	})
+
+	It("keeps the default for large memory requests", func() {
+		Expect(utils.IPFSGOGCEnvs(withMemoryRequest("2G"))).To(BeEmpty())
+		Expect(utils.IPFSGOGCEnvs(withMemoryRequest("16Gi"))).To(BeEmpty())
+		Expect(utils.IPFSGOGCEnvs(corev1.ResourceRequirements{})).To(BeEmpty())
+	})
+})
+
+var _ = Describe("IPFS blockstore cache size", func() {
+	withMemoryRequest := func(memory string) corev1.ResourceRequirements {
+		return corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse(memory)},
+		}
+	}
+
+	It("scales with the memory request", func() {
+		small, err := utils.IPFSBlockstoreCacheSize(withMemoryRequest("2Gi"))
+		Expect(err).To(MatchError(scripts.ErrUnsupportedOption))
+		large, err := utils.IPFSBlockstoreCacheSize(withMemoryRequest("8Gi"))
+		Expect(err).To(MatchError(scripts.ErrUnsupportedOption))
+		Expect(small).To(BeNumerically(">", scripts.DefaultBlockstoreCacheSize))
+		Expect(large).To(Equal(4 * small))
+	})
+
+	It("never drops below the default", func() {
+		for _, resources := range []corev1.ResourceRequirements{withMemoryRequest("512Mi"), {}} {
+			size, err := utils.IPFSBlockstoreCacheSize(resources)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(size).To(Equal(scripts.DefaultBlockstoreCacheSize))
+		}
+	})
+
+	It("is bounded for very large memory requests", func() {
+		huge, _ := utils.IPFSBlockstoreCacheSize(withMemoryRequest("1Ti"))
+		bounded, _ := utils.IPFSBlockstoreCacheSize(withMemoryRequest("512Gi"))
+		Expect(bounded).To(Equal(huge))
+	})
+})
+
+var _ = Describe("Resource manager disabled condition", func() {
+	It("warns when the resource manager is disabled", func() {
+		cond := utils.ResourceMgrDisabledCondition(true)
+		Expect(cond.Type).To(Equal(clusterv1alpha1.ConditionResourceManagerDisabled))
+		Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+		Expect(cond.Reason).To(Equal(clusterv1alpha1.ResourceManagerReasonDebugging))
+		Expect(cond.Message).To(ContainSubstring("unsafe in production"))
+	})
+
+	It("clears the warning when it is enabled", func() {
+		cond := utils.ResourceMgrDisabledCondition(false)
+		Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+		Expect(cond.Reason).To(Equal(clusterv1alpha1.ResourceManagerReasonEnabled))
+	})
+})
+
+var _ = Describe("Accelerated DHT client memory", func() {
+	It("raises the memory floor when the accelerated client is on", func() {
+		for _, routingType := range []string{utils.RoutingTypeDHT, utils.RoutingTypeDHTClient} {
+			off := utils.IPFSRoutingMemoryFloor(routingType, false)
+			on := utils.IPFSRoutingMemoryFloor(routingType, true)
+			Expect(off).To(Equal(utils.IPFSMemoryFloor(routingType)))
+			Expect(on.Cmp(off)).To(Equal(1), routingType)
+		}
+	})
+
+	It("raises the request and limit of the resources", func() {
+		resources := corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2G")},
+			Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("3G")},
+		}
+		Expect(utils.EnsureIPFSRoutingMemoryFloor(&resources, utils.RoutingTypeDHT, false)).To(BeFalse())
+		Expect(utils.EnsureIPFSRoutingMemoryFloor(&resources, utils.RoutingTypeDHT, true)).To(BeTrue())
+		floor := utils.IPFSRoutingMemoryFloor(utils.RoutingTypeDHT, true)
+		Expect(resources.Requests.Memory().Cmp(floor)).To(Equal(0))
+		Expect(resources.Limits.Memory().Cmp(floor)).To(BeNumerically(">=", 0))
+	})
+
+	It("sizes peers for the accelerated client they run", func() {
+		peer := utils.IPFSResourcesForRole(utils.EvictionRolePeer, 1<<30, 100, true)
+		floor := utils.IPFSRoutingMemoryFloor(utils.RoutingTypeDHT, true)
+		Expect(peer.Requests.Memory().Cmp(floor)).To(BeNumerically(">=", 0))
+
+		peer = utils.IPFSResourcesForRole(utils.EvictionRolePeer, 1<<30, 100, false)
+		Expect(peer.Requests.Memory().Cmp(floor)).To(Equal(-1))
+	})
+})
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"path"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// ContainerBackupRepo Defines the name of the init container archiving the repo before a migration.
+	ContainerBackupRepo = "backup-repo"
+	// ContainerMigrateRepo Defines the name of the init container migrating the repo in place.
+	ContainerMigrateRepo = "migrate-repo"
+	// RepoBackupPath Defines where the backup volume is mounted in the backup container.
+	RepoBackupPath = "/backup"
+)
+
+// backupRepoScript Archives the repo into the backup volume, failing the pod
+// before the migration runs if the archive could not be written.
+const backupRepoScript = `set -e
+archive="` + RepoBackupPath + `/ipfs-repo-$(date +%Y%m%dT%H%M%S).tar.gz"
+echo "backing up ${IPFS_PATH} to ${archive}"
+tar -czf "${archive}" -C "${IPFS_PATH}" .
+`
+
+// RepoBackupContainer Returns an init container which archives the IPFS repo mounted from
+// dataVolume at repoPath into backupVolume.
+func RepoBackupContainer(image, dataVolume, repoPath, backupVolume string) corev1.Container {
+	return corev1.Container{
+		Name:    ContainerBackupRepo,
+		Image:   image,
+		Command: []string{"sh", "-c", backupRepoScript},
+		Env: []corev1.EnvVar{
+			{
+				Name:  "IPFS_PATH",
+				Value: repoPath,
+			},
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      dataVolume,
+				MountPath: path.Dir(repoPath),
+			},
+			{
+				Name:      backupVolume,
+				MountPath: RepoBackupPath,
+			},
+		},
+	}
+}
+
+// RepoMigrationContainer Returns an init container which migrates the IPFS repo mounted
+// from dataVolume at repoPath to the version supported by the image.
+func RepoMigrationContainer(image, dataVolume, repoPath string) corev1.Container {
+	return corev1.Container{
+		Name:    ContainerMigrateRepo,
+		Image:   image,
+		Command: []string{"ipfs", "repo", "migrate"},
+		Env: []corev1.EnvVar{
+			{
+				Name:  "IPFS_PATH",
+				Value: repoPath,
+			},
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      dataVolume,
+				MountPath: path.Dir(repoPath),
+			},
+		},
+	}
+}
+
+// MigrationInitContainers Returns the init containers which migrate the repo when a
+// migration is needed, preceded by a backup of the repo when backup is set.
+// No containers are returned when no migration is needed.
+func MigrationInitContainers(
+	image, dataVolume, repoPath, backupVolume string,
+	migrationNeeded, backup bool,
+) []corev1.Container {
+	if !migrationNeeded {
+		return nil
+	}
+	containers := make([]corev1.Container, 0, 2)
+	if backup {
+		containers = append(containers, RepoBackupContainer(image, dataVolume, repoPath, backupVolume))
+	}
+	return append(containers, RepoMigrationContainer(image, dataVolume, repoPath))
+}
@@ -0,0 +1,36 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Repo migration init containers", func() {
+	const image = "docker.io/ipfs/kubo:v0.16.0"
+
+	It("backs up the repo before migrating it", func() {
+		containers := utils.MigrationInitContainers(image, "ipfs-storage", "/data/ipfs", "ipfs-backup", true, true)
+		Expect(containers).To(HaveLen(2))
+		Expect(containers[0].Name).To(Equal(utils.ContainerBackupRepo))
+		Expect(containers[1].Name).To(Equal(utils.ContainerMigrateRepo))
+
+		backupMounts := containers[0].VolumeMounts
+		Expect(backupMounts).To(HaveLen(2))
+		Expect(backupMounts[0].Name).To(Equal("ipfs-storage"))
+		Expect(backupMounts[0].MountPath).To(Equal("/data"))
+		Expect(backupMounts[1].Name).To(Equal("ipfs-backup"))
+		Expect(backupMounts[1].MountPath).To(Equal(utils.RepoBackupPath))
+	})
+
+	It("migrates without a backup when it is disabled", func() {
+		containers := utils.MigrationInitContainers(image, "ipfs-storage", "/data/ipfs", "ipfs-backup", true, false)
+		Expect(containers).To(HaveLen(1))
+		Expect(containers[0].Name).To(Equal(utils.ContainerMigrateRepo))
+	})
+
+	It("adds nothing when no migration is needed", func() {
+		Expect(utils.MigrationInitContainers(image, "ipfs-storage", "/data/ipfs", "ipfs-backup", false, true)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"sort"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// kindPriorities Orders the kinds of the objects created or patched by the operator, so that
+// objects are only applied once what they depend on exists: RBAC before the workloads running
+// under it, configuration and Services before the workloads using them, the objects targeting
+// workloads after them, and the webhooks which may reject other objects last.
+var kindPriorities = map[string]int{
+	"Namespace":                      0,
+	"ServiceAccount":                 1,
+	"Role":                           1,
+	"ClusterRole":                    1,
+	"RoleBinding":                    2,
+	"ClusterRoleBinding":             2,
+	"ConfigMap":                      3,
+	"Secret":                         3,
+	"PersistentVolumeClaim":          3,
+	"Service":                        4,
+	"Deployment":                     5,
+	"StatefulSet":                    5,
+	"DaemonSet":                      5,
+	"Job":                            5,
+	"CronJob":                        5,
+	"PodDisruptionBudget":            6,
+	"HorizontalPodAutoscaler":        6,
+	"CustomResourceDefinition":       8,
+	"MutatingWebhookConfiguration":   9,
+	"ValidatingWebhookConfiguration": 9,
+}
+
+// unknownKindPriority Places the kinds missing from kindPriorities, such as custom resources,
+// after the built-in kinds but before CRDs and webhooks.
+const unknownKindPriority = 7
+
+// objectKind Returns the kind of the given object, looking it up in the scheme when the
+// object does not carry its type information.
+func objectKind(obj client.Object, scheme *runtime.Scheme) string {
+	kind := obj.GetObjectKind().GroupVersionKind().Kind
+	if kind == "" && scheme != nil {
+		if gvk, err := apiutil.GVKForObject(obj, scheme); err == nil {
+			kind = gvk.Kind
+		}
+	}
+	return kind
+}
+
+// kindPriority Returns the priority of the given kind.
+func kindPriority(kind string) int {
+	if priority, ok := kindPriorities[kind]; ok {
+		return priority
+	}
+	return unknownKindPriority
+}
+
+// OrderObjects Returns the given objects in the order they should be created or patched in,
+// by kind priority, then kind, namespace and name, so that the order is deterministic.
+func OrderObjects(objs []client.Object, scheme *runtime.Scheme) []client.Object {
+	kinds := make(map[client.Object]string, len(objs))
+	for _, obj := range objs {
+		kinds[obj] = objectKind(obj, scheme)
+	}
+	ordered := make([]client.Object, len(objs))
+	copy(ordered, objs)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, b := ordered[i], ordered[j]
+		if pa, pb := kindPriority(kinds[a]), kindPriority(kinds[b]); pa != pb {
+			return pa < pb
+		}
+		if kinds[a] != kinds[b] {
+			return kinds[a] < kinds[b]
+		}
+		if a.GetNamespace() != b.GetNamespace() {
+			return a.GetNamespace() < b.GetNamespace()
+		}
+		return a.GetName() < b.GetName()
+	})
+	return ordered
+}
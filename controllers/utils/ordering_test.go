@@ -0,0 +1,63 @@
+package utils_test
+
+import (
+	"math/rand"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Object ordering", func() {
+	meta := func(name string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Name: name, Namespace: "test"}
+	}
+
+	It("orders a shuffled mixed set by kind priority", func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(clusterv1alpha1.AddToScheme(scheme)).To(Succeed())
+		expected := []client.Object{
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test"}},
+			&rbacv1.Role{ObjectMeta: meta("ipfs-cluster")},
+			&corev1.ServiceAccount{ObjectMeta: meta("ipfs-cluster")},
+			&rbacv1.RoleBinding{ObjectMeta: meta("ipfs-cluster")},
+			&corev1.ConfigMap{ObjectMeta: meta("ipfs-cluster-scripts")},
+			&corev1.Secret{ObjectMeta: meta("ipfs-cluster")},
+			&corev1.Secret{ObjectMeta: meta("ipfs-cluster-ipfs")},
+			&corev1.Service{ObjectMeta: meta("ipfs-cluster")},
+			&appsv1.StatefulSet{ObjectMeta: meta("ipfs-cluster")},
+			&autoscalingv2.HorizontalPodAutoscaler{ObjectMeta: meta("ipfs-cluster")},
+			&policyv1.PodDisruptionBudget{ObjectMeta: meta("ipfs-cluster")},
+			&clusterv1alpha1.CircuitRelay{ObjectMeta: meta("relay")},
+			&admissionregistrationv1.ValidatingWebhookConfiguration{ObjectMeta: metav1.ObjectMeta{Name: "ipfs"}},
+		}
+		shuffled := make([]client.Object, len(expected))
+		copy(shuffled, expected)
+		rand.New(rand.NewSource(42)).Shuffle(len(shuffled), func(i, j int) {
+			shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+		})
+		Expect(utils.OrderObjects(shuffled, scheme)).To(Equal(expected))
+	})
+
+	It("uses the kind carried by the object without a scheme", func() {
+		webhook := &admissionregistrationv1.MutatingWebhookConfiguration{}
+		webhook.SetGroupVersionKind(admissionregistrationv1.SchemeGroupVersion.WithKind("MutatingWebhookConfiguration"))
+		secret := &corev1.Secret{}
+		secret.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Secret"))
+		ordered := utils.OrderObjects([]client.Object{webhook, secret}, nil)
+		Expect(ordered).To(Equal([]client.Object{secret, webhook}))
+	})
+})
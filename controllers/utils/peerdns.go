@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// ContainerWaitForPeers Defines the name of the init container waiting for sibling DNS records.
+	ContainerWaitForPeers = "wait-for-peers"
+	// DefaultPeerDNSTimeout Bounds how long a starting peer waits for its siblings to resolve.
+	DefaultPeerDNSTimeout = 2 * time.Minute
+)
+
+// SiblingDNSNames Returns the DNS names the headless service gives each of the replicas of the
+// given StatefulSet, e.g. `ipfs-cluster-test-0.ipfs-cluster-test.default.svc`.
+func SiblingDNSNames(stsName, serviceName, namespace string, replicas int32) []string {
+	names := make([]string, 0, replicas)
+	for i := int32(0); i < replicas; i++ {
+		names = append(names, fmt.Sprintf("%s-%d.%s.%s.svc", stsName, i, serviceName, namespace))
+	}
+	return names
+}
+
+// waitForPeersScript Waits until every given name other than the pod's own resolves, or
+// until the timeout passes. Peers which are still unscheduled when it passes are dialed
+// later by the daemon anyway, so the script never fails the pod, it only avoids dialing
+// into missing records during startup. StatefulSets with the OrderedReady policy won't
+// schedule the higher ordinals before this pod is ready, which the timeout also covers.
+const waitForPeersScript = `deadline=$(( $(date +%%s) + %d ))
+for name in %s; do
+  case "$name" in "$(hostname)".*) continue ;; esac
+  until nslookup "$name" > /dev/null 2>&1; do
+    if [ "$(date +%%s)" -ge "$deadline" ]; then
+      echo "timed out waiting for $name to resolve"
+      exit 0
+    fi
+    sleep 2
+  done
+done
+`
+
+// WaitForPeersDNSContainer Returns an init container which holds back the daemons until the
+// DNS names of the sibling peers resolve, bounded by the given timeout. A non-positive
+// timeout uses DefaultPeerDNSTimeout. The headless service should publish not-ready
+// addresses, otherwise siblings only resolve once they are ready.
+func WaitForPeersDNSContainer(image string, siblings []string, timeout time.Duration) corev1.Container {
+	if timeout <= 0 {
+		timeout = DefaultPeerDNSTimeout
+	}
+	script := fmt.Sprintf(waitForPeersScript, int(timeout.Seconds()), strings.Join(siblings, " "))
+	return corev1.Container{
+		Name:    ContainerWaitForPeers,
+		Image:   image,
+		Command: []string{"sh", "-c", script},
+	}
+}
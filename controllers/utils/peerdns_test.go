@@ -0,0 +1,38 @@
+package utils_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Waiting for sibling DNS", func() {
+	siblings := utils.SiblingDNSNames("ipfs-cluster-test", "ipfs-cluster-test", "default", 3)
+
+	It("lists the DNS name of each ordinal", func() {
+		Expect(siblings).To(Equal([]string{
+			"ipfs-cluster-test-0.ipfs-cluster-test.default.svc",
+			"ipfs-cluster-test-1.ipfs-cluster-test.default.svc",
+			"ipfs-cluster-test-2.ipfs-cluster-test.default.svc",
+		}))
+	})
+
+	It("waits for every sibling with a bounded timeout", func() {
+		container := utils.WaitForPeersDNSContainer("docker.io/ipfs/kubo:v0.16.0", siblings, 90*time.Second)
+		Expect(container.Name).To(Equal(utils.ContainerWaitForPeers))
+		script := container.Command[2]
+		for _, name := range siblings {
+			Expect(script).To(ContainSubstring(name))
+		}
+		Expect(script).To(ContainSubstring("$(date +%s) + 90"))
+		Expect(script).To(ContainSubstring("nslookup"))
+	})
+
+	It("falls back to the default timeout", func() {
+		container := utils.WaitForPeersDNSContainer("docker.io/ipfs/kubo:v0.16.0", siblings, 0)
+		Expect(container.Command[2]).To(ContainSubstring("$(date +%s) + 120"))
+	})
+})
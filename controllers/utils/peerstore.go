@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultPeerstoreTTL Is how long the address of a peer stays in the rendered peerstore once
+// the peer is gone, long enough to survive node maintenance without re-bootstrapping.
+const DefaultPeerstoreTTL = 24 * time.Hour
+
+// PeerstoreEntry Is an address of the IPFS Cluster peerstore along with when it expires.
+type PeerstoreEntry struct {
+	Addr    string    `json:"addr"`
+	Expires time.Time `json:"expires"`
+}
+
+// RefreshPeerstore Returns the given peerstore entries with the addresses of the current peers
+// refreshed to expire ttl after now, and the entries of other peers dropped once expired, so
+// that peers which are long gone age out instead of being dialed on every start. The entries
+// are sorted by address.
+func RefreshPeerstore(entries []PeerstoreEntry, current []string, now time.Time, ttl time.Duration) []PeerstoreEntry {
+	byAddr := make(map[string]time.Time, len(entries)+len(current))
+	for _, entry := range entries {
+		if entry.Expires.After(now) {
+			byAddr[entry.Addr] = entry.Expires
+		}
+	}
+	for _, addr := range current {
+		byAddr[addr] = now.Add(ttl)
+	}
+	refreshed := make([]PeerstoreEntry, 0, len(byAddr))
+	for addr, expires := range byAddr {
+		refreshed = append(refreshed, PeerstoreEntry{Addr: addr, Expires: expires})
+	}
+	sort.Slice(refreshed, func(i, j int) bool {
+		return refreshed[i].Addr < refreshed[j].Addr
+	})
+	return refreshed
+}
+
+// RenderPeerstore Returns the given entries in the format of the IPFS Cluster peerstore file,
+// one multiaddr per line.
+func RenderPeerstore(entries []PeerstoreEntry) string {
+	var b strings.Builder
+	for _, entry := range entries {
+		b.WriteString(entry.Addr)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// MarshalPeerstoreEntries Encodes the given entries so they can be stored between reconciles.
+func MarshalPeerstoreEntries(entries []PeerstoreEntry) (string, error) {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return "", fmt.Errorf("could not encode peerstore entries: %w", err)
+	}
+	return string(data), nil
+}
+
+// UnmarshalPeerstoreEntries Decodes the entries encoded by MarshalPeerstoreEntries. Empty data
+// holds no entries.
+func UnmarshalPeerstoreEntries(data string) ([]PeerstoreEntry, error) {
+	if data == "" {
+		return nil, nil
+	}
+	entries := make([]PeerstoreEntry, 0)
+	if err := json.Unmarshal([]byte(data), &entries); err != nil {
+		return nil, fmt.Errorf("could not decode peerstore entries: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,54 @@
+package utils_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Cluster peerstore", func() {
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	const (
+		peer0 = "/dns4/ipfs-cluster-test-0.ipfs-cluster-test/tcp/9096/p2p/12D3KooWzero"
+		peer1 = "/dns4/ipfs-cluster-test-1.ipfs-cluster-test/tcp/9096/p2p/12D3KooWone"
+		gone  = "/dns4/ipfs-cluster-test-5.ipfs-cluster-test/tcp/9096/p2p/12D3KooWgone"
+	)
+
+	It("sets the TTL of the current peers", func() {
+		entries := utils.RefreshPeerstore(nil, []string{peer1, peer0}, now, utils.DefaultPeerstoreTTL)
+		Expect(entries).To(Equal([]utils.PeerstoreEntry{
+			{Addr: peer0, Expires: now.Add(utils.DefaultPeerstoreTTL)},
+			{Addr: peer1, Expires: now.Add(utils.DefaultPeerstoreTTL)},
+		}))
+		Expect(utils.RenderPeerstore(entries)).To(Equal(peer0 + "\n" + peer1 + "\n"))
+	})
+
+	It("refreshes current peers and ages out gone ones", func() {
+		entries := []utils.PeerstoreEntry{
+			{Addr: peer0, Expires: now.Add(time.Minute)},
+			{Addr: peer1, Expires: now.Add(time.Hour)},
+			{Addr: gone, Expires: now.Add(-time.Minute)},
+		}
+		refreshed := utils.RefreshPeerstore(entries, []string{peer0}, now, time.Hour*2)
+		Expect(refreshed).To(Equal([]utils.PeerstoreEntry{
+			{Addr: peer0, Expires: now.Add(2 * time.Hour)},
+			{Addr: peer1, Expires: now.Add(time.Hour)},
+		}))
+	})
+
+	It("round-trips the entries", func() {
+		entries := utils.RefreshPeerstore(nil, []string{peer0}, now, time.Hour)
+		data, err := utils.MarshalPeerstoreEntries(entries)
+		Expect(err).NotTo(HaveOccurred())
+		decoded, err := utils.UnmarshalPeerstoreEntries(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decoded).To(Equal(entries))
+
+		decoded, err = utils.UnmarshalPeerstoreEntries("")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decoded).To(BeEmpty())
+	})
+})
@@ -0,0 +1,216 @@
+package utils
+
+import (
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+	ma "github.com/multiformats/go-multiaddr"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ValidateCIDs Parses each of the given strings as a CID and returns an error
+// for every entry which is malformed. No errors are returned if all entries are valid.
+func ValidateCIDs(cids []string) []error {
+	var errs []error
+	for i, c := range cids {
+		if _, err := cid.Decode(c); err != nil {
+			errs = append(errs, fmt.Errorf("entry %d (%q) is not a valid CID: %w", i, c, err))
+		}
+	}
+	return errs
+}
+
+// PinBootstrapJob Returns a Job which pins each of the given CIDs through the IPFS Cluster
+// REST API at clusterAPIAddr. The CIDs are validated before the Job is built, so that
+// malformed entries are reported up-front rather than failing at runtime.
+func PinBootstrapJob(
+	name string,
+	namespace string,
+	image string,
+	clusterAPIAddr string,
+	cids []string,
+) (*batchv1.Job, error) {
+	if errs := ValidateCIDs(cids); len(errs) > 0 {
+		return nil, fmt.Errorf("cannot pin %d malformed CIDs, first error: %w", len(errs), errs[0])
+	}
+	script := "set -e\n"
+	for _, c := range cids {
+		script += fmt.Sprintf("ipfs-cluster-ctl --host %q pin add %q\n", clusterAPIAddr, c)
+	}
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: batchv1.JobSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyOnFailure,
+					Containers: []corev1.Container{
+						{
+							Name:    "pin",
+							Image:   image,
+							Command: []string{"sh", "-c", script},
+						},
+					},
+				},
+			},
+		},
+	}
+	return job, nil
+}
+
+// pinsetFilter Reads a pinset listed by `ipfs-cluster-ctl --enc=json pin ls` from stdin and prints
+// each pin as `<cid> <replication-min> <replication-max>`, one pin per line. Whitespace is dropped
+// first, so that both the indented and the compact encoding are read, leaving the CID and the
+// replication factors of each pin in the order they are encoded in.
+const pinsetFilter = `tr -d ' \t\n' | grep -oE '"cid":\{"/":"[^"]+"\}|"replication_factor_m(in|ax)":-?[0-9]+' | ` +
+	`awk -F'"' '$2 == "cid" {cid = $6} $2 == "replication_factor_min" {min = substr($3, 2)} ` +
+	`$2 == "replication_factor_max" {print cid, min, substr($3, 2)}'`
+
+// RepinScript Returns a shell script which lists the pinset through the IPFS Cluster REST API
+// at clusterAPIAddr and re-pins every CID whose replication factor differs from the given one.
+// Pins otherwise keep the factor they were added with until they are touched. In dry-run mode
+// the affected CIDs are printed as `would repin <cid>` instead.
+func RepinScript(clusterAPIAddr string, replicationMin, replicationMax int32, dryRun bool) (string, error) {
+	if err := ValidateReplicationFactors(replicationMin, replicationMax); err != nil {
+		return "", err
+	}
+	action := fmt.Sprintf("ipfs-cluster-ctl --host %q pin add --replication-min %d --replication-max %d \"$cid\"",
+		clusterAPIAddr, replicationMin, replicationMax)
+	if dryRun {
+		action = `echo "would repin $cid"`
+	}
+	script := "set -e\n"
+	script += fmt.Sprintf("ipfs-cluster-ctl --host %q --enc=json pin ls > /tmp/pins\n", clusterAPIAddr)
+	script += fmt.Sprintf("(%s) < /tmp/pins | while read -r cid min max; do\n", pinsetFilter)
+	script += fmt.Sprintf("  if [ \"$min\" = \"%d\" ] && [ \"$max\" = \"%d\" ]; then continue; fi\n",
+		replicationMin, replicationMax)
+	script += "  " + action + "\n"
+	script += "done\n"
+	return script, nil
+}
+
+// RepinJob Returns a one-shot Job which re-pins the whole pinset at the given replication
+// factor, or only lists the affected pins when dryRun is set.
+func RepinJob(
+	name string,
+	namespace string,
+	image string,
+	clusterAPIAddr string,
+	replicationMin int32,
+	replicationMax int32,
+	dryRun bool,
+) (*batchv1.Job, error) {
+	script, err := RepinScript(clusterAPIAddr, replicationMin, replicationMax, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	var backoffLimit int32
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:    "repin",
+							Image:   image,
+							Command: []string{"sh", "-c", script},
+						},
+					},
+				},
+			},
+		},
+	}
+	return job, nil
+}
+
+// canaryScript Adds a small test object through the IPFS proxy of one peer, which pins it
+// across the cluster, then retrieves it through the IPFS API of another peer and compares
+// it with what was added. The test object is unpinned again afterwards.
+const canaryScript = `set -e
+expected="ipfs-operator canary $(date +%%s)"
+cid=$(echo "$expected" | ipfs --api %q add -q)
+echo "added $cid"
+retrieved=$(ipfs --api %q cat "$cid")
+if [ "$retrieved" != "$expected" ]; then
+  echo "retrieved content of $cid does not match"
+  exit 1
+fi
+echo "retrieved $cid"
+ipfs --api %q pin rm "$cid"
+`
+
+// CanaryScript Returns the shell script of the canary Job, adding the test object through the
+// cluster proxy at addAPIAddr and retrieving it through the IPFS API at getAPIAddr. The two must
+// belong to different peers, otherwise the object would be read from where it was added.
+func CanaryScript(addAPIAddr, getAPIAddr string) (string, error) {
+	if addAPIAddr == "" || getAPIAddr == "" {
+		return "", fmt.Errorf("canary needs both an add and a get api address")
+	}
+	addHost, err := multiaddrHost(addAPIAddr)
+	if err != nil {
+		return "", err
+	}
+	getHost, err := multiaddrHost(getAPIAddr)
+	if err != nil {
+		return "", err
+	}
+	// the proxy and the IPFS API of a peer listen on different ports of the same host
+	if addHost == getHost {
+		return "", fmt.Errorf("canary must retrieve from a different peer than it adds to")
+	}
+	return fmt.Sprintf(canaryScript, addAPIAddr, getAPIAddr, addAPIAddr), nil
+}
+
+// multiaddrHost Returns the host component of the given multiaddr, e.g. `/dns4/<name>`.
+func multiaddrHost(addr string) (string, error) {
+	parsed, err := ma.NewMultiaddr(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid api address %q: %w", addr, err)
+	}
+	host, _ := ma.SplitFirst(parsed)
+	return host.String(), nil
+}
+
+// CanaryJob Returns a Job which verifies, usually after an upgrade, that the cluster can still
+// pin and retrieve content. The Job fails if any step fails, and is not retried.
+func CanaryJob(name, namespace, image, addAPIAddr, getAPIAddr string) (*batchv1.Job, error) {
+	script, err := CanaryScript(addAPIAddr, getAPIAddr)
+	if err != nil {
+		return nil, err
+	}
+	var backoffLimit int32
+	activeDeadlineSeconds := int64(300)
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:          &backoffLimit,
+			ActiveDeadlineSeconds: &activeDeadlineSeconds,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:    "canary",
+							Image:   image,
+							Command: []string{"sh", "-c", script},
+						},
+					},
+				},
+			},
+		},
+	}
+	return job, nil
+}
@@ -0,0 +1,107 @@
+package utils_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Pin CIDs", func() {
+	const (
+		cidV0 = "QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG"
+		cidV1 = "bafybeigdyrzt5sfp7udm7hu76uh7y26nf3efuylqabf3oclgtqy55fbzdi"
+	)
+
+	It("accepts CIDv0 and CIDv1 strings", func() {
+		Expect(utils.ValidateCIDs([]string{cidV0, cidV1})).To(BeEmpty())
+	})
+
+	It("reports an error per malformed entry", func() {
+		errs := utils.ValidateCIDs([]string{cidV0, "not-a-cid", cidV1, ""})
+		Expect(errs).To(HaveLen(2))
+		Expect(errs[0].Error()).To(ContainSubstring("not-a-cid"))
+		Expect(errs[1].Error()).To(ContainSubstring("entry 3"))
+	})
+
+	It("builds a pin Job for valid CIDs", func() {
+		cids := []string{cidV0, cidV1}
+		job, err := utils.PinBootstrapJob("pin", "test", "ipfs/ipfs-cluster", "/dns4/cluster/tcp/9094", cids)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job.Spec.Template.Spec.Containers).To(HaveLen(1))
+		script := job.Spec.Template.Spec.Containers[0].Command[2]
+		Expect(script).To(ContainSubstring(cidV0))
+		Expect(script).To(ContainSubstring(cidV1))
+	})
+
+	It("refuses to build a pin Job with malformed CIDs", func() {
+		_, err := utils.PinBootstrapJob("pin", "test", "ipfs/ipfs-cluster", "/dns4/cluster/tcp/9094", []string{"bogus"})
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Repin at a new replication factor", func() {
+	const clusterAPI = "/dns4/cluster/tcp/9094"
+
+	It("re-pins every pin with a different factor", func() {
+		job, err := utils.RepinJob("repin", "test", "ipfs/ipfs-cluster", clusterAPI, 2, 3, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job.Spec.Template.Spec.RestartPolicy).To(Equal(corev1.RestartPolicyNever))
+		Expect(job.Spec.Template.Spec.Containers).To(HaveLen(1))
+		script := job.Spec.Template.Spec.Containers[0].Command[2]
+		Expect(script).To(ContainSubstring(`--enc=json pin ls`))
+		Expect(script).To(ContainSubstring(`if [ "$min" = "2" ] && [ "$max" = "3" ]; then continue; fi`))
+		Expect(script).To(ContainSubstring(`pin add --replication-min 2 --replication-max 3 "$cid"`))
+	})
+
+	It("only lists the affected pins in dry-run mode", func() {
+		script, err := utils.RepinScript(clusterAPI, -1, -1, true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(script).To(ContainSubstring(`if [ "$min" = "-1" ] && [ "$max" = "-1" ]; then continue; fi`))
+		Expect(script).To(ContainSubstring(`echo "would repin $cid"`))
+		Expect(script).NotTo(ContainSubstring("pin add"))
+	})
+
+	It("rejects invalid replication factors", func() {
+		for _, factors := range [][2]int32{{0, 3}, {2, 0}, {-2, 3}, {3, 2}, {-1, 3}} {
+			_, err := utils.RepinScript(clusterAPI, factors[0], factors[1], false)
+			Expect(err).To(HaveOccurred())
+		}
+	})
+})
+
+var _ = Describe("Canary validation", func() {
+	const (
+		addAPI = "/dns4/ipfs-cluster-test-0.ipfs-cluster-test/tcp/9095"
+		getAPI = "/dns4/ipfs-cluster-test-1.ipfs-cluster-test/tcp/5001"
+	)
+
+	It("adds, pins and retrieves the test object in order", func() {
+		job, err := utils.CanaryJob("canary", "test", "docker.io/ipfs/kubo:v0.16.0", addAPI, getAPI)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*job.Spec.BackoffLimit).To(BeZero())
+		Expect(job.Spec.Template.Spec.RestartPolicy).To(Equal(corev1.RestartPolicyNever))
+		script := job.Spec.Template.Spec.Containers[0].Command[2]
+		Expect(script).To(HavePrefix("set -e\n"))
+
+		add := strings.Index(script, `ipfs --api "`+addAPI+`" add -q`)
+		get := strings.Index(script, `ipfs --api "`+getAPI+`" cat "$cid"`)
+		unpin := strings.Index(script, `ipfs --api "`+addAPI+`" pin rm "$cid"`)
+		Expect(add).To(BeNumerically(">", 0))
+		Expect(get).To(BeNumerically(">", add))
+		Expect(unpin).To(BeNumerically(">", get))
+	})
+
+	It("targets distinct peers for add and get", func() {
+		_, err := utils.CanaryScript(addAPI, addAPI)
+		Expect(err).To(HaveOccurred())
+		_, err = utils.CanaryScript(addAPI, "")
+		Expect(err).To(HaveOccurred())
+		// the proxy and the IPFS API of the same peer
+		_, err = utils.CanaryScript(addAPI, "/dns4/ipfs-cluster-test-0.ipfs-cluster-test/tcp/5001")
+		Expect(err).To(MatchError(ContainSubstring("different peer")))
+	})
+})
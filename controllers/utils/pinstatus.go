@@ -0,0 +1,57 @@
+package utils
+
+import "time"
+
+// Pin tracker statuses reported by IPFS Cluster which never resolve on their own.
+const (
+	PinStatusError       = "error"
+	PinStatusPinError    = "pin_error"
+	PinStatusUnpinError  = "unpin_error"
+	PinStatusPinQueued   = "pin_queued"
+	PinStatusPinning     = "pinning"
+	PinStatusUnpinQueued = "unpin_queued"
+	PinStatusUnpinning   = "unpinning"
+)
+
+// PinPeerStatus Is the status of a single pin on a single peer, as reported by `/pins`.
+type PinPeerStatus struct {
+	CID       string
+	Peer      string
+	Status    string
+	Timestamp time.Time
+}
+
+// isErrorStatus Returns true for statuses which need a recover to make progress.
+func isErrorStatus(status string) bool {
+	switch status {
+	case PinStatusError, PinStatusPinError, PinStatusUnpinError:
+		return true
+	}
+	return false
+}
+
+// isInProgressStatus Returns true for statuses which are expected to resolve over time.
+func isInProgressStatus(status string) bool {
+	switch status {
+	case PinStatusPinQueued, PinStatusPinning, PinStatusUnpinQueued, PinStatusUnpinning:
+		return true
+	}
+	return false
+}
+
+// StuckPins Aggregates a sample of pin statuses into the number of stuck pins per peer.
+// Pins in an error state are always stuck, while queued or in-progress pins count once
+// their status is older than maxAge. Every peer in the sample is present in the result,
+// so that a peer which recovered reports zero stuck pins.
+func StuckPins(sample []PinPeerStatus, now time.Time, maxAge time.Duration) map[string]int {
+	stuck := make(map[string]int)
+	for _, s := range sample {
+		if _, ok := stuck[s.Peer]; !ok {
+			stuck[s.Peer] = 0
+		}
+		if isErrorStatus(s.Status) || (isInProgressStatus(s.Status) && now.Sub(s.Timestamp) > maxAge) {
+			stuck[s.Peer]++
+		}
+	}
+	return stuck
+}
@@ -0,0 +1,34 @@
+package utils_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Stuck pins", func() {
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	It("counts errored and long-queued pins per peer", func() {
+		sample := []utils.PinPeerStatus{
+			{CID: "a", Peer: "peer-0", Status: "pinned", Timestamp: now.Add(-time.Hour)},
+			{CID: "b", Peer: "peer-0", Status: utils.PinStatusPinError, Timestamp: now},
+			{CID: "c", Peer: "peer-0", Status: utils.PinStatusPinQueued, Timestamp: now.Add(-2 * time.Hour)},
+			{CID: "d", Peer: "peer-1", Status: utils.PinStatusPinning, Timestamp: now.Add(-time.Minute)},
+			{CID: "a", Peer: "peer-1", Status: utils.PinStatusUnpinError, Timestamp: now},
+			{CID: "a", Peer: "peer-2", Status: "pinned", Timestamp: now},
+		}
+		Expect(utils.StuckPins(sample, now, time.Hour)).To(Equal(map[string]int{
+			"peer-0": 2,
+			"peer-1": 1,
+			"peer-2": 0,
+		}))
+	})
+
+	It("reports nothing for an empty sample", func() {
+		Expect(utils.StuckPins(nil, now, time.Hour)).To(BeEmpty())
+	})
+})
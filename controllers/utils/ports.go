@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+
+	ma "github.com/multiformats/go-multiaddr"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// binding Describes a socket a container listens on.
+type binding struct {
+	container string
+	ip        net.IP
+	protocol  corev1.Protocol
+	port      int
+}
+
+// conflicts Returns true if both bindings cannot be held at the same time inside one
+// network namespace. A wildcard address claims the port on every address of its family,
+// and the IPv6 wildcard is dual-stack so it claims the IPv4 addresses too.
+func (b binding) conflicts(other binding) bool {
+	if b.protocol != other.protocol || b.port != other.port {
+		return false
+	}
+	isIPv6Wildcard := func(ip net.IP) bool {
+		return ip.IsUnspecified() && ip.To4() == nil
+	}
+	if isIPv6Wildcard(b.ip) || isIPv6Wildcard(other.ip) {
+		return true
+	}
+	if (b.ip.To4() == nil) != (other.ip.To4() == nil) {
+		return false
+	}
+	return b.ip.IsUnspecified() || other.ip.IsUnspecified() || b.ip.Equal(other.ip)
+}
+
+// bindingFromMultiaddr Returns the socket the given listen multiaddr binds.
+func bindingFromMultiaddr(container string, addr ma.Multiaddr) (binding, error) {
+	b := binding{container: container}
+	var err error
+	ma.ForEach(addr, func(c ma.Component) bool {
+		switch c.Protocol().Code {
+		case ma.P_IP4, ma.P_IP6:
+			b.ip = net.IP(c.RawValue())
+		case ma.P_TCP, ma.P_UDP:
+			b.protocol = corev1.ProtocolTCP
+			if c.Protocol().Code == ma.P_UDP {
+				b.protocol = corev1.ProtocolUDP
+			}
+			b.port, err = strconv.Atoi(c.Value())
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return b, fmt.Errorf("invalid port in %s: %w", addr, err)
+	}
+	if b.ip == nil || b.protocol == "" {
+		return b, fmt.Errorf("listen address %s has no IP and port", addr)
+	}
+	return b, nil
+}
+
+// ValidateListenAddrs Detects containers of a pod which would bind the same port. Since the
+// containers share a network namespace, only one of them can listen on it. Each container's
+// declared ports are treated as bound on all addresses, and listenAddrs maps container names
+// onto the listen multiaddrs rendered into their configuration, e.g. the go-ipfs API address.
+// Bindings within the same container are never reported.
+func ValidateListenAddrs(spec *corev1.PodSpec, listenAddrs map[string][]string) error {
+	bindings := make([]binding, 0)
+	for i := range spec.Containers {
+		container := &spec.Containers[i]
+		for _, p := range container.Ports {
+			protocol := p.Protocol
+			if protocol == "" {
+				protocol = corev1.ProtocolTCP
+			}
+			bindings = append(bindings, binding{
+				container: container.Name,
+				ip:        net.IPv4zero,
+				protocol:  protocol,
+				port:      int(p.ContainerPort),
+			})
+		}
+	}
+	containers := make([]string, 0, len(listenAddrs))
+	for name := range listenAddrs {
+		containers = append(containers, name)
+	}
+	sort.Strings(containers)
+	for _, name := range containers {
+		for _, s := range listenAddrs[name] {
+			addr, err := ma.NewMultiaddr(s)
+			if err != nil {
+				return fmt.Errorf("invalid listen address %q of container %q: %w", s, name, err)
+			}
+			b, err := bindingFromMultiaddr(name, addr)
+			if err != nil {
+				return fmt.Errorf("container %q: %w", name, err)
+			}
+			bindings = append(bindings, b)
+		}
+	}
+	for i := range bindings {
+		for j := i + 1; j < len(bindings); j++ {
+			a, b := bindings[i], bindings[j]
+			if a.container != b.container && a.conflicts(b) {
+				return fmt.Errorf(
+					"containers %q and %q both bind %s port %d", a.container, b.container, a.protocol, a.port,
+				)
+			}
+		}
+	}
+	return nil
+}
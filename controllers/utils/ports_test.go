@@ -0,0 +1,74 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Listen address validation", func() {
+	var spec *corev1.PodSpec
+
+	BeforeEach(func() {
+		spec = &corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name: "ipfs",
+					Ports: []corev1.ContainerPort{
+						{Name: "api", ContainerPort: 5001, Protocol: corev1.ProtocolTCP},
+						{Name: "swarm-udp", ContainerPort: 4001, Protocol: corev1.ProtocolUDP},
+					},
+				},
+				{
+					Name: "ipfs-cluster",
+					Ports: []corev1.ContainerPort{
+						{Name: "api-http", ContainerPort: 9094},
+						{Name: "proxy-http", ContainerPort: 9095},
+					},
+				},
+			},
+		}
+	})
+
+	It("accepts a clean configuration", func() {
+		Expect(utils.ValidateListenAddrs(spec, map[string][]string{
+			"ipfs":         {"/ip4/0.0.0.0/tcp/5001", "/ip4/0.0.0.0/tcp/4001", "/ip6/::/tcp/4001"},
+			"ipfs-cluster": {"/ip4/0.0.0.0/tcp/9094", "/ip4/0.0.0.0/tcp/9096"},
+		})).To(Succeed())
+	})
+
+	It("detects a conflict across two containers", func() {
+		err := utils.ValidateListenAddrs(spec, map[string][]string{
+			"ipfs-cluster": {"/ip4/0.0.0.0/tcp/5001"},
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring(`"ipfs" and "ipfs-cluster"`))
+	})
+
+	It("detects a specific address colliding with a wildcard", func() {
+		Expect(utils.ValidateListenAddrs(spec, map[string][]string{
+			"ipfs-cluster": {"/ip4/127.0.0.1/tcp/5001"},
+		})).NotTo(Succeed())
+	})
+
+	It("tells protocols and address families apart", func() {
+		Expect(utils.ValidateListenAddrs(spec, map[string][]string{
+			"ipfs":         {"/ip6/::1/tcp/9000"},
+			"ipfs-cluster": {"/ip4/0.0.0.0/tcp/4001", "/ip4/0.0.0.0/tcp/9000"},
+		})).To(Succeed())
+	})
+
+	It("treats the IPv6 wildcard as dual-stack", func() {
+		Expect(utils.ValidateListenAddrs(spec, map[string][]string{
+			"ipfs-cluster": {"/ip6/::/tcp/5001"},
+		})).NotTo(Succeed())
+	})
+
+	It("rejects listen addresses without a port", func() {
+		Expect(utils.ValidateListenAddrs(spec, map[string][]string{
+			"ipfs": {"/ip4/0.0.0.0"},
+		})).NotTo(Succeed())
+	})
+})
@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+)
+
+const (
+	// startupProbePeriodSeconds Defines how often the startupProbe is ran.
+	startupProbePeriodSeconds = 10
+	// startupBaseFailureThreshold Gives a freshly started IPFS node 5 minutes to come up.
+	startupBaseFailureThreshold int32 = 30
+	// startupMaxFailureThreshold Caps the startup budget at 4 hours, which should be
+	// enough to verify even the largest repos.
+	startupMaxFailureThreshold int32 = 1440
+	// peerReadinessPeriodSeconds Defines how often the peer readiness probe is ran.
+	peerReadinessPeriodSeconds = 10
+	// peerReadinessTimeoutSeconds Bounds how long both health checks may take together.
+	peerReadinessTimeoutSeconds = 5
+)
+
+// StartupFailureThreshold Returns the failure threshold of the IPFS startupProbe for a
+// container which has restarted the given number of times. The budget doubles after every
+// restart to accommodate a datastore verification following an unclean shutdown,
+// up to a fixed cap.
+func StartupFailureThreshold(restartCount int32) int32 {
+	threshold := startupBaseFailureThreshold
+	for i := int32(0); i < restartCount; i++ {
+		threshold *= 2
+		if threshold >= startupMaxFailureThreshold {
+			return startupMaxFailureThreshold
+		}
+	}
+	return threshold
+}
+
+// startupBytesPerPeriod Is how much of the repo each datastore backend gets through while starting
+// during one startupProbe period. Badger replays its value log, which takes time proportional to the
+// bytes written since the last compaction, whereas flatfs only walks its directory tree to find its
+// disk usage, which is much cheaper per byte.
+var startupBytesPerPeriod = map[clusterv1alpha1.DatastoreBackend]int64{
+	clusterv1alpha1.DatastoreBackendBadger: 2 << 30,
+	clusterv1alpha1.DatastoreBackendFlatfs: 20 << 30,
+}
+
+// DatastoreStartupFailureThreshold Returns the failure threshold of the IPFS startupProbe for a repo
+// of the given size on the given datastore backend, for a container which has restarted the given
+// number of times. On top of the base budget, each backend is given the time its startup takes for
+// that size; an unknown backend is treated as flatfs. Like StartupFailureThreshold, the budget
+// doubles after every restart, up to the same cap.
+func DatastoreStartupFailureThreshold(
+	backend clusterv1alpha1.DatastoreBackend,
+	repoBytes int64,
+	restartCount int32,
+) int32 {
+	perPeriod, ok := startupBytesPerPeriod[backend]
+	if !ok {
+		perPeriod = startupBytesPerPeriod[clusterv1alpha1.DatastoreBackendFlatfs]
+	}
+	threshold := int64(startupBaseFailureThreshold)
+	if repoBytes > 0 {
+		threshold += (repoBytes + perPeriod - 1) / perPeriod
+	}
+	for i := int32(0); i < restartCount && threshold < int64(startupMaxFailureThreshold); i++ {
+		threshold *= 2
+	}
+	if threshold > int64(startupMaxFailureThreshold) {
+		return startupMaxFailureThreshold
+	}
+	return int32(threshold)
+}
+
+// IPFSStartupProbe Returns a startupProbe for the IPFS container whose budget grows
+// with the given restart count.
+func IPFSStartupProbe(restartCount int32) *corev1.Probe {
+	return &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			TCPSocket: &corev1.TCPSocketAction{
+				Port: intstr.FromString("swarm"),
+			},
+		},
+		PeriodSeconds:    startupProbePeriodSeconds,
+		FailureThreshold: StartupFailureThreshold(restartCount),
+	}
+}
+
+// PeerReady Returns whether a peer may receive traffic, given the health of the IPFS API and of
+// the IPFS Cluster REST API. Either half failing leaves a peer which cannot serve every request,
+// so the peer is only ready when both are healthy.
+func PeerReady(ipfsHealthy, clusterHealthy bool) bool {
+	return ipfsHealthy && clusterHealthy
+}
+
+// PeerReadinessProbe Returns a readinessProbe checking both the IPFS API and the IPFS Cluster REST
+// API of the given instance on the given ports. Since the containers of a pod share its network
+// namespace, setting it on both containers keeps the pod unready while either of them is unhealthy.
+// A REST API requiring basic auth or served over TLS can't be queried anonymously over plain HTTP,
+// so it is only checked to accept connections.
+func PeerReadinessProbe(m *clusterv1alpha1.IpfsCluster, ipfsAPIPort, clusterAPIPort int32) *corev1.Probe {
+	ipfsCheck := fmt.Sprintf("wget -q -O /dev/null http://127.0.0.1:%d/debug/metrics/prometheus", ipfsAPIPort)
+	clusterCheck := fmt.Sprintf("wget -q -O /dev/null http://127.0.0.1:%d/id", clusterAPIPort)
+	if m.Spec.ClusterAPIBasicAuthSecretRef != nil || m.Spec.ClusterAPITLSSecretRef != nil {
+		clusterCheck = fmt.Sprintf("nc -z 127.0.0.1 %d", clusterAPIPort)
+	}
+	return &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			Exec: &corev1.ExecAction{
+				Command: []string{
+					"sh",
+					"-c",
+					ipfsCheck + " && " + clusterCheck,
+				},
+			},
+		},
+		PeriodSeconds:  peerReadinessPeriodSeconds,
+		TimeoutSeconds: peerReadinessTimeoutSeconds,
+	}
+}
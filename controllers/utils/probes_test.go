@@ -0,0 +1,105 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Startup budget", func() {
+	It("grows with the restart count", func() {
+		previous := utils.StartupFailureThreshold(0)
+		Expect(previous).To(BeNumerically(">", 0))
+		for restarts := int32(1); restarts <= 5; restarts++ {
+			current := utils.StartupFailureThreshold(restarts)
+			Expect(current).To(Equal(previous * 2))
+			previous = current
+		}
+	})
+
+	It("caps the budget", func() {
+		capped := utils.StartupFailureThreshold(6)
+		Expect(capped).To(BeNumerically("<", utils.StartupFailureThreshold(5)*2))
+		Expect(utils.StartupFailureThreshold(20)).To(Equal(capped))
+		Expect(utils.StartupFailureThreshold(1 << 30)).To(Equal(capped))
+	})
+
+	It("builds a probe using the budget", func() {
+		probe := utils.IPFSStartupProbe(2)
+		Expect(probe.FailureThreshold).To(Equal(utils.StartupFailureThreshold(2)))
+		Expect(probe.TCPSocket).NotTo(BeNil())
+		Expect(probe.TCPSocket.Port.StrVal).To(Equal("swarm"))
+	})
+})
+
+var _ = Describe("Peer readiness", func() {
+	DescribeTable("requires both containers to be healthy",
+		func(ipfsHealthy, clusterHealthy, ready bool) {
+			Expect(utils.PeerReady(ipfsHealthy, clusterHealthy)).To(Equal(ready))
+		},
+		Entry("both healthy", true, true, true),
+		Entry("cluster down", true, false, false),
+		Entry("ipfs down", false, true, false),
+		Entry("both down", false, false, false),
+	)
+
+	It("probes both APIs", func() {
+		probe := utils.PeerReadinessProbe(&clusterv1alpha1.IpfsCluster{}, 5001, 9094)
+		Expect(probe.Exec).NotTo(BeNil())
+		Expect(probe.Exec.Command).To(HaveLen(3))
+		Expect(probe.Exec.Command[2]).To(ContainSubstring("127.0.0.1:5001"))
+		Expect(probe.Exec.Command[2]).To(ContainSubstring("http://127.0.0.1:9094/id"))
+		Expect(probe.Exec.Command[2]).To(ContainSubstring(" && "))
+	})
+
+	DescribeTable("only checks that a secured cluster api accepts connections",
+		func(spec clusterv1alpha1.IpfsClusterSpec) {
+			probe := utils.PeerReadinessProbe(&clusterv1alpha1.IpfsCluster{Spec: spec}, 5001, 9094)
+			Expect(probe.Exec.Command[2]).To(ContainSubstring("127.0.0.1:5001"))
+			Expect(probe.Exec.Command[2]).To(HaveSuffix(" && nc -z 127.0.0.1 9094"))
+			Expect(probe.Exec.Command[2]).NotTo(ContainSubstring("9094/id"))
+		},
+		Entry("basic auth", clusterv1alpha1.IpfsClusterSpec{
+			ClusterAPIBasicAuthSecretRef: &corev1.SecretKeySelector{Key: "credentials"},
+		}),
+		Entry("tls", clusterv1alpha1.IpfsClusterSpec{
+			ClusterAPITLSSecretRef: &corev1.LocalObjectReference{Name: "cluster-api-tls"},
+		}),
+	)
+})
+
+var _ = Describe("Datastore startup budget", func() {
+	const repoSize = 500 << 30
+
+	It("gives badger more time than flatfs for the same repo", func() {
+		badger := utils.DatastoreStartupFailureThreshold(clusterv1alpha1.DatastoreBackendBadger, repoSize, 0)
+		flatfs := utils.DatastoreStartupFailureThreshold(clusterv1alpha1.DatastoreBackendFlatfs, repoSize, 0)
+		Expect(badger).To(BeNumerically(">", flatfs))
+		Expect(flatfs).To(BeNumerically(">", utils.StartupFailureThreshold(0)))
+	})
+
+	It("keeps the base budget for an empty repo", func() {
+		for _, backend := range []clusterv1alpha1.DatastoreBackend{
+			clusterv1alpha1.DatastoreBackendBadger,
+			clusterv1alpha1.DatastoreBackendFlatfs,
+		} {
+			Expect(utils.DatastoreStartupFailureThreshold(backend, 0, 0)).To(Equal(utils.StartupFailureThreshold(0)))
+		}
+	})
+
+	It("treats an unknown backend as flatfs", func() {
+		Expect(utils.DatastoreStartupFailureThreshold("", repoSize, 0)).
+			To(Equal(utils.DatastoreStartupFailureThreshold(clusterv1alpha1.DatastoreBackendFlatfs, repoSize, 0)))
+	})
+
+	It("grows with restarts up to the cap", func() {
+		backend := clusterv1alpha1.DatastoreBackendBadger
+		first := utils.DatastoreStartupFailureThreshold(backend, repoSize, 0)
+		Expect(utils.DatastoreStartupFailureThreshold(backend, repoSize, 1)).To(Equal(first * 2))
+		Expect(utils.DatastoreStartupFailureThreshold(backend, repoSize, 30)).To(Equal(utils.StartupFailureThreshold(30)))
+		Expect(utils.DatastoreStartupFailureThreshold(backend, 1<<50, 0)).To(Equal(utils.StartupFailureThreshold(30)))
+	})
+})
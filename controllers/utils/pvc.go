@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// volumeClaimOrdinal Returns the ordinal of the StatefulSet pod a PVC was created for,
+// given the StatefulSet's name and its volume claim templates. PVCs created by a StatefulSet
+// are named `<template>-<statefulset>-<ordinal>`.
+func volumeClaimOrdinal(pvcName, statefulSetName string, templates []string) (int32, bool) {
+	for _, template := range templates {
+		prefix := template + "-" + statefulSetName + "-"
+		if !strings.HasPrefix(pvcName, prefix) {
+			continue
+		}
+		ordinal, err := strconv.ParseInt(strings.TrimPrefix(pvcName, prefix), 10, 32)
+		if err != nil || ordinal < 0 {
+			return 0, false
+		}
+		return int32(ordinal), true
+	}
+	return 0, false
+}
+
+// volumeClaimSize Returns the provisioned capacity of the PVC, falling back to its request
+// while it is still pending.
+func volumeClaimSize(pvc *corev1.PersistentVolumeClaim) resource.Quantity {
+	if capacity, ok := pvc.Status.Capacity[corev1.ResourceStorage]; ok {
+		return capacity
+	}
+	return pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+}
+
+// OrphanedVolumeClaims Lists the PVCs of the given StatefulSet's volume claim templates which
+// belong to ordinals at or beyond the current replica count. These are left behind when
+// scaling down and keep their storage until they are deleted. Returns the orphaned PVCs
+// sorted by name along with their total size.
+func OrphanedVolumeClaims(
+	ctx context.Context,
+	c client.Reader,
+	namespace, statefulSetName string,
+	templates []string,
+	replicas int32,
+) ([]corev1.PersistentVolumeClaim, resource.Quantity, error) {
+	var reclaimable resource.Quantity
+	pvcs := &corev1.PersistentVolumeClaimList{}
+	if err := c.List(ctx, pvcs, client.InNamespace(namespace)); err != nil {
+		return nil, reclaimable, fmt.Errorf("could not list persistent volume claims: %w", err)
+	}
+	orphaned := make([]corev1.PersistentVolumeClaim, 0)
+	for i := range pvcs.Items {
+		pvc := &pvcs.Items[i]
+		ordinal, ok := volumeClaimOrdinal(pvc.Name, statefulSetName, templates)
+		if !ok || ordinal < replicas {
+			continue
+		}
+		orphaned = append(orphaned, *pvc)
+		reclaimable.Add(volumeClaimSize(pvc))
+	}
+	sort.Slice(orphaned, func(i, j int) bool {
+		return orphaned[i].Name < orphaned[j].Name
+	})
+	return orphaned, reclaimable, nil
+}
+
+// storageClassOf Returns the storage class name of a PVC, or the empty string for the default class.
+func storageClassOf(pvc *corev1.PersistentVolumeClaim) string {
+	if pvc.Spec.StorageClassName == nil {
+		return ""
+	}
+	return *pvc.Spec.StorageClassName
+}
+
+// VolumeClaimTemplateDrift Compares the volume claim templates of a live StatefulSet against the
+// desired ones, returning a description of each template whose requested size or storage class
+// differs, sorted by template name. Templates can't be changed on an existing StatefulSet, so
+// drifted templates need the StatefulSet to be recreated, or the existing PVCs to be expanded.
+// Templates present on only one side are reported as well.
+func VolumeClaimTemplateDrift(live, desired []corev1.PersistentVolumeClaim) []string {
+	liveByName := make(map[string]*corev1.PersistentVolumeClaim, len(live))
+	for i := range live {
+		liveByName[live[i].Name] = &live[i]
+	}
+	var drift []string
+	for i := range desired {
+		want := &desired[i]
+		have, ok := liveByName[want.Name]
+		if !ok {
+			drift = append(drift, fmt.Sprintf("%s: missing from the statefulset", want.Name))
+			continue
+		}
+		delete(liveByName, want.Name)
+		haveSize := have.Spec.Resources.Requests[corev1.ResourceStorage]
+		wantSize := want.Spec.Resources.Requests[corev1.ResourceStorage]
+		if haveSize.Cmp(wantSize) != 0 {
+			drift = append(drift, fmt.Sprintf("%s: size %s, desired %s", want.Name, haveSize.String(), wantSize.String()))
+		}
+		if haveClass, wantClass := storageClassOf(have), storageClassOf(want); haveClass != wantClass {
+			drift = append(drift, fmt.Sprintf("%s: storage class %q, desired %q", want.Name, haveClass, wantClass))
+		}
+	}
+	for name := range liveByName {
+		drift = append(drift, fmt.Sprintf("%s: no longer desired", name))
+	}
+	sort.Strings(drift)
+	return drift
+}
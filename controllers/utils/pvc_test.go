@@ -0,0 +1,120 @@
+package utils_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Orphaned volume claims", func() {
+	const (
+		namespace = "test"
+		stsName   = "ipfs-cluster-test"
+	)
+	templates := []string{"cluster-storage", "ipfs-storage"}
+
+	newPVC := func(name, request, capacity string) client.Object {
+		pvc := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceStorage: resource.MustParse(request),
+					},
+				},
+			},
+		}
+		if capacity != "" {
+			pvc.Status.Capacity = corev1.ResourceList{
+				corev1.ResourceStorage: resource.MustParse(capacity),
+			}
+		}
+		return pvc
+	}
+
+	It("reports PVCs beyond the current replica count", func() {
+		c := fake.NewClientBuilder().WithObjects(
+			newPVC("ipfs-storage-ipfs-cluster-test-0", "5Gi", "8Gi"),
+			newPVC("cluster-storage-ipfs-cluster-test-0", "1Gi", "1Gi"),
+			newPVC("ipfs-storage-ipfs-cluster-test-1", "5Gi", "8Gi"),
+			newPVC("cluster-storage-ipfs-cluster-test-1", "1Gi", ""),
+			newPVC("ipfs-storage-ipfs-cluster-test-2", "5Gi", ""),
+			newPVC("ipfs-storage-ipfs-cluster-other-3", "5Gi", ""),
+			newPVC("unrelated-ipfs-cluster-test-4", "5Gi", ""),
+		).Build()
+
+		orphaned, reclaimable, err := utils.OrphanedVolumeClaims(context.TODO(), c, namespace, stsName, templates, 1)
+		Expect(err).NotTo(HaveOccurred())
+		names := make([]string, 0, len(orphaned))
+		for _, pvc := range orphaned {
+			names = append(names, pvc.Name)
+		}
+		Expect(names).To(Equal([]string{
+			"cluster-storage-ipfs-cluster-test-1",
+			"ipfs-storage-ipfs-cluster-test-1",
+			"ipfs-storage-ipfs-cluster-test-2",
+		}))
+		// the provisioned capacity counts for bound claims, the request for pending ones
+		Expect(reclaimable.Cmp(resource.MustParse("14Gi"))).To(Equal(0))
+	})
+
+	It("reports nothing when all PVCs are in use", func() {
+		c := fake.NewClientBuilder().WithObjects(
+			newPVC("ipfs-storage-ipfs-cluster-test-0", "5Gi", ""),
+			newPVC("ipfs-storage-ipfs-cluster-test-1", "5Gi", ""),
+		).Build()
+
+		orphaned, reclaimable, err := utils.OrphanedVolumeClaims(context.TODO(), c, namespace, stsName, templates, 2)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(orphaned).To(BeEmpty())
+		Expect(reclaimable.IsZero()).To(BeTrue())
+	})
+})
+
+var _ = Describe("Volume claim template drift", func() {
+	desired := []corev1.PersistentVolumeClaim{
+		utils.BuildDataPVC("cluster-storage", resource.MustParse("5Gi"), ""),
+		utils.BuildDataPVC("ipfs-storage", resource.MustParse("100Gi"), "ssd"),
+	}
+
+	It("reports nothing for matching templates", func() {
+		live := []corev1.PersistentVolumeClaim{
+			utils.BuildDataPVC("cluster-storage", resource.MustParse("5120Mi"), ""),
+			utils.BuildDataPVC("ipfs-storage", resource.MustParse("100Gi"), "ssd"),
+		}
+		Expect(utils.VolumeClaimTemplateDrift(live, desired)).To(BeEmpty())
+	})
+
+	It("reports a drifted size and storage class", func() {
+		live := []corev1.PersistentVolumeClaim{
+			utils.BuildDataPVC("cluster-storage", resource.MustParse("5Gi"), ""),
+			utils.BuildDataPVC("ipfs-storage", resource.MustParse("50Gi"), "hdd"),
+		}
+		Expect(utils.VolumeClaimTemplateDrift(live, desired)).To(Equal([]string{
+			`ipfs-storage: size 50Gi, desired 100Gi`,
+			`ipfs-storage: storage class "hdd", desired "ssd"`,
+		}))
+	})
+
+	It("reports added and removed templates", func() {
+		live := []corev1.PersistentVolumeClaim{
+			utils.BuildDataPVC("ipfs-storage", resource.MustParse("100Gi"), "ssd"),
+			utils.BuildDataPVC("scratch", resource.MustParse("1Gi"), ""),
+		}
+		Expect(utils.VolumeClaimTemplateDrift(live, desired)).To(ConsistOf(
+			"cluster-storage: missing from the statefulset",
+			"scratch: no longer desired",
+		))
+	})
+})
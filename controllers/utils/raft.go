@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+)
+
+// RaftLeaders Groups the peers of a raft cluster by the leader each of them reports,
+// keyed by the leader's peer ID. Peers which report no leader are left out.
+func RaftLeaders(reported map[string]string) map[string][]string {
+	leaders := make(map[string][]string)
+	for p, leader := range reported {
+		if leader == "" {
+			continue
+		}
+		leaders[leader] = append(leaders[leader], p)
+	}
+	for leader := range leaders {
+		sort.Strings(leaders[leader])
+	}
+	return leaders
+}
+
+// RaftSplitBrainCondition Returns the condition reporting whether the peers of a raft cluster
+// of the given size follow diverging leaders, given the leader reported by each peer. Once a
+// partition elects a second leader, both sides accept pins and the pinset diverges. Fewer
+// reports than a quorum of the peers are inconclusive, and are reported as such.
+func RaftSplitBrainCondition(reported map[string]string, peers int) metav1.Condition {
+	leaders := RaftLeaders(reported)
+	reporting := 0
+	for _, followers := range leaders {
+		reporting += len(followers)
+	}
+	condition := metav1.Condition{
+		Type:    clusterv1alpha1.ConditionRaftSplitBrain,
+		Status:  metav1.ConditionFalse,
+		Reason:  clusterv1alpha1.RaftSplitBrainReasonAgreed,
+		Message: "peers agree on the raft leader",
+	}
+	switch {
+	case len(leaders) > 1:
+		ids := make([]string, 0, len(leaders))
+		for leader, followers := range leaders {
+			ids = append(ids, fmt.Sprintf("%s (%d peers)", leader, len(followers)))
+		}
+		sort.Strings(ids)
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = clusterv1alpha1.RaftSplitBrainReasonLeadersDiverged
+		condition.Message = "peers follow different raft leaders: " + strings.Join(ids, ", ")
+	case reporting < peers/2+1:
+		condition.Status = metav1.ConditionUnknown
+		condition.Reason = clusterv1alpha1.RaftSplitBrainReasonNoQuorum
+		condition.Message = fmt.Sprintf("only %d of %d peers reported a raft leader", reporting, peers)
+	}
+	return condition
+}
@@ -0,0 +1,44 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Raft split brain", func() {
+	It("reports agreement on a single leader", func() {
+		reported := map[string]string{"peer-0": "peer-0", "peer-1": "peer-0", "peer-2": "peer-0"}
+		condition := utils.RaftSplitBrainCondition(reported, 3)
+		Expect(condition.Type).To(Equal(clusterv1alpha1.ConditionRaftSplitBrain))
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(clusterv1alpha1.RaftSplitBrainReasonAgreed))
+	})
+
+	It("reports diverging leaders", func() {
+		reported := map[string]string{
+			"peer-0": "peer-0", "peer-1": "peer-0", "peer-2": "peer-0",
+			"peer-3": "peer-3", "peer-4": "peer-3",
+		}
+		condition := utils.RaftSplitBrainCondition(reported, 5)
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Reason).To(Equal(clusterv1alpha1.RaftSplitBrainReasonLeadersDiverged))
+		Expect(condition.Message).To(ContainSubstring("peer-0 (3 peers)"))
+		Expect(condition.Message).To(ContainSubstring("peer-3 (2 peers)"))
+	})
+
+	It("is inconclusive without a quorum of reports", func() {
+		reported := map[string]string{"peer-0": "peer-0", "peer-1": "", "peer-2": ""}
+		condition := utils.RaftSplitBrainCondition(reported, 3)
+		Expect(condition.Status).To(Equal(metav1.ConditionUnknown))
+		Expect(condition.Reason).To(Equal(clusterv1alpha1.RaftSplitBrainReasonNoQuorum))
+	})
+
+	It("groups the peers by leader", func() {
+		leaders := utils.RaftLeaders(map[string]string{"peer-1": "peer-0", "peer-0": "peer-0", "peer-2": ""})
+		Expect(leaders).To(Equal(map[string][]string{"peer-0": {"peer-0", "peer-1"}}))
+	})
+})
@@ -0,0 +1,85 @@
+package utils
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ConditionClusterPeerJoined Defines the pod condition used as a readiness gate,
+	// indicating that the IPFS Cluster peer has joined the cluster consensus.
+	ConditionClusterPeerJoined corev1.PodConditionType = "cluster.ipfs.io/peer-joined"
+	// ReasonClusterPeerJoined indicates that the peer is a member of the cluster.
+	ReasonClusterPeerJoined = "PeerJoined"
+	// ReasonClusterPeerNotJoined indicates that the peer has not joined the cluster yet.
+	ReasonClusterPeerNotJoined = "PeerNotJoined"
+)
+
+// ClusterPeerJoinStatus Describes a single cluster peer's view of its own membership,
+// as reported by `ipfs-cluster-ctl id`.
+type ClusterPeerJoinStatus struct {
+	// ID is the peer ID of the IPFS Cluster peer.
+	ID string `json:"id"`
+	// ClusterPeers is the list of peers this peer considers to be part of the cluster.
+	ClusterPeers []string `json:"cluster_peers"`
+	// Error is set when the peer could not determine its status.
+	Error string `json:"error,omitempty"`
+}
+
+// ClusterPeerJoined Returns whether or not the given peer has joined the cluster consensus.
+// A peer is considered to have joined once it reports no errors and lists itself
+// as one of the cluster peers.
+func ClusterPeerJoined(status ClusterPeerJoinStatus) bool {
+	if status.Error != "" || status.ID == "" {
+		return false
+	}
+	for _, p := range status.ClusterPeers {
+		if p == status.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// ClusterJoinReadinessGates Returns the readiness gates which keep a pod out of the
+// Service endpoints until its cluster peer has joined the cluster.
+func ClusterJoinReadinessGates() []corev1.PodReadinessGate {
+	return []corev1.PodReadinessGate{
+		{
+			ConditionType: ConditionClusterPeerJoined,
+		},
+	}
+}
+
+// SetClusterJoinCondition Updates the readiness gate condition on the given pod
+// from the peer's join status. Returns true if the pod's status was changed
+// and needs to be updated.
+func SetClusterJoinCondition(pod *corev1.Pod, status ClusterPeerJoinStatus) bool {
+	condition := corev1.PodCondition{
+		Type:   ConditionClusterPeerJoined,
+		Status: corev1.ConditionFalse,
+		Reason: ReasonClusterPeerNotJoined,
+	}
+	if ClusterPeerJoined(status) {
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = ReasonClusterPeerJoined
+	} else if status.Error != "" {
+		condition.Message = status.Error
+	}
+
+	for i, existing := range pod.Status.Conditions {
+		if existing.Type != ConditionClusterPeerJoined {
+			continue
+		}
+		if existing.Status == condition.Status && existing.Reason == condition.Reason &&
+			existing.Message == condition.Message {
+			return false
+		}
+		condition.LastTransitionTime = metav1.Now()
+		pod.Status.Conditions[i] = condition
+		return true
+	}
+	condition.LastTransitionTime = metav1.Now()
+	pod.Status.Conditions = append(pod.Status.Conditions, condition)
+	return true
+}
@@ -0,0 +1,69 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Cluster join readiness", func() {
+	const self = "12D3KooWSelf"
+
+	When("the peer has joined the cluster", func() {
+		status := utils.ClusterPeerJoinStatus{
+			ID:           self,
+			ClusterPeers: []string{"12D3KooWOther", self},
+		}
+
+		It("reports the peer as ready", func() {
+			Expect(utils.ClusterPeerJoined(status)).To(BeTrue())
+		})
+
+		It("sets the readiness gate condition to true", func() {
+			pod := &corev1.Pod{}
+			Expect(utils.SetClusterJoinCondition(pod, status)).To(BeTrue())
+			Expect(pod.Status.Conditions).To(HaveLen(1))
+			Expect(pod.Status.Conditions[0].Type).To(Equal(utils.ConditionClusterPeerJoined))
+			Expect(pod.Status.Conditions[0].Status).To(Equal(corev1.ConditionTrue))
+
+			// applying the same status again is a no-op
+			Expect(utils.SetClusterJoinCondition(pod, status)).To(BeFalse())
+		})
+	})
+
+	When("the peer has not joined the cluster yet", func() {
+		It("reports the peer as not ready when it is missing from the peerset", func() {
+			status := utils.ClusterPeerJoinStatus{
+				ID:           self,
+				ClusterPeers: []string{"12D3KooWOther"},
+			}
+			Expect(utils.ClusterPeerJoined(status)).To(BeFalse())
+		})
+
+		It("reports the peer as not ready when it reports an error", func() {
+			status := utils.ClusterPeerJoinStatus{
+				ID:           self,
+				ClusterPeers: []string{self},
+				Error:        "consensus not ready",
+			}
+			Expect(utils.ClusterPeerJoined(status)).To(BeFalse())
+		})
+
+		It("flips an existing condition to false", func() {
+			pod := &corev1.Pod{}
+			utils.SetClusterJoinCondition(pod, utils.ClusterPeerJoinStatus{ID: self, ClusterPeers: []string{self}})
+			Expect(utils.SetClusterJoinCondition(pod, utils.ClusterPeerJoinStatus{ID: self})).To(BeTrue())
+			Expect(pod.Status.Conditions).To(HaveLen(1))
+			Expect(pod.Status.Conditions[0].Status).To(Equal(corev1.ConditionFalse))
+			Expect(pod.Status.Conditions[0].Reason).To(Equal(utils.ReasonClusterPeerNotJoined))
+		})
+	})
+
+	It("gates readiness on the join condition", func() {
+		gates := utils.ClusterJoinReadinessGates()
+		Expect(gates).To(HaveLen(1))
+		Expect(gates[0].ConditionType).To(Equal(utils.ConditionClusterPeerJoined))
+	})
+})
@@ -0,0 +1,21 @@
+package utils
+
+// ConfigChangeAction Describes how a running IPFS node must be updated to pick up a config change.
+type ConfigChangeAction string
+
+const (
+	// ConfigChangeNone Means nothing needs to be done.
+	ConfigChangeNone ConfigChangeAction = "None"
+	// ConfigChangeRestart Means the pod must be restarted for the changes to take effect.
+	ConfigChangeRestart ConfigChangeAction = "Restart"
+)
+
+// ClassifyConfigChanges Decides whether the given set of changed config keys requires the
+// node to be restarted. Kubo v0.16 only reads its config on startup and shuts down on SIGHUP
+// like on SIGINT or SIGTERM, so no change can be applied by a reload.
+func ClassifyConfigChanges(changedKeys []string) ConfigChangeAction {
+	if len(changedKeys) == 0 {
+		return ConfigChangeNone
+	}
+	return ConfigChangeRestart
+}
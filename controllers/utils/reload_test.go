@@ -0,0 +1,27 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Config change classification", func() {
+	It("does nothing without changes", func() {
+		Expect(utils.ClassifyConfigChanges(nil)).To(Equal(utils.ConfigChangeNone))
+	})
+
+	It("restarts for gateway header changes", func() {
+		changes := []string{
+			"Gateway.HTTPHeaders.Access-Control-Allow-Origin",
+			"Gateway.RootRedirect",
+		}
+		Expect(utils.ClassifyConfigChanges(changes)).To(Equal(utils.ConfigChangeRestart))
+	})
+
+	It("restarts for any other change", func() {
+		Expect(utils.ClassifyConfigChanges([]string{"Swarm.ConnMgr.HighWater"})).
+			To(Equal(utils.ConfigChangeRestart))
+	})
+})
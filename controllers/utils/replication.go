@@ -0,0 +1,57 @@
+package utils
+
+import "fmt"
+
+// ReplicateEverywhere Is the replication factor pinning content on every peer of the cluster.
+const ReplicateEverywhere int32 = -1
+
+// ValidateReplicationFactors Ensures the given minimum and maximum replication factors are either
+// both ReplicateEverywhere, or positive with the minimum not exceeding the maximum.
+func ValidateReplicationFactors(replicationMin, replicationMax int32) error {
+	if replicationMin == 0 || replicationMax == 0 {
+		return fmt.Errorf("replication factors cannot be zero")
+	}
+	if replicationMin < ReplicateEverywhere || replicationMax < ReplicateEverywhere {
+		return fmt.Errorf("replication factors must be -1 or positive")
+	}
+	if replicationMax == ReplicateEverywhere {
+		return nil
+	}
+	if replicationMin == ReplicateEverywhere || replicationMin > replicationMax {
+		return fmt.Errorf("minimum replication factor %d exceeds the maximum %d", replicationMin, replicationMax)
+	}
+	return nil
+}
+
+// NormalizeReplicationFactors Validates the replication factors against the number of peers and
+// returns them with the maximum capped to the peer count. A minimum which the peers can't satisfy
+// is an error, since IPFS Cluster refuses to allocate such pins.
+func NormalizeReplicationFactors(replicationMin, replicationMax, peers int32) (int32, int32, error) {
+	if err := ValidateReplicationFactors(replicationMin, replicationMax); err != nil {
+		return 0, 0, err
+	}
+	if replicationMin == ReplicateEverywhere {
+		return replicationMin, replicationMax, nil
+	}
+	if replicationMin > peers {
+		return 0, 0, fmt.Errorf("minimum replication factor %d exceeds the %d peers", replicationMin, peers)
+	}
+	if replicationMax > peers {
+		replicationMax = peers
+	}
+	return replicationMin, replicationMax, nil
+}
+
+// ReplicationWarnings Returns the surprising implications of the replication factors given
+// whether the number of peers is autoscaled. Replicating everywhere makes each scale-up
+// allocate the entire pinset to the new peer, which has to fetch all of it before serving,
+// and each scale-down discard a full copy.
+func ReplicationWarnings(replicationMax int32, autoscaling bool) []string {
+	if replicationMax != ReplicateEverywhere || !autoscaling {
+		return nil
+	}
+	return []string{
+		"replication factor -1 pins everything on every peer, so each peer added by autoscaling " +
+			"fetches the entire pinset; consider a fixed replication factor instead",
+	}
+}
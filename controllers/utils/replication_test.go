@@ -0,0 +1,40 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Replication factors", func() {
+	It("warns when replicating everywhere with autoscaling", func() {
+		warnings := utils.ReplicationWarnings(utils.ReplicateEverywhere, true)
+		Expect(warnings).To(HaveLen(1))
+		Expect(warnings[0]).To(ContainSubstring("autoscaling"))
+	})
+
+	It("doesn't warn when replicating everywhere without autoscaling", func() {
+		Expect(utils.ReplicationWarnings(utils.ReplicateEverywhere, false)).To(BeEmpty())
+		Expect(utils.ReplicationWarnings(3, true)).To(BeEmpty())
+	})
+
+	It("caps the maximum to the peer count", func() {
+		replicationMin, replicationMax, err := utils.NormalizeReplicationFactors(2, 5, 3)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(replicationMin).To(BeEquivalentTo(2))
+		Expect(replicationMax).To(BeEquivalentTo(3))
+
+		replicationMin, replicationMax, err = utils.NormalizeReplicationFactors(-1, -1, 3)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(replicationMin).To(Equal(utils.ReplicateEverywhere))
+		Expect(replicationMax).To(Equal(utils.ReplicateEverywhere))
+	})
+
+	It("rejects a minimum the peers can't satisfy", func() {
+		_, _, err := utils.NormalizeReplicationFactors(4, 5, 3)
+		Expect(err).To(HaveOccurred())
+		_, _, err = utils.NormalizeReplicationFactors(3, 2, 3)
+		Expect(err).To(HaveOccurred())
+	})
+})
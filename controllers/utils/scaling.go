@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"fmt"
+
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+)
+
+// DefaultScaleUpStep Is the number of peers added per reconcile while scaling up.
+const DefaultScaleUpStep int32 = 2
+
+// NextReplicaTarget Returns the replica count to scale towards from current on the way to
+// desired. Scaling up adds at most step peers at a time, since every new peer bootstraps
+// through the existing ones and joining many at once floods them, whereas scaling down
+// happens immediately. A non-positive step uses DefaultScaleUpStep.
+func NextReplicaTarget(current, desired, step int32) int32 {
+	if step <= 0 {
+		step = DefaultScaleUpStep
+	}
+	if desired <= current || desired-current <= step {
+		return desired
+	}
+	return current + step
+}
+
+// ValidateScaleAgainstPDB Ensures that scaling to the desired number of replicas leaves more pods
+// than the given PodDisruptionBudget requires to be available, so that at least one of them can be
+// evicted. Otherwise every eviction is blocked once the StatefulSet is scaled down, which Kubernetes
+// only reports confusingly when nodes are drained. A percentage is scaled to the desired replicas,
+// rounding up as the disruption controller does. A budget setting maxUnavailable is always met.
+func ValidateScaleAgainstPDB(desired int32, pdb *policyv1.PodDisruptionBudget) error {
+	if pdb == nil || pdb.Spec.MinAvailable == nil {
+		return nil
+	}
+	minAvailable, err := intstr.GetScaledValueFromIntOrPercent(pdb.Spec.MinAvailable, int(desired), true)
+	if err != nil {
+		return fmt.Errorf("invalid minAvailable of pod disruption budget %q: %w", pdb.Name, err)
+	}
+	if int(desired) <= minAvailable {
+		return fmt.Errorf(
+			"scaling to %d replicas blocks evictions under pod disruption budget %q requiring %d available pods",
+			desired, pdb.Name, minAvailable,
+		)
+	}
+	return nil
+}
+
+// ScaleBlockedByPDBCondition Returns the condition reporting whether scaling to the desired number
+// of replicas is blocked by the given PodDisruptionBudget.
+func ScaleBlockedByPDBCondition(desired int32, pdb *policyv1.PodDisruptionBudget) metav1.Condition {
+	condition := metav1.Condition{
+		Type:    clusterv1alpha1.ConditionScaleBlockedByPDB,
+		Status:  metav1.ConditionFalse,
+		Reason:  clusterv1alpha1.ScaleBlockedByPDBReasonAllowed,
+		Message: fmt.Sprintf("scaling to %d replicas is allowed", desired),
+	}
+	if err := ValidateScaleAgainstPDB(desired, pdb); err != nil {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = clusterv1alpha1.ScaleBlockedByPDBReasonMinAvailable
+		condition.Message = err.Error()
+	}
+	return condition
+}
@@ -0,0 +1,74 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Scaling ramp-up", func() {
+	It("steps from one to ten peers over multiple reconciles", func() {
+		current := int32(1)
+		var steps []int32
+		for current != 10 {
+			current = utils.NextReplicaTarget(current, 10, 0)
+			steps = append(steps, current)
+		}
+		Expect(steps).To(Equal([]int32{3, 5, 7, 9, 10}))
+	})
+
+	It("uses the given step", func() {
+		Expect(utils.NextReplicaTarget(1, 10, 4)).To(BeEquivalentTo(5))
+	})
+
+	It("scales down immediately", func() {
+		Expect(utils.NextReplicaTarget(10, 1, 0)).To(BeEquivalentTo(1))
+		Expect(utils.NextReplicaTarget(3, 3, 0)).To(BeEquivalentTo(3))
+	})
+})
+
+var _ = Describe("Scaling against the PodDisruptionBudget", func() {
+	budget := func(minAvailable intstr.IntOrString) *policyv1.PodDisruptionBudget {
+		return &policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "ipfs-cluster-test"},
+			Spec:       policyv1.PodDisruptionBudgetSpec{MinAvailable: &minAvailable},
+		}
+	}
+
+	It("allows a safe scale-down", func() {
+		pdb := budget(intstr.FromInt(2))
+		Expect(utils.ValidateScaleAgainstPDB(3, pdb)).To(Succeed())
+		condition := utils.ScaleBlockedByPDBCondition(3, pdb)
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(clusterv1alpha1.ScaleBlockedByPDBReasonAllowed))
+	})
+
+	It("blocks a scale-down violating minAvailable", func() {
+		pdb := budget(intstr.FromInt(3))
+		Expect(utils.ValidateScaleAgainstPDB(2, pdb)).NotTo(Succeed())
+		condition := utils.ScaleBlockedByPDBCondition(2, pdb)
+		Expect(condition.Type).To(Equal(clusterv1alpha1.ConditionScaleBlockedByPDB))
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Reason).To(Equal(clusterv1alpha1.ScaleBlockedByPDBReasonMinAvailable))
+	})
+
+	It("blocks a scale-down to exactly minAvailable", func() {
+		pdb := budget(intstr.FromInt(2))
+		Expect(utils.ValidateScaleAgainstPDB(2, pdb)).NotTo(Succeed())
+		Expect(utils.ScaleBlockedByPDBCondition(2, pdb).Status).To(Equal(metav1.ConditionTrue))
+	})
+
+	It("scales percentages to the desired replicas", func() {
+		Expect(utils.ValidateScaleAgainstPDB(2, budget(intstr.FromString("50%")))).To(Succeed())
+		Expect(utils.ValidateScaleAgainstPDB(1, budget(intstr.FromString("50%")))).NotTo(Succeed())
+	})
+
+	It("allows missing budgets", func() {
+		Expect(utils.ValidateScaleAgainstPDB(1, nil)).To(Succeed())
+	})
+})
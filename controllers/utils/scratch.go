@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// VolumeExportScratch Defines the name of the gateway's export staging volume.
+	VolumeExportScratch = "export-scratch"
+	// ExportScratchPath Defines where the export staging volume is mounted.
+	ExportScratchPath = "/tmp/ipfs-export"
+	// EnvTmpDir Points go-ipfs at the directory used to stage temp files.
+	EnvTmpDir = "TMPDIR"
+)
+
+// DefaultExportScratchSize Is used when the IpfsCluster does not specify an expected export size.
+var DefaultExportScratchSize = resource.MustParse("1Gi")
+
+// ExportScratchVolume Returns an emptyDir volume limited to the given size, along
+// with the mount placing it on the export staging path.
+func ExportScratchVolume(size resource.Quantity) (corev1.Volume, corev1.VolumeMount) {
+	volume := corev1.Volume{
+		Name: VolumeExportScratch,
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{
+				SizeLimit: &size,
+			},
+		},
+	}
+	mount := corev1.VolumeMount{
+		Name:      VolumeExportScratch,
+		MountPath: ExportScratchPath,
+	}
+	return volume, mount
+}
+
+// ApplyGatewayExportScratch Mounts a sized scratch volume into the given container for
+// gateway pods, so CAR and archive exports can stage temp files on a read-only root
+// filesystem. Pods with any other role are left untouched. A nil size uses the default.
+func ApplyGatewayExportScratch(
+	tmpl *corev1.PodTemplateSpec,
+	containerName string,
+	role EvictionRole,
+	size *resource.Quantity,
+) error {
+	if role != EvictionRoleGateway {
+		return nil
+	}
+	scratchSize := DefaultExportScratchSize
+	if size != nil {
+		scratchSize = size.DeepCopy()
+	}
+	if scratchSize.Sign() <= 0 {
+		return fmt.Errorf("export scratch size must be positive, got %s", scratchSize.String())
+	}
+	for i := range tmpl.Spec.Containers {
+		container := &tmpl.Spec.Containers[i]
+		if container.Name != containerName {
+			continue
+		}
+		volume, mount := ExportScratchVolume(scratchSize)
+		tmpl.Spec.Volumes = append(tmpl.Spec.Volumes, volume)
+		container.VolumeMounts = append(container.VolumeMounts, mount)
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:  EnvTmpDir,
+			Value: ExportScratchPath,
+		})
+		return nil
+	}
+	return fmt.Errorf("container %q not found in pod template", containerName)
+}
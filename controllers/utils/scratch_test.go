@@ -0,0 +1,59 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Gateway export scratch", func() {
+	var tmpl *corev1.PodTemplateSpec
+
+	BeforeEach(func() {
+		tmpl = &corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "ipfs"}},
+			},
+		}
+	})
+
+	It("mounts a sized scratch volume for the gateway role", func() {
+		size := resource.MustParse("5Gi")
+		Expect(utils.ApplyGatewayExportScratch(tmpl, "ipfs", utils.EvictionRoleGateway, &size)).To(Succeed())
+		Expect(tmpl.Spec.Volumes).To(HaveLen(1))
+		volume := tmpl.Spec.Volumes[0]
+		Expect(volume.Name).To(Equal(utils.VolumeExportScratch))
+		Expect(volume.EmptyDir).NotTo(BeNil())
+		Expect(volume.EmptyDir.SizeLimit.Cmp(size)).To(Equal(0))
+
+		container := tmpl.Spec.Containers[0]
+		Expect(container.VolumeMounts).To(ContainElement(corev1.VolumeMount{
+			Name:      utils.VolumeExportScratch,
+			MountPath: utils.ExportScratchPath,
+		}))
+		Expect(container.Env).To(ContainElement(corev1.EnvVar{
+			Name:  utils.EnvTmpDir,
+			Value: utils.ExportScratchPath,
+		}))
+	})
+
+	It("uses the default size when none is given", func() {
+		Expect(utils.ApplyGatewayExportScratch(tmpl, "ipfs", utils.EvictionRoleGateway, nil)).To(Succeed())
+		Expect(tmpl.Spec.Volumes[0].EmptyDir.SizeLimit.Cmp(utils.DefaultExportScratchSize)).To(Equal(0))
+	})
+
+	It("leaves peer pods untouched", func() {
+		Expect(utils.ApplyGatewayExportScratch(tmpl, "ipfs", utils.EvictionRolePeer, nil)).To(Succeed())
+		Expect(tmpl.Spec.Volumes).To(BeEmpty())
+		Expect(tmpl.Spec.Containers[0].VolumeMounts).To(BeEmpty())
+	})
+
+	It("rejects a missing container or non-positive size", func() {
+		Expect(utils.ApplyGatewayExportScratch(tmpl, "missing", utils.EvictionRoleGateway, nil)).NotTo(Succeed())
+		zero := resource.MustParse("0")
+		Expect(utils.ApplyGatewayExportScratch(tmpl, "ipfs", utils.EvictionRoleGateway, &zero)).NotTo(Succeed())
+	})
+})
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SecretSubset Returns a new Secret with the given name which only contains the
+// entries of the source Secret accepted by keep.
+func SecretSubset(source *corev1.Secret, name string, keep func(key string) bool) *corev1.Secret {
+	subset := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: source.Namespace,
+		},
+		Data: make(map[string][]byte),
+	}
+	for key, value := range source.Data {
+		if keep(key) {
+			subset.Data[key] = value
+		}
+	}
+	// values which have not been persisted yet take precedence
+	for key, value := range source.StringData {
+		if keep(key) {
+			subset.Data[key] = []byte(value)
+		}
+	}
+	return subset
+}
+
+// ContainerSecretNames Returns the names of all Secrets the given container has access to,
+// either through environment variables or mounted volumes.
+func ContainerSecretNames(podSpec *corev1.PodSpec, containerName string) []string {
+	var container *corev1.Container
+	for i := range podSpec.InitContainers {
+		if podSpec.InitContainers[i].Name == containerName {
+			container = &podSpec.InitContainers[i]
+		}
+	}
+	for i := range podSpec.Containers {
+		if podSpec.Containers[i].Name == containerName {
+			container = &podSpec.Containers[i]
+		}
+	}
+	if container == nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	names := make([]string, 0)
+	add := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for _, env := range container.Env {
+		if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil {
+			add(env.ValueFrom.SecretKeyRef.Name)
+		}
+	}
+	for _, envFrom := range container.EnvFrom {
+		if envFrom.SecretRef != nil {
+			add(envFrom.SecretRef.Name)
+		}
+	}
+	for _, mount := range container.VolumeMounts {
+		for _, volume := range podSpec.Volumes {
+			if volume.Name == mount.Name && volume.Secret != nil {
+				add(volume.Secret.SecretName)
+			}
+		}
+	}
+	return names
+}
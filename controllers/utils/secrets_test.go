@@ -0,0 +1,72 @@
+package utils_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Component secrets", func() {
+	It("only copies the accepted keys", func() {
+		source := &corev1.Secret{
+			Data: map[string][]byte{
+				"CLUSTER_SECRET": []byte("secret"),
+				"peerID-0":       []byte("id"),
+			},
+			StringData: map[string]string{
+				"privateKey-0": "key",
+			},
+		}
+		source.Namespace = "test"
+		subset := utils.SecretSubset(source, "subset", func(key string) bool {
+			return strings.HasPrefix(key, "peerID-") || strings.HasPrefix(key, "privateKey-")
+		})
+		Expect(subset.Name).To(Equal("subset"))
+		Expect(subset.Namespace).To(Equal("test"))
+		Expect(subset.Data).To(HaveLen(2))
+		Expect(subset.Data).To(HaveKeyWithValue("peerID-0", []byte("id")))
+		Expect(subset.Data).To(HaveKeyWithValue("privateKey-0", []byte("key")))
+		Expect(subset.Data).NotTo(HaveKey("CLUSTER_SECRET"))
+	})
+
+	It("lists the secrets a container can read", func() {
+		podSpec := &corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name: "gateway",
+					Env: []corev1.EnvVar{{
+						Name: "SWARM_KEY",
+						ValueFrom: &corev1.EnvVarSource{
+							SecretKeyRef: &corev1.SecretKeySelector{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "ipfs"},
+								Key:                  "SWARM_KEY",
+							},
+						},
+					}},
+					VolumeMounts: []corev1.VolumeMount{{Name: "identities"}},
+				},
+				{
+					Name: "cluster",
+					EnvFrom: []corev1.EnvFromSource{{
+						SecretRef: &corev1.SecretEnvSource{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "cluster"},
+						},
+					}},
+				},
+			},
+			Volumes: []corev1.Volume{{
+				Name: "identities",
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{SecretName: "ipfs"},
+				},
+			}},
+		}
+		Expect(utils.ContainerSecretNames(podSpec, "gateway")).To(Equal([]string{"ipfs"}))
+		Expect(utils.ContainerSecretNames(podSpec, "cluster")).To(Equal([]string{"cluster"}))
+		Expect(utils.ContainerSecretNames(podSpec, "missing")).To(BeEmpty())
+	})
+})
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// LabelPeerRole Defines the label used to distinguish active peers from warm standbys.
+	LabelPeerRole = "cluster.ipfs.io/role"
+	// PeerRoleActive Marks a peer which participates in the cluster consensus.
+	PeerRoleActive = "active"
+	// PeerRoleStandby Marks a peer which runs IPFS but stays out of the cluster
+	// consensus until it gets promoted.
+	PeerRoleStandby = "standby"
+
+	// ClusterOpRun Defines the entrypoint operation which runs the IPFS Cluster daemon.
+	ClusterOpRun = "run"
+	// ClusterOpStandby Defines the entrypoint operation which keeps the IPFS Cluster
+	// container idle.
+	ClusterOpStandby = "standby"
+)
+
+// StandbyPodTemplate Returns a copy of the given pod template for a warm standby peer.
+// The IPFS container runs as usual, while the IPFS Cluster container stays idle
+// so the peer does not join the consensus.
+func StandbyPodTemplate(tmpl *corev1.PodTemplateSpec, clusterContainer string) (*corev1.PodTemplateSpec, error) {
+	standby := tmpl.DeepCopy()
+	if err := setClusterOp(standby, clusterContainer, ClusterOpStandby); err != nil {
+		return nil, err
+	}
+	if standby.Labels == nil {
+		standby.Labels = make(map[string]string, 1)
+	}
+	standby.Labels[LabelPeerRole] = PeerRoleStandby
+	return standby, nil
+}
+
+// PromoteStandbyPeer Flips the given standby pod template into an active peer,
+// starting the IPFS Cluster daemon so it joins the peer set.
+func PromoteStandbyPeer(tmpl *corev1.PodTemplateSpec, clusterContainer string) error {
+	if tmpl.Labels[LabelPeerRole] != PeerRoleStandby {
+		return fmt.Errorf("cannot promote a peer which is not on standby")
+	}
+	if err := setClusterOp(tmpl, clusterContainer, ClusterOpRun); err != nil {
+		return err
+	}
+	tmpl.Labels[LabelPeerRole] = PeerRoleActive
+	return nil
+}
+
+// setClusterOp Sets the entrypoint operation of the IPFS Cluster container.
+func setClusterOp(tmpl *corev1.PodTemplateSpec, clusterContainer, op string) error {
+	for i := range tmpl.Spec.Containers {
+		if tmpl.Spec.Containers[i].Name == clusterContainer {
+			tmpl.Spec.Containers[i].Args = []string{op}
+			return nil
+		}
+	}
+	return fmt.Errorf("could not find container %q in pod template", clusterContainer)
+}
@@ -0,0 +1,55 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Standby peers", func() {
+	const (
+		ipfsContainer    = "ipfs"
+		clusterContainer = "ipfs-cluster"
+	)
+	var active *corev1.PodTemplateSpec
+
+	BeforeEach(func() {
+		active = &corev1.PodTemplateSpec{}
+		active.Labels = map[string]string{"app.kubernetes.io/name": "ipfs-cluster-test"}
+		active.Spec.Containers = []corev1.Container{
+			{Name: ipfsContainer},
+			{Name: clusterContainer, Args: []string{utils.ClusterOpRun}},
+		}
+	})
+
+	It("keeps the cluster daemon idle on standby", func() {
+		standby, err := utils.StandbyPodTemplate(active, clusterContainer)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(standby.Labels).To(HaveKeyWithValue(utils.LabelPeerRole, utils.PeerRoleStandby))
+		Expect(standby.Labels).To(HaveKeyWithValue("app.kubernetes.io/name", "ipfs-cluster-test"))
+		Expect(standby.Spec.Containers[0].Args).To(BeEmpty())
+		Expect(standby.Spec.Containers[1].Args).To(Equal([]string{utils.ClusterOpStandby}))
+
+		// the original template is left untouched
+		Expect(active.Spec.Containers[1].Args).To(Equal([]string{utils.ClusterOpRun}))
+		Expect(active.Labels).NotTo(HaveKey(utils.LabelPeerRole))
+	})
+
+	It("errors when the cluster container is missing", func() {
+		_, err := utils.StandbyPodTemplate(active, "missing")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("promotes a standby peer into the active peer set", func() {
+		standby, err := utils.StandbyPodTemplate(active, clusterContainer)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(utils.PromoteStandbyPeer(standby, clusterContainer)).To(Succeed())
+		Expect(standby.Labels).To(HaveKeyWithValue(utils.LabelPeerRole, utils.PeerRoleActive))
+		Expect(standby.Spec.Containers[1].Args).To(Equal([]string{utils.ClusterOpRun}))
+
+		// an active peer cannot be promoted again
+		Expect(utils.PromoteStandbyPeer(standby, clusterContainer)).NotTo(Succeed())
+	})
+})
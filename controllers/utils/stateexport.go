@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+// StateExportVersionSuffix Is appended to the path of a state export to name the file
+// recording its format version.
+const StateExportVersionSuffix = ".format-version"
+
+// stateExportFormats Maps the first IPFS Cluster release writing each state export format
+// onto that format, newest first. IPFS Cluster v1.0.0 changed the serialization of pins.
+var stateExportFormats = []struct {
+	since  *version.Version
+	format int
+}{
+	{since: version.MustParseSemantic("v1.0.0"), format: 2},
+	{since: version.MustParseSemantic("v0.0.0"), format: 1},
+}
+
+// StateExportFormatVersion Returns the format of the state exported by the given IPFS Cluster image.
+func StateExportFormatVersion(clusterImage string) (int, error) {
+	v, err := ImageVersion(clusterImage)
+	if err != nil {
+		return 0, err
+	}
+	for _, f := range stateExportFormats {
+		if v.AtLeast(f.since) {
+			return f.format, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown state export format for image %q", clusterImage)
+}
+
+// StateExportScript Returns a shell script exporting the cluster state to the given path on
+// a peer of the given image, recording the export format version next to it so a restore
+// can be checked with ValidateStateRestore.
+func StateExportScript(clusterImage, path string) (string, error) {
+	format, err := StateExportFormatVersion(clusterImage)
+	if err != nil {
+		return "", err
+	}
+	script := "set -e\n"
+	script += fmt.Sprintf("ipfs-cluster-service state export -f %q\n", path)
+	script += fmt.Sprintf("echo %d > %q\n", format, path+StateExportVersionSuffix)
+	return script, nil
+}
+
+// ParseStateExportFormatVersion Parses the contents of the file recording the format version of
+// a state export.
+func ParseStateExportFormatVersion(contents string) (int, error) {
+	format, err := strconv.Atoi(strings.TrimRight(contents, "\r\n"))
+	if err != nil || format <= 0 {
+		return 0, fmt.Errorf("invalid state export format version %q", contents)
+	}
+	return format, nil
+}
+
+// ValidateStateRestore Ensures that peers of the given IPFS Cluster image can import a state
+// export of the given format version. Imports of another format fail on the peers part way,
+// so the mismatch is reported before a restore is started.
+func ValidateStateRestore(exportFormat int, clusterImage string) error {
+	format, err := StateExportFormatVersion(clusterImage)
+	if err != nil {
+		return err
+	}
+	if exportFormat != format {
+		return fmt.Errorf("image %q reads state export format %d, but the backup has format %d",
+			clusterImage, format, exportFormat)
+	}
+	return nil
+}
+
+// CountExportedPins Returns the number of pins in a state export, which holds one JSON-encoded
+// pin per line, e.g. the output of `ipfs-cluster-service state export` or the pinset streamed by
+// the REST API of a restore target.
+func CountExportedPins(export string) int {
+	pins := 0
+	for _, line := range strings.Split(export, "\n") {
+		if strings.TrimSpace(line) != "" {
+			pins++
+		}
+	}
+	return pins
+}
+
+// ValidateRestoreTarget Ensures the datastore of the given peer is freshly initialized, given
+// the number of pins its own state export holds. Importing a state into a peer which already
+// holds a pinset makes both diverge, so such peers must be cleaned up before a restore.
+func ValidateRestoreTarget(peer string, pins int) error {
+	if pins > 0 {
+		return fmt.Errorf("restore target %s already holds %d pins, its state must be cleaned up first", peer, pins)
+	}
+	return nil
+}
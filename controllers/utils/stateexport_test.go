@@ -0,0 +1,66 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("State export format version", func() {
+	const (
+		oldImage = "docker.io/ipfs/ipfs-cluster:v0.14.5"
+		newImage = "docker.io/ipfs/ipfs-cluster:1.0.4"
+	)
+
+	It("records the format version next to the export", func() {
+		script, err := utils.StateExportScript(newImage, "/backup/state.json")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(script).To(ContainSubstring(`state export -f "/backup/state.json"`))
+		Expect(script).To(ContainSubstring(`echo 2 > "/backup/state.json` + utils.StateExportVersionSuffix + `"`))
+	})
+
+	It("parses the recorded format version", func() {
+		format, err := utils.ParseStateExportFormatVersion("2\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(format).To(Equal(2))
+		_, err = utils.ParseStateExportFormatVersion("two")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("allows restoring a compatible format", func() {
+		format, err := utils.StateExportFormatVersion("docker.io/ipfs/ipfs-cluster:1.0.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(utils.ValidateStateRestore(format, newImage)).To(Succeed())
+	})
+
+	It("rejects restoring an incompatible format", func() {
+		format, err := utils.StateExportFormatVersion(oldImage)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(format).To(Equal(1))
+		Expect(utils.ValidateStateRestore(format, newImage)).NotTo(Succeed())
+		Expect(utils.ValidateStateRestore(2, oldImage)).NotTo(Succeed())
+	})
+
+	It("requires a versioned image", func() {
+		_, err := utils.StateExportScript("docker.io/ipfs/ipfs-cluster:latest", "/backup/state.json")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("State restore target", func() {
+	It("allows restoring into an empty datastore", func() {
+		pins := utils.CountExportedPins("\n")
+		Expect(pins).To(BeZero())
+		Expect(utils.ValidateRestoreTarget("ipfs-cluster-test-1", pins)).To(Succeed())
+	})
+
+	It("blocks restoring into a datastore holding pins", func() {
+		export := `{"cid":{"/":"bafyone"},"type":2}
+{"cid":{"/":"bafytwo"},"type":2}
+`
+		pins := utils.CountExportedPins(export)
+		Expect(pins).To(Equal(2))
+		Expect(utils.ValidateRestoreTarget("ipfs-cluster-test-1", pins)).NotTo(Succeed())
+	})
+})
@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+)
+
+// ValidateStorageUpdate Ensures that an update of an IpfsCluster doesn't shrink its storage. The
+// storage is backed by PVCs, which Kubernetes cannot shrink, so a decrease would never take effect.
+func ValidateStorageUpdate(oldCluster, newCluster *clusterv1alpha1.IpfsCluster) error {
+	sizes := []struct {
+		field    string
+		old, new resource.Quantity
+	}{
+		{field: "spec.ipfsStorage", old: oldCluster.Spec.IpfsStorage, new: newCluster.Spec.IpfsStorage},
+		{field: "spec.clusterStorage", old: oldCluster.Spec.ClusterStorage, new: newCluster.Spec.ClusterStorage},
+	}
+	for _, size := range sizes {
+		if size.new.Cmp(size.old) < 0 {
+			return fmt.Errorf("%s cannot shrink from %s to %s, since persistent volume claims cannot be shrunk",
+				size.field, size.old.String(), size.new.String())
+		}
+	}
+	return nil
+}
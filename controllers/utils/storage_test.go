@@ -0,0 +1,37 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Storage update", func() {
+	newCluster := func(ipfsStorage string) *clusterv1alpha1.IpfsCluster {
+		m := &clusterv1alpha1.IpfsCluster{}
+		m.Spec.IpfsStorage = resource.MustParse(ipfsStorage)
+		m.Spec.ClusterStorage = resource.MustParse("5Gi")
+		return m
+	}
+
+	It("allows growing the storage", func() {
+		Expect(utils.ValidateStorageUpdate(newCluster("100Gi"), newCluster("200Gi"))).To(Succeed())
+	})
+
+	It("allows leaving the storage unchanged", func() {
+		Expect(utils.ValidateStorageUpdate(newCluster("100Gi"), newCluster("100Gi"))).To(Succeed())
+	})
+
+	It("rejects shrinking the storage", func() {
+		err := utils.ValidateStorageUpdate(newCluster("100Gi"), newCluster("50Gi"))
+		Expect(err).To(MatchError(ContainSubstring("spec.ipfsStorage cannot shrink")))
+
+		shrunk := newCluster("100Gi")
+		shrunk.Spec.ClusterStorage = resource.MustParse("1Gi")
+		err = utils.ValidateStorageUpdate(newCluster("100Gi"), shrunk)
+		Expect(err).To(MatchError(ContainSubstring("spec.clusterStorage")))
+	})
+})
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+)
+
+// tierAppliesToRole Returns true if the tier has no role restriction or lists the given role.
+func tierAppliesToRole(tier *clusterv1alpha1.StorageTier, role EvictionRole) bool {
+	if len(tier.Roles) == 0 {
+		return true
+	}
+	for _, r := range tier.Roles {
+		if r == string(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// SelectStorageClass Returns the StorageClass for a volume of the given size used by a pod
+// with the given role. The tier with the largest minimum size not exceeding the volume size
+// wins, so small repos land on cheap storage while large ones move to faster classes.
+// The default class is returned when no tier matches, an empty result leaving the choice
+// to the cluster's default StorageClass.
+func SelectStorageClass(settings clusterv1alpha1.StorageSettings, size resource.Quantity, role EvictionRole) string {
+	var selected *clusterv1alpha1.StorageTier
+	for i := range settings.Tiers {
+		tier := &settings.Tiers[i]
+		if !tierAppliesToRole(tier, role) || tier.MinSize.Cmp(size) > 0 {
+			continue
+		}
+		if selected == nil {
+			selected = tier
+			continue
+		}
+		// a tier dedicated to the role wins over a shared tier of the same size
+		cmp := tier.MinSize.Cmp(selected.MinSize)
+		if cmp > 0 || (cmp == 0 && len(tier.Roles) > 0 && len(selected.Roles) == 0) {
+			selected = tier
+		}
+	}
+	if selected == nil {
+		return settings.DefaultStorageClassName
+	}
+	return selected.StorageClassName
+}
+
+// BuildDataPVC Returns a ReadWriteOnce PersistentVolumeClaim template requesting the given
+// size from the given StorageClass. An empty storageClassName uses the cluster's default StorageClass.
+func BuildDataPVC(name string, size resource.Quantity, storageClassName string) corev1.PersistentVolumeClaim {
+	pvc := corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{
+				corev1.ReadWriteOnce,
+			},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: size,
+				},
+			},
+		},
+	}
+	if storageClassName != "" {
+		pvc.Spec.StorageClassName = &storageClassName
+	}
+	return pvc
+}
@@ -0,0 +1,55 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Storage tiers", func() {
+	settings := clusterv1alpha1.StorageSettings{
+		DefaultStorageClassName: "standard",
+		Tiers: []clusterv1alpha1.StorageTier{
+			{StorageClassName: "nvme", MinSize: resource.MustParse("1Ti")},
+			{StorageClassName: "hdd", MinSize: resource.MustParse("10Gi")},
+			{StorageClassName: "ssd", MinSize: resource.MustParse("100Gi")},
+			{StorageClassName: "gateway-ssd", MinSize: resource.MustParse("10Gi"), Roles: []string{"gateway"}},
+		},
+	}
+
+	DescribeTable("selects the tier by size",
+		func(size, expected string) {
+			Expect(utils.SelectStorageClass(settings, resource.MustParse(size), utils.EvictionRolePeer)).To(Equal(expected))
+		},
+		Entry("below every tier", "5Gi", "standard"),
+		Entry("at a threshold", "10Gi", "hdd"),
+		Entry("between thresholds", "500Gi", "ssd"),
+		Entry("beyond the largest threshold", "4Ti", "nvme"),
+	)
+
+	It("honors the role restriction of a tier", func() {
+		size := resource.MustParse("50Gi")
+		Expect(utils.SelectStorageClass(settings, size, utils.EvictionRoleGateway)).To(Equal("gateway-ssd"))
+		Expect(utils.SelectStorageClass(settings, size, utils.EvictionRolePeer)).To(Equal("hdd"))
+	})
+
+	It("falls back to the cluster default without tiers", func() {
+		noTiers := clusterv1alpha1.StorageSettings{}
+		Expect(utils.SelectStorageClass(noTiers, resource.MustParse("1Ti"), utils.EvictionRolePeer)).To(BeEmpty())
+	})
+
+	It("builds a PVC for the selected class", func() {
+		size := resource.MustParse("500Gi")
+		pvc := utils.BuildDataPVC("ipfs-storage", size, utils.SelectStorageClass(settings, size, utils.EvictionRolePeer))
+		Expect(pvc.Spec.StorageClassName).NotTo(BeNil())
+		Expect(*pvc.Spec.StorageClassName).To(Equal("ssd"))
+		Expect(pvc.Spec.Resources.Requests[corev1.ResourceStorage]).To(Equal(size))
+
+		pvc = utils.BuildDataPVC("ipfs-storage", size, "")
+		Expect(pvc.Spec.StorageClassName).To(BeNil())
+	})
+})
@@ -0,0 +1,163 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+	"github.com/redhat-et/ipfs-operator/controllers/scripts"
+)
+
+// ContainerValidateSwarmKey Defines the name of the init container validating the swarm key.
+const ContainerValidateSwarmKey = "validate-swarm-key"
+
+const (
+	// swarmKeyHeader Is the first line of a private swarm key, as expected by Kubo.
+	swarmKeyHeader = "/key/swarm/psk/1.0.0/"
+	// legacySwarmKeyHeader Was written by earlier releases of the operator, without the trailing slash.
+	legacySwarmKeyHeader = "/key/swarm/psk/1.0.0"
+)
+
+// NormalizeSwarmKey Rewrites the legacy header of a swarm key into the one Kubo expects, keeping
+// the key material. Returns whether the key was rewritten, so that it is only stored once.
+func NormalizeSwarmKey(swarmKey string) (string, bool) {
+	lines := strings.SplitN(swarmKey, "\n", 2)
+	if len(lines) != 2 || strings.TrimSuffix(lines[0], "\r") != legacySwarmKeyHeader {
+		return swarmKey, false
+	}
+	return swarmKeyHeader + "\n" + lines[1], true
+}
+
+// SwarmKeyValidationContainer Returns an init container which validates the private swarm key
+// stored under the given Secret key before the IPFS daemon starts, so that a malformed key
+// fails fast with a clear message instead of crash-looping the daemon.
+func SwarmKeyValidationContainer(image, secretName, secretKey string) corev1.Container {
+	return corev1.Container{
+		Name:  ContainerValidateSwarmKey,
+		Image: image,
+		Command: []string{
+			"sh",
+			"-c",
+			scripts.ValidateSwarmKey,
+		},
+		Env: []corev1.EnvVar{
+			{
+				Name: "IPFS_SWARM_KEY",
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{
+							Name: secretName,
+						},
+						Key: secretKey,
+					},
+				},
+			},
+		},
+	}
+}
+
+// SwarmKeyRotationPhase Describes how far the rotation of the private swarm key has progressed.
+// Peers using different swarm keys cannot connect to each other, so the new key is first mounted
+// into every peer and only then do all peers restart together to switch over.
+type SwarmKeyRotationPhase string
+
+const (
+	// SwarmKeyRotationStaged Means the new key is stored, but no peer has it mounted yet.
+	SwarmKeyRotationStaged SwarmKeyRotationPhase = "Staged"
+	// SwarmKeyRotationMounting Means only some of the peers have the new key mounted.
+	SwarmKeyRotationMounting SwarmKeyRotationPhase = "Mounting"
+	// SwarmKeyRotationReadyToRestart Means every peer has the new key mounted, so that all of
+	// them can be restarted together.
+	SwarmKeyRotationReadyToRestart SwarmKeyRotationPhase = "ReadyToRestart"
+	// SwarmKeyRotationPartial Means only some of the peers run with the new key, so the swarm
+	// is partitioned between the peers on the old key and those on the new one.
+	SwarmKeyRotationPartial SwarmKeyRotationPhase = "PartialRollout"
+	// SwarmKeyRotationComplete Means every peer runs with the new key.
+	SwarmKeyRotationComplete SwarmKeyRotationPhase = "Complete"
+)
+
+// SwarmKeyPeerState Is the swarm key of a single peer, as the fingerprints of the key mounted into
+// its pod and of the key its IPFS daemon was started with.
+type SwarmKeyPeerState struct {
+	Pod     string
+	Mounted string
+	Active  string
+}
+
+// SwarmKeyFingerprint Returns a fingerprint of the given swarm key, which identifies the key in
+// annotations and status without revealing it.
+func SwarmKeyFingerprint(swarmKey string) string {
+	sum := sha256.Sum256([]byte(swarmKey))
+	return hex.EncodeToString(sum[:8])
+}
+
+// StageSwarmKey Stores the given new swarm key in the Secret under the given key, after validating
+// it, so that it gets mounted into the peers ahead of the coordinated restart.
+func StageSwarmKey(secret *corev1.Secret, secretKey, swarmKey string) error {
+	if _, err := ParseSwarmKey(swarmKey); err != nil {
+		return fmt.Errorf("invalid swarm key: %w", err)
+	}
+	if secret.StringData == nil {
+		secret.StringData = make(map[string]string, 1)
+	}
+	secret.StringData[secretKey] = swarmKey
+	return nil
+}
+
+// SwarmKeyRotationStatus Returns the phase of the rotation to the swarm key with the given
+// fingerprint, along with the sorted pods holding the rotation back: those without the new key
+// mounted while it is being mounted, and those still running with the old key once any peer
+// runs with the new one.
+func SwarmKeyRotationStatus(fingerprint string, peers []SwarmKeyPeerState) (SwarmKeyRotationPhase, []string) {
+	var unmounted, inactive []string
+	for _, p := range peers {
+		if p.Mounted != fingerprint {
+			unmounted = append(unmounted, p.Pod)
+		}
+		if p.Active != fingerprint {
+			inactive = append(inactive, p.Pod)
+		}
+	}
+	sort.Strings(unmounted)
+	sort.Strings(inactive)
+	switch {
+	case len(inactive) == 0:
+		return SwarmKeyRotationComplete, nil
+	case len(inactive) < len(peers):
+		return SwarmKeyRotationPartial, inactive
+	case len(unmounted) == 0:
+		return SwarmKeyRotationReadyToRestart, nil
+	case len(unmounted) < len(peers):
+		return SwarmKeyRotationMounting, unmounted
+	default:
+		return SwarmKeyRotationStaged, unmounted
+	}
+}
+
+// SwarmKeyRotationCondition Returns the condition reporting the given phase of a swarm key rotation
+// and the pods holding it back. Only a complete rotation is reported as rotated.
+func SwarmKeyRotationCondition(phase SwarmKeyRotationPhase, pending []string) metav1.Condition {
+	condition := metav1.Condition{
+		Type:    clusterv1alpha1.ConditionSwarmKeyRotated,
+		Status:  metav1.ConditionFalse,
+		Reason:  string(phase),
+		Message: fmt.Sprintf("swarm key rotation is %s", phase),
+	}
+	switch phase {
+	case SwarmKeyRotationComplete:
+		condition.Status = metav1.ConditionTrue
+		condition.Message = "every peer runs with the current swarm key"
+	case SwarmKeyRotationPartial:
+		condition.Message = "the swarm is partitioned, peers still running with the old swarm key: " +
+			strings.Join(pending, ", ")
+	case SwarmKeyRotationStaged, SwarmKeyRotationMounting:
+		condition.Message = "waiting for the new swarm key to be mounted into: " + strings.Join(pending, ", ")
+	}
+	return condition
+}
@@ -0,0 +1,145 @@
+package utils_test
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+	"github.com/redhat-et/ipfs-operator/controllers/scripts"
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Swarm key validation", func() {
+	var goodKey string
+	badKeys := []string{
+		"",
+		"/key/swarm/psk/1.0.0\n/base16/\n" + "00112233445566778899aabbccddeeff00112233445566778899aabbccddeeff",
+		"/key/swarm/psk/1.0.0/\n/base32/\n" + "00112233445566778899aabbccddeeff00112233445566778899aabbccddeeff",
+		"/key/swarm/psk/1.0.0/\n/base16/\n" + "not-hex",
+	}
+
+	BeforeEach(func() {
+		var err error
+		goodKey, err = utils.NewSwarmKey()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("parses generated swarm keys", func() {
+		psk, err := utils.ParseSwarmKey(goodKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(psk).To(HaveLen(32))
+	})
+
+	It("rejects malformed swarm keys", func() {
+		for _, key := range badKeys {
+			_, err := utils.ParseSwarmKey(key)
+			Expect(err).To(HaveOccurred(), key)
+		}
+	})
+
+	It("runs the validation script against the mounted key", func() {
+		container := utils.SwarmKeyValidationContainer("ipfs/kubo", "my-secret", "SWARM_KEY")
+		Expect(container.Command).To(Equal([]string{"sh", "-c", scripts.ValidateSwarmKey}))
+		Expect(container.Env).To(HaveLen(1))
+		Expect(container.Env[0].Name).To(Equal("IPFS_SWARM_KEY"))
+		Expect(container.Env[0].ValueFrom.SecretKeyRef.Name).To(Equal("my-secret"))
+		Expect(container.Env[0].ValueFrom.SecretKeyRef.Key).To(Equal("SWARM_KEY"))
+	})
+
+	It("agrees with the parser", func() {
+		run := func(key string) error {
+			cmd := exec.Command("sh", "-c", scripts.ValidateSwarmKey)
+			cmd.Env = append(os.Environ(), "IPFS_SWARM_KEY="+key)
+			return cmd.Run()
+		}
+		Expect(run(goodKey)).To(Succeed())
+		for _, key := range badKeys {
+			Expect(run(key)).NotTo(Succeed(), key)
+		}
+	})
+
+	It("rewrites the legacy header once", func() {
+		legacy := badKeys[1]
+		normalized, rewritten := utils.NormalizeSwarmKey(legacy)
+		Expect(rewritten).To(BeTrue())
+		_, err := utils.ParseSwarmKey(normalized)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, rewritten = utils.NormalizeSwarmKey(normalized)
+		Expect(rewritten).To(BeFalse())
+		unchanged, rewritten := utils.NormalizeSwarmKey(goodKey)
+		Expect(rewritten).To(BeFalse())
+		Expect(unchanged).To(Equal(goodKey))
+	})
+})
+
+var _ = Describe("Swarm key rotation", func() {
+	var oldKey, newKey string
+
+	BeforeEach(func() {
+		var err error
+		oldKey, err = utils.NewSwarmKey()
+		Expect(err).NotTo(HaveOccurred())
+		newKey, err = utils.NewSwarmKey()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	peers := func(mounted, active []string) []utils.SwarmKeyPeerState {
+		states := make([]utils.SwarmKeyPeerState, len(mounted))
+		for i := range mounted {
+			states[i] = utils.SwarmKeyPeerState{
+				Pod:     "ipfs-cluster-" + strconv.Itoa(i),
+				Mounted: mounted[i],
+				Active:  active[i],
+			}
+		}
+		return states
+	}
+
+	It("stages a valid new key", func() {
+		secret := &corev1.Secret{}
+		Expect(utils.StageSwarmKey(secret, "SWARM_KEY_NEXT", newKey)).To(Succeed())
+		Expect(secret.StringData["SWARM_KEY_NEXT"]).To(Equal(newKey))
+		Expect(utils.StageSwarmKey(secret, "SWARM_KEY_NEXT", "not-a-key")).NotTo(Succeed())
+	})
+
+	It("walks through the staged states", func() {
+		o, n := utils.SwarmKeyFingerprint(oldKey), utils.SwarmKeyFingerprint(newKey)
+		Expect(o).NotTo(Equal(n))
+
+		phase, pending := utils.SwarmKeyRotationStatus(n, peers([]string{o, o, o}, []string{o, o, o}))
+		Expect(phase).To(Equal(utils.SwarmKeyRotationStaged))
+		Expect(pending).To(HaveLen(3))
+
+		phase, pending = utils.SwarmKeyRotationStatus(n, peers([]string{n, o, n}, []string{o, o, o}))
+		Expect(phase).To(Equal(utils.SwarmKeyRotationMounting))
+		Expect(pending).To(Equal([]string{"ipfs-cluster-1"}))
+
+		phase, pending = utils.SwarmKeyRotationStatus(n, peers([]string{n, n, n}, []string{o, o, o}))
+		Expect(phase).To(Equal(utils.SwarmKeyRotationReadyToRestart))
+		Expect(pending).To(BeEmpty())
+
+		phase, _ = utils.SwarmKeyRotationStatus(n, peers([]string{n, n, n}, []string{n, n, n}))
+		Expect(phase).To(Equal(utils.SwarmKeyRotationComplete))
+		Expect(utils.SwarmKeyRotationCondition(phase, nil).Status).To(Equal(metav1.ConditionTrue))
+	})
+
+	It("detects a partial rollout", func() {
+		o, n := utils.SwarmKeyFingerprint(oldKey), utils.SwarmKeyFingerprint(newKey)
+		phase, pending := utils.SwarmKeyRotationStatus(n, peers([]string{n, n, n}, []string{n, o, n}))
+		Expect(phase).To(Equal(utils.SwarmKeyRotationPartial))
+		Expect(pending).To(Equal([]string{"ipfs-cluster-1"}))
+
+		cond := utils.SwarmKeyRotationCondition(phase, pending)
+		Expect(cond.Type).To(Equal(clusterv1alpha1.ConditionSwarmKeyRotated))
+		Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+		Expect(cond.Reason).To(Equal(string(utils.SwarmKeyRotationPartial)))
+		Expect(cond.Message).To(ContainSubstring("ipfs-cluster-1"))
+	})
+})
@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+)
+
+const (
+	// AnnotationClusterAPITLSHash Records on the pod template the hash of the certificate material
+	// the IPFS Cluster REST API serves, so that pods are rolled out when it is rotated.
+	AnnotationClusterAPITLSHash = "cluster.ipfs.io/cluster-api-tls-hash"
+	// AnnotationClusterAPITLSResourceVersion Records on the StatefulSet the resourceVersion of the
+	// TLS Secret last hashed, so the certificate material is only hashed again once it changes.
+	AnnotationClusterAPITLSResourceVersion = "cluster.ipfs.io/cluster-api-tls-resource-version"
+)
+
+// ValidateTLSSecret Ensures that the given Secret contains a parseable certificate and
+// key pair under tls.crt and tls.key. When a host is provided, the certificate must
+// also be valid for that host. A nil secret is reported as missing.
+func ValidateTLSSecret(secret *corev1.Secret, host string) error {
+	if secret == nil {
+		return fmt.Errorf("tls secret does not exist")
+	}
+	certPEM, ok := secret.Data[corev1.TLSCertKey]
+	if !ok || len(certPEM) == 0 {
+		return fmt.Errorf("tls secret %q is missing %s", secret.Name, corev1.TLSCertKey)
+	}
+	keyPEM, ok := secret.Data[corev1.TLSPrivateKeyKey]
+	if !ok || len(keyPEM) == 0 {
+		return fmt.Errorf("tls secret %q is missing %s", secret.Name, corev1.TLSPrivateKeyKey)
+	}
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return fmt.Errorf("tls secret %q does not contain a valid certificate and key pair: %w", secret.Name, err)
+	}
+	if host == "" {
+		return nil
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("could not parse certificate from tls secret %q: %w", secret.Name, err)
+	}
+	if err = leaf.VerifyHostname(host); err != nil {
+		return fmt.Errorf("certificate in tls secret %q does not match host: %w", secret.Name, err)
+	}
+	return nil
+}
+
+// GatewayTLSCondition Returns the condition reporting whether the TLS Secret referenced for the
+// gateway holds a valid certificate and key pair for the given host. A nil secret is missing.
+func GatewayTLSCondition(secretName string, secret *corev1.Secret, host string) metav1.Condition {
+	if err := ValidateTLSSecret(secret, host); err != nil {
+		return metav1.Condition{
+			Type:    clusterv1alpha1.ConditionGatewayTLSValid,
+			Status:  metav1.ConditionFalse,
+			Reason:  clusterv1alpha1.GatewayTLSReasonInvalid,
+			Message: fmt.Sprintf("tls secret %q is invalid: %s", secretName, err),
+		}
+	}
+	return metav1.Condition{
+		Type:    clusterv1alpha1.ConditionGatewayTLSValid,
+		Status:  metav1.ConditionTrue,
+		Reason:  clusterv1alpha1.GatewayTLSReasonValid,
+		Message: fmt.Sprintf("tls secret %q is valid", secretName),
+	}
+}
+
+// TLSSecretHash Returns a hash over the certificate material held by the given TLS Secret, i.e. every
+// entry of its data, so that a rotated certificate, key or CA yields a different hash. Metadata such
+// as labels and annotations is left out.
+func TLSSecretHash(secret *corev1.Secret) string {
+	keys := make([]string, 0, len(secret.Data))
+	for key := range secret.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, key := range keys {
+		// length-prefix each entry so that moving bytes across entries changes the hash
+		fmt.Fprintf(h, "%d:%s%d:", len(key), key, len(secret.Data[key]))
+		h.Write(secret.Data[key])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ApplyClusterAPITLSRotation Detects a rotation of the TLS Secret served by the IPFS Cluster REST API,
+// e.g. by cert-manager, since the running peers don't reload their certificate. Once the Secret's
+// resourceVersion differs from the one last seen, its certificate material is hashed into the pod
+// template of the given StatefulSet. Returns true if the hash changed, in which case the StatefulSet
+// must be updated to roll the new certificate out. Updates which leave the certificate material
+// alone only record the new resourceVersion on the StatefulSet, leaving the pods running.
+func ApplyClusterAPITLSRotation(sts *appsv1.StatefulSet, secret *corev1.Secret) bool {
+	if sts.Annotations[AnnotationClusterAPITLSResourceVersion] == secret.ResourceVersion &&
+		sts.Spec.Template.Annotations[AnnotationClusterAPITLSHash] != "" {
+		return false
+	}
+	if sts.Annotations == nil {
+		sts.Annotations = make(map[string]string, 1)
+	}
+	sts.Annotations[AnnotationClusterAPITLSResourceVersion] = secret.ResourceVersion
+	hash := TLSSecretHash(secret)
+	if sts.Spec.Template.Annotations[AnnotationClusterAPITLSHash] == hash {
+		return false
+	}
+	if sts.Spec.Template.Annotations == nil {
+		sts.Spec.Template.Annotations = make(map[string]string, 1)
+	}
+	sts.Spec.Template.Annotations[AnnotationClusterAPITLSHash] = hash
+	return true
+}
+
+// ClusterAPITLSSecretRequests Returns the reconcile requests of those of the given IpfsClusters which
+// serve their REST API with the given Secret, so that its rotation is rolled out as soon as the
+// Secret changes, rather than on the next unrelated reconcile.
+func ClusterAPITLSSecretRequests(secret client.Object, clusters []clusterv1alpha1.IpfsCluster) []reconcile.Request {
+	requests := make([]reconcile.Request, 0)
+	for i := range clusters {
+		m := &clusters[i]
+		ref := m.Spec.ClusterAPITLSSecretRef
+		if ref == nil || ref.Name != secret.GetName() || m.Namespace != secret.GetNamespace() {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: m.Name, Namespace: m.Namespace},
+		})
+	}
+	return requests
+}
@@ -0,0 +1,150 @@
+package utils_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+// newTLSSecret Returns a Secret containing a self-signed certificate for the given host.
+func newTLSSecret(host string) *corev1.Secret {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	Expect(err).NotTo(HaveOccurred())
+
+	secret := &corev1.Secret{}
+	secret.Name = "gateway-tls"
+	secret.Type = corev1.SecretTypeTLS
+	secret.Data = map[string][]byte{
+		corev1.TLSCertKey:       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		corev1.TLSPrivateKeyKey: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+	return secret
+}
+
+var _ = Describe("Gateway TLS secret", func() {
+	const host = "gateway.example.com"
+
+	It("accepts a valid certificate and key pair", func() {
+		secret := newTLSSecret(host)
+		Expect(utils.ValidateTLSSecret(secret, host)).To(Succeed())
+		Expect(utils.ValidateTLSSecret(secret, "")).To(Succeed())
+	})
+
+	It("rejects a missing secret", func() {
+		Expect(utils.ValidateTLSSecret(nil, host)).NotTo(Succeed())
+	})
+
+	It("rejects a secret without a key", func() {
+		secret := newTLSSecret(host)
+		delete(secret.Data, corev1.TLSPrivateKeyKey)
+		Expect(utils.ValidateTLSSecret(secret, host)).NotTo(Succeed())
+	})
+
+	It("rejects a malformed certificate", func() {
+		secret := newTLSSecret(host)
+		secret.Data[corev1.TLSCertKey] = []byte("not a certificate")
+		Expect(utils.ValidateTLSSecret(secret, host)).NotTo(Succeed())
+	})
+
+	It("rejects a certificate for another host", func() {
+		secret := newTLSSecret("other.example.com")
+		Expect(utils.ValidateTLSSecret(secret, host)).NotTo(Succeed())
+	})
+
+	It("reports the validity as a condition", func() {
+		condition := utils.GatewayTLSCondition("gateway-tls", newTLSSecret(host), host)
+		Expect(condition.Type).To(Equal(clusterv1alpha1.ConditionGatewayTLSValid))
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+
+		condition = utils.GatewayTLSCondition("gateway-tls", nil, host)
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(clusterv1alpha1.GatewayTLSReasonInvalid))
+		Expect(condition.Message).To(ContainSubstring("gateway-tls"))
+	})
+})
+
+var _ = Describe("Cluster API TLS rotation", func() {
+	const host = "cluster.example.com"
+
+	It("hashes the certificate material", func() {
+		secret := newTLSSecret(host)
+		hash := utils.TLSSecretHash(secret)
+		Expect(hash).To(HaveLen(64))
+
+		secret.Labels = map[string]string{"rotated": "no"}
+		Expect(utils.TLSSecretHash(secret)).To(Equal(hash))
+		Expect(utils.TLSSecretHash(newTLSSecret(host))).NotTo(Equal(hash))
+	})
+
+	It("rolls the peers out when the certificate is rotated", func() {
+		secret := newTLSSecret(host)
+		secret.ResourceVersion = "1"
+		sts := &appsv1.StatefulSet{}
+		Expect(utils.ApplyClusterAPITLSRotation(sts, secret)).To(BeTrue())
+		hash := sts.Spec.Template.Annotations[utils.AnnotationClusterAPITLSHash]
+		Expect(hash).To(Equal(utils.TLSSecretHash(secret)))
+		Expect(utils.ApplyClusterAPITLSRotation(sts, secret)).To(BeFalse())
+
+		rotated := newTLSSecret(host)
+		rotated.ResourceVersion = "2"
+		Expect(utils.ApplyClusterAPITLSRotation(sts, rotated)).To(BeTrue())
+		Expect(sts.Spec.Template.Annotations[utils.AnnotationClusterAPITLSHash]).NotTo(Equal(hash))
+		Expect(sts.Annotations[utils.AnnotationClusterAPITLSResourceVersion]).To(Equal("2"))
+	})
+
+	It("keeps the peers running on metadata-only updates", func() {
+		secret := newTLSSecret(host)
+		secret.ResourceVersion = "1"
+		sts := &appsv1.StatefulSet{}
+		Expect(utils.ApplyClusterAPITLSRotation(sts, secret)).To(BeTrue())
+
+		secret.ResourceVersion = "2"
+		secret.Annotations = map[string]string{"cert-manager.io/issuer-name": "other"}
+		Expect(utils.ApplyClusterAPITLSRotation(sts, secret)).To(BeFalse())
+		Expect(sts.Annotations[utils.AnnotationClusterAPITLSResourceVersion]).To(Equal("2"))
+	})
+	It("maps the secret to the clusters serving it", func() {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "cluster-api-tls", Namespace: "ipfs"}}
+		cluster := func(name, namespace, secretName string) clusterv1alpha1.IpfsCluster {
+			m := clusterv1alpha1.IpfsCluster{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+			if secretName != "" {
+				m.Spec.ClusterAPITLSSecretRef = &corev1.LocalObjectReference{Name: secretName}
+			}
+			return m
+		}
+		requests := utils.ClusterAPITLSSecretRequests(secret, []clusterv1alpha1.IpfsCluster{
+			cluster("serving", "ipfs", "cluster-api-tls"),
+			cluster("other-secret", "ipfs", "other-tls"),
+			cluster("plain-http", "ipfs", ""),
+			cluster("other-namespace", "default", "cluster-api-tls"),
+		})
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].NamespacedName).To(Equal(types.NamespacedName{Name: "serving", Namespace: "ipfs"}))
+	})
+})
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// StatefulSetUpdateStrategy Returns the update strategy for an IPFS Cluster StatefulSet
+// with the given number of replicas. A single-replica cluster can't surge a new pod next
+// to the old one since both would need the same ReadWriteOnce volume, so the StatefulSet
+// controller is told to leave updates to the operator, which recreates the pod through
+// OutdatedPods. Clusters with more replicas roll through their pods one at a time.
+func StatefulSetUpdateStrategy(replicas int32) appsv1.StatefulSetUpdateStrategy {
+	if replicas <= 1 {
+		return appsv1.StatefulSetUpdateStrategy{
+			Type: appsv1.OnDeleteStatefulSetStrategyType,
+		}
+	}
+	return appsv1.StatefulSetUpdateStrategy{
+		Type: appsv1.RollingUpdateStatefulSetStrategyType,
+	}
+}
+
+// OutdatedPods Returns the pods of a StatefulSet using the OnDelete strategy which don't
+// run its update revision yet and must be deleted to be recreated. Pods which are already
+// terminating are skipped, so the replacement is only created once the volume is released.
+func OutdatedPods(sts *appsv1.StatefulSet, pods []corev1.Pod) []corev1.Pod {
+	if sts.Spec.UpdateStrategy.Type != appsv1.OnDeleteStatefulSetStrategyType {
+		return nil
+	}
+	updateRevision := sts.Status.UpdateRevision
+	if updateRevision == "" {
+		return nil
+	}
+	outdated := make([]corev1.Pod, 0)
+	for i := range pods {
+		pod := &pods[i]
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		if pod.Labels[appsv1.StatefulSetRevisionLabel] != updateRevision {
+			outdated = append(outdated, *pod)
+		}
+	}
+	return outdated
+}
@@ -0,0 +1,69 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("StatefulSet update strategy", func() {
+	newPod := func(name, revision string) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+				Labels: map[string]string{
+					appsv1.StatefulSetRevisionLabel: revision,
+				},
+			},
+		}
+	}
+
+	It("recreates the pod of a single-replica cluster", func() {
+		sts := &appsv1.StatefulSet{
+			Spec: appsv1.StatefulSetSpec{
+				UpdateStrategy: utils.StatefulSetUpdateStrategy(1),
+			},
+			Status: appsv1.StatefulSetStatus{
+				UpdateRevision: "rev-2",
+			},
+		}
+		Expect(sts.Spec.UpdateStrategy.Type).To(Equal(appsv1.OnDeleteStatefulSetStrategyType))
+
+		outdated := utils.OutdatedPods(sts, []corev1.Pod{newPod("ipfs-cluster-test-0", "rev-1")})
+		Expect(outdated).To(HaveLen(1))
+		Expect(outdated[0].Name).To(Equal("ipfs-cluster-test-0"))
+		Expect(utils.OutdatedPods(sts, []corev1.Pod{newPod("ipfs-cluster-test-0", "rev-2")})).To(BeEmpty())
+	})
+
+	It("waits for a terminating pod to release its volume", func() {
+		sts := &appsv1.StatefulSet{
+			Spec: appsv1.StatefulSetSpec{
+				UpdateStrategy: utils.StatefulSetUpdateStrategy(1),
+			},
+			Status: appsv1.StatefulSetStatus{
+				UpdateRevision: "rev-2",
+			},
+		}
+		pod := newPod("ipfs-cluster-test-0", "rev-1")
+		now := metav1.Now()
+		pod.DeletionTimestamp = &now
+		Expect(utils.OutdatedPods(sts, []corev1.Pod{pod})).To(BeEmpty())
+	})
+
+	It("rolls through multi-replica clusters", func() {
+		sts := &appsv1.StatefulSet{
+			Spec: appsv1.StatefulSetSpec{
+				UpdateStrategy: utils.StatefulSetUpdateStrategy(3),
+			},
+			Status: appsv1.StatefulSetStatus{
+				UpdateRevision: "rev-2",
+			},
+		}
+		Expect(sts.Spec.UpdateStrategy.Type).To(Equal(appsv1.RollingUpdateStatefulSetStrategyType))
+		Expect(utils.OutdatedPods(sts, []corev1.Pod{newPod("ipfs-cluster-test-0", "rev-1")})).To(BeEmpty())
+	})
+})
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+)
+
+// ResourceRecommendation Is how the request of a resource should change to match its usage.
+type ResourceRecommendation string
+
+const (
+	// RecommendScaleUp Means the usage gets close to, or exceeds, the request.
+	RecommendScaleUp ResourceRecommendation = "ScaleUp"
+	// RecommendScaleDown Means the request is well above the usage.
+	RecommendScaleDown ResourceRecommendation = "ScaleDown"
+	// RecommendKeep Means the request matches the usage.
+	RecommendKeep ResourceRecommendation = "Keep"
+)
+
+const (
+	// usagePercentile Is the percentile of the usage samples compared against the requests, which
+	// ignores short spikes.
+	usagePercentile = 0.95
+	// usageHighFraction Is the fraction of a request above which more should be requested.
+	usageHighFraction = 0.9
+	// usageLowFraction Is the fraction of a request below which less should be requested.
+	usageLowFraction = 0.5
+)
+
+// RecommendResources Compares the 95th percentile of the observed usage samples of each requested
+// resource against its request, e.g. the requests computed by IPFSContainerResources against the
+// usage reported by the metrics API, and recommends scaling requests used above 90% up and requests
+// used below 50% down. Resources without samples get no recommendation.
+func RecommendResources(
+	requests corev1.ResourceList,
+	samples []corev1.ResourceList,
+) map[corev1.ResourceName]ResourceRecommendation {
+	recommendations := make(map[corev1.ResourceName]ResourceRecommendation)
+	for name, request := range requests {
+		usage := make([]int64, 0, len(samples))
+		for _, sample := range samples {
+			if q, ok := sample[name]; ok {
+				usage = append(usage, q.MilliValue())
+			}
+		}
+		if len(usage) == 0 || request.IsZero() {
+			continue
+		}
+		sort.Slice(usage, func(i, j int) bool { return usage[i] < usage[j] })
+		observed := float64(usage[int(math.Ceil(usagePercentile*float64(len(usage))))-1])
+		requested := float64(request.MilliValue())
+		switch {
+		case observed > usageHighFraction*requested:
+			recommendations[name] = RecommendScaleUp
+		case observed < usageLowFraction*requested:
+			recommendations[name] = RecommendScaleDown
+		default:
+			recommendations[name] = RecommendKeep
+		}
+	}
+	return recommendations
+}
+
+// ResourceDriftCondition Returns the condition reporting the given recommendations on the status.
+// Under-provisioning is reported over over-provisioning, since it risks throttling and OOM kills.
+func ResourceDriftCondition(recommendations map[corev1.ResourceName]ResourceRecommendation) metav1.Condition {
+	condition := metav1.Condition{
+		Type:    clusterv1alpha1.ConditionResourceRequestsDrifted,
+		Status:  metav1.ConditionFalse,
+		Reason:  clusterv1alpha1.ResourceRequestsReasonRightsized,
+		Message: "resource requests match the observed usage",
+	}
+	if len(recommendations) == 0 {
+		condition.Status = metav1.ConditionUnknown
+		condition.Reason = clusterv1alpha1.ResourceRequestsReasonNoSamples
+		condition.Message = "no resource usage has been observed"
+		return condition
+	}
+	var up, down []string
+	for name, recommendation := range recommendations {
+		switch recommendation {
+		case RecommendScaleUp:
+			up = append(up, string(name))
+		case RecommendScaleDown:
+			down = append(down, string(name))
+		}
+	}
+	sort.Strings(up)
+	sort.Strings(down)
+	switch {
+	case len(up) > 0:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = clusterv1alpha1.ResourceRequestsReasonUnderProvisioned
+		condition.Message = fmt.Sprintf("requests should be scaled up for: %s", strings.Join(up, ", "))
+	case len(down) > 0:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = clusterv1alpha1.ResourceRequestsReasonOverProvisioned
+		condition.Message = fmt.Sprintf("requests could be scaled down for: %s", strings.Join(down, ", "))
+	}
+	return condition
+}
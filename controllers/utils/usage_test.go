@@ -0,0 +1,65 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clusterv1alpha1 "github.com/redhat-et/ipfs-operator/api/v1alpha1"
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Resource usage drift", func() {
+	requests := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("1"),
+		corev1.ResourceMemory: resource.MustParse("4Gi"),
+	}
+	samplesOf := func(cpu, memory string, n int) []corev1.ResourceList {
+		samples := make([]corev1.ResourceList, n)
+		for i := range samples {
+			samples[i] = corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(memory),
+			}
+		}
+		return samples
+	}
+
+	It("recommends scaling over-provisioned requests down", func() {
+		recommendations := utils.RecommendResources(requests, samplesOf("100m", "1Gi", 20))
+		Expect(recommendations).To(Equal(map[corev1.ResourceName]utils.ResourceRecommendation{
+			corev1.ResourceCPU:    utils.RecommendScaleDown,
+			corev1.ResourceMemory: utils.RecommendScaleDown,
+		}))
+		condition := utils.ResourceDriftCondition(recommendations)
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Reason).To(Equal(clusterv1alpha1.ResourceRequestsReasonOverProvisioned))
+	})
+
+	It("recommends scaling under-provisioned requests up", func() {
+		samples := append(samplesOf("950m", "3Gi", 19), samplesOf("2", "3Gi", 1)...)
+		recommendations := utils.RecommendResources(requests, samples)
+		Expect(recommendations[corev1.ResourceCPU]).To(Equal(utils.RecommendScaleUp))
+		Expect(recommendations[corev1.ResourceMemory]).To(Equal(utils.RecommendKeep))
+		condition := utils.ResourceDriftCondition(recommendations)
+		Expect(condition.Reason).To(Equal(clusterv1alpha1.ResourceRequestsReasonUnderProvisioned))
+		Expect(condition.Message).To(ContainSubstring("cpu"))
+	})
+
+	It("ignores short spikes", func() {
+		samples := append(samplesOf("600m", "3Gi", 19), samplesOf("4", "16Gi", 1)...)
+		recommendations := utils.RecommendResources(requests, samples)
+		Expect(recommendations[corev1.ResourceCPU]).To(Equal(utils.RecommendKeep))
+		condition := utils.ResourceDriftCondition(recommendations)
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(clusterv1alpha1.ResourceRequestsReasonRightsized))
+	})
+
+	It("reports missing samples", func() {
+		recommendations := utils.RecommendResources(requests, nil)
+		Expect(recommendations).To(BeEmpty())
+		Expect(utils.ResourceDriftCondition(recommendations).Status).To(Equal(metav1.ConditionUnknown))
+	})
+})
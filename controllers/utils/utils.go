@@ -6,6 +6,7 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"fmt"
+	"strings"
 
 	"github.com/alecthomas/units"
 	"github.com/go-logr/logr"
@@ -13,15 +14,17 @@ import (
 	ci "github.com/libp2p/go-libp2p/core/crypto"
 
 	peer "github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/pnet"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
-// CreateOrPatchTrackedObjects Goes through the map of tracked objects and attempts to
-// apply the ctrl.createOrPatch function to each one. This function will return a
-// boolean indicating whether or not the requeue should be set to true.
+// CreateOrPatchTrackedObjects Goes through the map of tracked objects in the order given by
+// OrderObjects and attempts to apply the ctrl.createOrPatch function to each one. This function
+// will return a boolean indicating whether or not the requeue should be set to true.
 func CreateOrPatchTrackedObjects(
 	ctx context.Context,
 	trackedObjects map[client.Object]controllerutil.MutateFn,
@@ -30,7 +33,8 @@ func CreateOrPatchTrackedObjects(
 ) bool {
 	var requeue bool
 	var err error
-	for obj, mut := range trackedObjects {
+	for _, obj := range orderTrackedObjects(trackedObjects, client.Scheme()) {
+		mut := trackedObjects[obj]
 		var result controllerutil.OperationResult
 		kind := obj.GetObjectKind().GroupVersionKind()
 		name := obj.GetName()
@@ -45,6 +49,18 @@ func CreateOrPatchTrackedObjects(
 	return requeue
 }
 
+// orderTrackedObjects Returns the tracked objects in the order given by OrderObjects.
+func orderTrackedObjects(
+	trackedObjects map[client.Object]controllerutil.MutateFn,
+	scheme *runtime.Scheme,
+) []client.Object {
+	objs := make([]client.Object, 0, len(trackedObjects))
+	for obj := range trackedObjects {
+		objs = append(objs, obj)
+	}
+	return OrderObjects(objs, scheme)
+}
+
 // ErrFunc Returns a function which returns the provided error when called.
 func ErrFunc(err error) controllerutil.MutateFn {
 	return func() error {
@@ -108,7 +124,7 @@ func NewClusterSecret() (string, error) {
 
 // NewSwarmKey Generates and returns a key used for hosting a private swarm.
 func NewSwarmKey() (string, error) {
-	const swarmPrefix = "/key/swarm/psk/1.0.0"
+	const swarmPrefix = "/key/swarm/psk/1.0.0/"
 	const multiBase = "/base16/"
 	buf, err := randomKey(32)
 	if err != nil {
@@ -133,6 +149,15 @@ func NewKey() (ci.PrivKey, peer.ID, error) {
 	return priv, peerid, nil
 }
 
+// ParseSwarmKey Decodes the given private swarm key, returning an error if it is malformed.
+func ParseSwarmKey(swarmKey string) (pnet.PSK, error) {
+	psk, err := pnet.DecodeV1PSK(strings.NewReader(swarmKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid swarm key: %w", err)
+	}
+	return psk, nil
+}
+
 // GenerateIdentity Generates a new key and returns the peer ID and private key
 // encoded as a base64 string using standard encoding, or an error if the key could not be generated.
 func GenerateIdentity() (peerid peer.ID, privStr string, err error) {
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+// IPFSFeature Describes a go-ipfs configuration feature which requires a minimum version.
+type IPFSFeature string
+
+const (
+	// FeaturePeering Is Peering.Peers.
+	FeaturePeering IPFSFeature = "Peering"
+	// FeatureExperimentalAcceleratedDHTClient Is Experimental.AcceleratedDHTClient.
+	FeatureExperimentalAcceleratedDHTClient IPFSFeature = "Experimental.AcceleratedDHTClient"
+	// FeatureRelayClient Is Swarm.RelayClient.
+	FeatureRelayClient IPFSFeature = "Swarm.RelayClient"
+	// FeatureHolePunching Is Swarm.EnableHolePunching.
+	FeatureHolePunching IPFSFeature = "Swarm.EnableHolePunching"
+	// FeatureResourceMgr Is Swarm.ResourceMgr.
+	FeatureResourceMgr IPFSFeature = "Swarm.ResourceMgr"
+	// FeatureRoutingAcceleratedDHTClient Is Routing.AcceleratedDHTClient, which replaced
+	// the experimental flag.
+	FeatureRoutingAcceleratedDHTClient IPFSFeature = "Routing.AcceleratedDHTClient"
+)
+
+// ipfsFeatureMinVersions Maps each feature onto the first go-ipfs release supporting it.
+var ipfsFeatureMinVersions = map[IPFSFeature]*version.Version{
+	FeaturePeering:                          version.MustParseSemantic("v0.6.0"),
+	FeatureExperimentalAcceleratedDHTClient: version.MustParseSemantic("v0.9.0"),
+	FeatureRelayClient:                      version.MustParseSemantic("v0.11.0"),
+	FeatureHolePunching:                     version.MustParseSemantic("v0.11.0"),
+	FeatureResourceMgr:                      version.MustParseSemantic("v0.13.0"),
+	FeatureRoutingAcceleratedDHTClient:      version.MustParseSemantic("v0.21.0"),
+}
+
+// ImageVersion Returns the semantic version from the tag of the given container image,
+// e.g. `docker.io/ipfs/kubo:v0.16.0`. Images referenced by digest or by a tag which isn't
+// a version, such as `latest`, have no known version and return an error.
+func ImageVersion(image string) (*version.Version, error) {
+	if strings.Contains(image, "@") {
+		return nil, fmt.Errorf("cannot determine the version of image %q referenced by digest", image)
+	}
+	name := image[strings.LastIndex(image, "/")+1:]
+	i := strings.LastIndex(name, ":")
+	if i < 0 {
+		return nil, fmt.Errorf("image %q has no tag", image)
+	}
+	v, err := version.ParseSemantic(name[i+1:])
+	if err != nil {
+		return nil, fmt.Errorf("image %q is not tagged with a version: %w", image, err)
+	}
+	return v, nil
+}
+
+// UnsupportedIPFSFeatures Returns the given features which the go-ipfs image doesn't support,
+// sorted by name. Config keys of unsupported features are silently ignored by go-ipfs,
+// so these should be reported rather than rendered. Unknown features are assumed supported.
+func UnsupportedIPFSFeatures(image string, features []IPFSFeature) ([]IPFSFeature, error) {
+	v, err := ImageVersion(image)
+	if err != nil {
+		return nil, err
+	}
+	unsupported := make([]IPFSFeature, 0)
+	for _, feature := range features {
+		minVersion, ok := ipfsFeatureMinVersions[feature]
+		if !ok {
+			continue
+		}
+		if v.LessThan(minVersion) {
+			unsupported = append(unsupported, feature)
+		}
+	}
+	sort.Slice(unsupported, func(i, j int) bool {
+		return unsupported[i] < unsupported[j]
+	})
+	return unsupported, nil
+}
+
+// ValidateIPFSFeatures Returns an error listing the features unsupported by the go-ipfs image.
+func ValidateIPFSFeatures(image string, features []IPFSFeature) error {
+	unsupported, err := UnsupportedIPFSFeatures(image, features)
+	if err != nil {
+		return err
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("image %q does not support: %v", image, unsupported)
+	}
+	return nil
+}
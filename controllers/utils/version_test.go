@@ -0,0 +1,60 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("IPFS image features", func() {
+	features := []utils.IPFSFeature{
+		utils.FeatureExperimentalAcceleratedDHTClient,
+		utils.FeatureRelayClient,
+		utils.FeatureResourceMgr,
+	}
+
+	It("parses the version from the image tag", func() {
+		v, err := utils.ImageVersion("docker.io/ipfs/kubo:v0.16.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v.String()).To(Equal("0.16.0"))
+		v, err = utils.ImageVersion("localhost:5000/go-ipfs:0.12.2")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v.Minor()).To(BeEquivalentTo(12))
+	})
+
+	It("rejects images without a version", func() {
+		for _, image := range []string{
+			"docker.io/ipfs/kubo:latest",
+			"docker.io/ipfs/kubo",
+			"localhost:5000/kubo",
+			"docker.io/ipfs/kubo@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		} {
+			_, err := utils.ImageVersion(image)
+			Expect(err).To(HaveOccurred(), image)
+		}
+	})
+
+	It("supports every feature on a recent image", func() {
+		Expect(utils.ValidateIPFSFeatures("docker.io/ipfs/kubo:v0.16.0", features)).To(Succeed())
+	})
+
+	It("detects features unsupported by an older image", func() {
+		unsupported, err := utils.UnsupportedIPFSFeatures("docker.io/ipfs/go-ipfs:v0.10.0", features)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(unsupported).To(Equal([]utils.IPFSFeature{
+			utils.FeatureRelayClient,
+			utils.FeatureResourceMgr,
+		}))
+		Expect(utils.ValidateIPFSFeatures("docker.io/ipfs/go-ipfs:v0.10.0", features)).NotTo(Succeed())
+	})
+
+	It("detects features newer than the bundled image", func() {
+		unsupported, err := utils.UnsupportedIPFSFeatures(
+			"docker.io/ipfs/kubo:v0.16.0",
+			[]utils.IPFSFeature{utils.FeatureRoutingAcceleratedDHTClient},
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(unsupported).To(ConsistOf(utils.FeatureRoutingAcceleratedDHTClient))
+	})
+})
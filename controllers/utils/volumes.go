@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/alecthomas/units"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// VolumeClusterState Defines the name of the volume holding the IPFS Cluster consensus state.
+	VolumeClusterState = "cluster-state"
+	// ClusterConfigPath Defines the IPFS Cluster configuration directory.
+	ClusterConfigPath = "/data/ipfs-cluster"
+	// VolumeSharedCache Defines the name of the read-only cache volume shared across gateway pods.
+	VolumeSharedCache = "shared-cache"
+)
+
+// clusterStateDirs Maps each consensus component onto the directory its state is stored under.
+var clusterStateDirs = map[string]string{
+	"crdt": "badger",
+	"raft": "raft",
+}
+
+// ClusterStateVolume Returns a PersistentVolumeClaim template and the matching mount which
+// place the IPFS Cluster consensus state for the given consensus onto its own volume.
+// This allows the state to live on a small, fast StorageClass separate from the bulk datastore.
+// An empty storageClassName uses the cluster's default StorageClass.
+func ClusterStateVolume(
+	consensus string,
+	storageClassName string,
+	size resource.Quantity,
+) (corev1.PersistentVolumeClaim, corev1.VolumeMount) {
+	dir, ok := clusterStateDirs[consensus]
+	if !ok {
+		dir = clusterStateDirs["crdt"]
+	}
+	pvc := BuildDataPVC(VolumeClusterState, size, storageClassName)
+	mount := corev1.VolumeMount{
+		Name:      VolumeClusterState,
+		MountPath: path.Join(ClusterConfigPath, dir),
+	}
+	return pvc, mount
+}
+
+// DataVolumesStorage Returns the total size in bytes of the data volumes the datastore is striped
+// across, from which StorageMax is computed, since the datastore fills all of them and not just one.
+// Sizes which cannot be represented as an int64 are rounded.
+func DataVolumesStorage(sizes ...resource.Quantity) int64 {
+	var total int64
+	for _, size := range sizes {
+		bytes, ok := size.AsInt64()
+		if !ok {
+			bytes = size.ToDec().Value()
+		}
+		total += bytes
+	}
+	return total
+}
+
+// SharedCacheVolumeMount Returns the read-only mount of the cache volume shared across gateway
+// pods, with the given mount propagation mode. An empty mode uses None. Bidirectional propagation
+// is only accepted by Kubernetes for privileged containers.
+func SharedCacheVolumeMount(mountPath, propagation string) (corev1.VolumeMount, error) {
+	mode := corev1.MountPropagationMode(propagation)
+	switch mode {
+	case "":
+		mode = corev1.MountPropagationNone
+	case corev1.MountPropagationNone, corev1.MountPropagationHostToContainer, corev1.MountPropagationBidirectional:
+	default:
+		return corev1.VolumeMount{}, fmt.Errorf("invalid mount propagation mode: %s", propagation)
+	}
+	return corev1.VolumeMount{
+		Name:             VolumeSharedCache,
+		MountPath:        mountPath,
+		ReadOnly:         true,
+		MountPropagation: &mode,
+	}, nil
+}
+
+// ParseIPFSSize Parses a size the way Kubo parses Datastore.StorageMax, e.g. `10GB` or `500MiB`:
+// decimal units are powers of 1000 and binary units powers of 1024, units are case-insensitive and
+// may omit the trailing B, and a bare number is a count of bytes.
+func ParseIPFSSize(size string) (int64, error) {
+	s := strings.ReplaceAll(strings.TrimSpace(size), " ", "")
+	split := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	number, unit := s, ""
+	if split >= 0 {
+		number, unit = s[:split], strings.ToUpper(s[split:])
+	}
+	unit = strings.TrimSuffix(unit, "B")
+	switch {
+	case unit == "":
+		unit = "B"
+	case strings.HasSuffix(unit, "I"):
+		unit = strings.TrimSuffix(unit, "I") + "iB"
+	default:
+		unit += "B"
+	}
+	bytes, err := units.ParseStrictBytes(number + unit)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ipfs size %q: %w", size, err)
+	}
+	return bytes, nil
+}
+
+// ValidateStorageMaxOverride Ensures that a StorageMax set explicitly rather than computed from the
+// PVC size fits the PVC. A larger StorageMax lets Kubo fill the volume, and it then crashes on the
+// first write. An empty override is valid, since StorageMax is then computed from the PVC size.
+func ValidateStorageMaxOverride(storageMax string, pvcSize resource.Quantity) error {
+	if storageMax == "" {
+		return nil
+	}
+	bytes, err := ParseIPFSSize(storageMax)
+	if err != nil {
+		return err
+	}
+	if capacity := DataVolumesStorage(pvcSize); bytes > capacity {
+		return fmt.Errorf("storageMax %s (%d bytes) exceeds the volume size %s (%d bytes)",
+			storageMax, bytes, pvcSize.String(), capacity)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package utils_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/redhat-et/ipfs-operator/controllers/utils"
+)
+
+var _ = Describe("Cluster state volume", func() {
+	const (
+		dataVolume    = "ipfs-storage"
+		dataMountPath = "/data/ipfs"
+		dataClass     = "standard"
+		fastClass     = "fast-ssd"
+	)
+
+	It("separates the state from the data volume", func() {
+		dataClassName := dataClass
+		data := corev1.PersistentVolumeClaim{}
+		data.Name = dataVolume
+		data.Spec.StorageClassName = &dataClassName
+
+		pvc, mount := utils.ClusterStateVolume("crdt", fastClass, resource.MustParse("1Gi"))
+		Expect(pvc.Name).NotTo(Equal(data.Name))
+		Expect(pvc.Spec.StorageClassName).NotTo(BeNil())
+		Expect(*pvc.Spec.StorageClassName).To(Equal(fastClass))
+		Expect(*pvc.Spec.StorageClassName).NotTo(Equal(*data.Spec.StorageClassName))
+		Expect(mount.Name).To(Equal(pvc.Name))
+		Expect(mount.MountPath).NotTo(Equal(dataMountPath))
+		Expect(mount.MountPath).To(Equal("/data/ipfs-cluster/badger"))
+		Expect(pvc.Spec.Resources.Requests[corev1.ResourceStorage]).To(Equal(resource.MustParse("1Gi")))
+	})
+
+	It("mounts the raft state directory", func() {
+		_, mount := utils.ClusterStateVolume("raft", fastClass, resource.MustParse("1Gi"))
+		Expect(mount.MountPath).To(Equal("/data/ipfs-cluster/raft"))
+	})
+
+	It("uses the default storage class when none is given", func() {
+		pvc, _ := utils.ClusterStateVolume("crdt", "", resource.MustParse("1Gi"))
+		Expect(pvc.Spec.StorageClassName).To(BeNil())
+	})
+})
+
+var _ = Describe("Data volumes storage", func() {
+	It("uses the size of a single volume", func() {
+		Expect(utils.DataVolumesStorage(resource.MustParse("100Gi"))).To(BeEquivalentTo(100 << 30))
+	})
+
+	It("sums the volumes the datastore is striped across", func() {
+		total := utils.DataVolumesStorage(
+			resource.MustParse("1Ti"), resource.MustParse("1Ti"), resource.MustParse("512Gi"),
+		)
+		Expect(total).To(BeEquivalentTo(2<<40 + 512<<30))
+		Expect(utils.DataVolumesStorage()).To(BeZero())
+	})
+})
+
+var _ = Describe("Shared cache volume mount", func() {
+	DescribeTable("sets the propagation mode",
+		func(propagation string, expected corev1.MountPropagationMode) {
+			mount, err := utils.SharedCacheVolumeMount("/cache", propagation)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mount.Name).To(Equal(utils.VolumeSharedCache))
+			Expect(mount.MountPath).To(Equal("/cache"))
+			Expect(mount.ReadOnly).To(BeTrue())
+			Expect(mount.MountPropagation).NotTo(BeNil())
+			Expect(*mount.MountPropagation).To(Equal(expected))
+		},
+		Entry("defaulting to none", "", corev1.MountPropagationNone),
+		Entry("none", "None", corev1.MountPropagationNone),
+		Entry("host to container", "HostToContainer", corev1.MountPropagationHostToContainer),
+		Entry("bidirectional", "Bidirectional", corev1.MountPropagationBidirectional),
+	)
+
+	It("rejects an unknown propagation mode", func() {
+		_, err := utils.SharedCacheVolumeMount("/cache", "bidirectional")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("StorageMax override", func() {
+	DescribeTable("parses sizes the way Kubo does",
+		func(size string, expected int64) {
+			bytes, err := utils.ParseIPFSSize(size)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bytes).To(Equal(expected))
+		},
+		Entry("decimal units", "10GB", int64(10_000_000_000)),
+		Entry("binary units", "10GiB", int64(10*1024*1024*1024)),
+		Entry("bytes", "8589934592B", int64(8589934592)),
+		Entry("a bare number", "1024", int64(1024)),
+		Entry("lowercase units without B", "2gi", int64(2*1024*1024*1024)),
+	)
+
+	It("rejects malformed sizes", func() {
+		_, err := utils.ParseIPFSSize("ten gigabytes")
+		Expect(err).To(HaveOccurred())
+		_, err = utils.ParseIPFSSize("10XB")
+		Expect(err).To(HaveOccurred())
+	})
+
+	pvcSize := resource.MustParse("100Gi")
+
+	It("accepts an override within the PVC", func() {
+		Expect(utils.ValidateStorageMaxOverride("80GiB", pvcSize)).To(Succeed())
+		Expect(utils.ValidateStorageMaxOverride("", pvcSize)).To(Succeed())
+	})
+
+	It("accepts an override equal to the PVC", func() {
+		Expect(utils.ValidateStorageMaxOverride("100GiB", pvcSize)).To(Succeed())
+	})
+
+	It("rejects an override exceeding the PVC", func() {
+		err := utils.ValidateStorageMaxOverride("101GiB", pvcSize)
+		Expect(err).To(MatchError(ContainSubstring("exceeds the volume size 100Gi")))
+		// 110GB is only slightly larger than 100GiB, in decimal units
+		Expect(utils.ValidateStorageMaxOverride("110GB", pvcSize)).NotTo(Succeed())
+	})
+})